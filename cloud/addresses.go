@@ -0,0 +1,123 @@
+package cloud
+
+import (
+	"fmt"
+	"net"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+type AddressFamily string
+
+const (
+	IPv4 AddressFamily = "ipv4"
+	IPv6 AddressFamily = "ipv6"
+)
+
+// AddressOptions configure the addresses reported for Nodes.
+type AddressOptions struct {
+	// Families are the reported address families in order of preference.
+	// Addresses of other families are dropped.
+	Families []AddressFamily
+	// ExcludeCIDRs are the networks whose addresses are never reported, e.g.
+	// the floating IPs of load balancers that are bound to a Node.
+	ExcludeCIDRs []*net.IPNet
+}
+
+// DefaultAddressOptions report IPv4 addresses before IPv6 addresses, so that
+// single-stack IPv4 clusters keep their Node IPs.
+var DefaultAddressOptions = AddressOptions{Families: []AddressFamily{IPv4, IPv6}}
+
+var addressOptions = DefaultAddressOptions
+
+// SetAddressOptions configures the Node addresses of all cloud providers. It
+// must be called before the cloud provider is initialized.
+func SetAddressOptions(options AddressOptions) error {
+	if len(options.Families) == 0 {
+		return fmt.Errorf("at least one address family is required")
+	}
+	seen := map[AddressFamily]bool{}
+	for _, f := range options.Families {
+		if f != IPv4 && f != IPv6 {
+			return fmt.Errorf("invalid address family %q, must be %s or %s", f, IPv4, IPv6)
+		}
+		if seen[f] {
+			return fmt.Errorf("duplicate address family %q", f)
+		}
+		seen[f] = true
+	}
+	addressOptions = options
+	return nil
+}
+
+// ParseCIDRs parses networks in CIDR notation, e.g. 192.0.2.0/24.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var result []*net.IPNet
+	for _, s := range cidrs {
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cidr)
+	}
+	return result, nil
+}
+
+// excluded returns whether address is in one of the excluded networks.
+func excluded(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range addressOptions.ExcludeCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressFamily returns the family of address, or an empty string if it is not
+// an IP address.
+func addressFamily(address string) AddressFamily {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return IPv4
+	default:
+		return IPv6
+	}
+}
+
+// NodeAddresses orders the addresses of a Node by the preferred address
+// families, see SetAddressOptions. Host names and DNS names come first, IP
+// addresses of families that are not configured or in excluded networks are
+// dropped, and empty or duplicate addresses are removed. The order of the
+// provider is kept within a family, so providers must list the primary
+// addresses first and the others in a deterministic order.
+func NodeAddresses(addresses []v1.NodeAddress) []v1.NodeAddress {
+	var names []v1.NodeAddress
+	byFamily := map[AddressFamily][]v1.NodeAddress{}
+	seen := map[v1.NodeAddress]bool{}
+	for _, addr := range addresses {
+		if addr.Address == "" || seen[addr] || excluded(addr.Address) {
+			continue
+		}
+		seen[addr] = true
+		switch addr.Type {
+		case v1.NodeHostName, v1.NodeInternalDNS, v1.NodeExternalDNS:
+			names = append(names, addr)
+		default:
+			family := addressFamily(addr.Address)
+			byFamily[family] = append(byFamily[family], addr)
+		}
+	}
+
+	result := names
+	for _, family := range addressOptions.Families {
+		result = append(result, byFamily[family]...)
+	}
+	return result
+}
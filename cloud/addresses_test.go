@@ -0,0 +1,85 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNodeAddresses(t *testing.T) {
+	defer SetAddressOptions(DefaultAddressOptions)
+
+	addresses := []v1.NodeAddress{
+		{Type: v1.NodeInternalIP, Address: "fd00::10"},
+		{Type: v1.NodeHostName, Address: "node-1"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: ""},
+	}
+
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "node-1"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeInternalIP, Address: "fd00::10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::10"},
+	}
+	if got := NodeAddresses(addresses); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if err := SetAddressOptions(AddressOptions{Families: []AddressFamily{IPv6}}); err != nil {
+		t.Fatal(err)
+	}
+	expected = []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "node-1"},
+		{Type: v1.NodeInternalIP, Address: "fd00::10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::10"},
+	}
+	if got := NodeAddresses(addresses); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	for _, families := range [][]AddressFamily{nil, {"ipv5"}, {IPv4, IPv4}} {
+		if err := SetAddressOptions(AddressOptions{Families: families}); err == nil {
+			t.Errorf("expected error for families %v", families)
+		}
+	}
+}
+
+func TestExcludeCIDRs(t *testing.T) {
+	defer SetAddressOptions(DefaultAddressOptions)
+
+	cidrs, err := ParseCIDRs([]string{"203.0.113.128/25", "2001:db8:ff::/48"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAddressOptions(AddressOptions{Families: DefaultAddressOptions.Families, ExcludeCIDRs: cidrs}); err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "node-1"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.200"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.20"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8:ff::1"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::10"},
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "node-1"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.20"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::10"},
+	}
+	if got := NodeAddresses(addresses); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := ParseCIDRs([]string{"203.0.113.10"}); err == nil {
+		t.Error("expected error for address without prefix length")
+	}
+}
@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrNotImplemented = errors.New("not implemented")
 	ErrLBUnsupported  = errors.New("loadbalancer unsupported")
+	ErrNoClusterID    = errors.New("cluster ID is not configured, refusing to change resources that may belong to another cluster")
 )
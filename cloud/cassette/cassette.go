@@ -0,0 +1,332 @@
+// Package cassette records HTTP interactions with cloud provider APIs into
+// testdata files and replays them in tests.
+//
+// A Recorder is an http.RoundTripper. In replay mode it answers requests from
+// its cassette without network access, in record mode it forwards them and
+// appends the scrubbed exchange to the cassette. Recording is enabled by the
+// PHARMER_RECORD env variable, e.g. via `./hack/make.py record`.
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	EnvRecord = "PHARMER_RECORD"
+	// EnvConfig names the cloud config file of the account to record against
+	EnvConfig = "PHARMER_RECORD_CONFIG"
+	// EnvNode is the name of a node in the account to record against
+	EnvNode = "PHARMER_RECORD_NODE"
+
+	// Redacted replaces secrets in recorded interactions
+	Redacted = "REDACTED"
+)
+
+// Recording returns true if cassettes are re-recorded against the real APIs.
+func Recording() bool {
+	v := os.Getenv(EnvRecord)
+	return v == "1" || v == "true"
+}
+
+// Cassette is the file format of recorded interactions.
+type Cassette struct {
+	// Vars are values needed to replay the interactions, e.g. node names
+	Vars         map[string]string `json:"vars,omitempty"`
+	Interactions []Interaction     `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Apart from matchedHeaders, request headers
+// are not recorded, as they carry the credentials of the API clients.
+type Request struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body,omitempty"`
+}
+
+// matchedHeaders select the operation of APIs that post every request to the
+// same URL.
+var matchedHeaders = []string{"X-Amz-Target"}
+
+func newRequest(req *http.Request, body string) Request {
+	r := Request{Method: req.Method, URL: normalizeURL(req.URL), Body: body}
+	for _, k := range matchedHeaders {
+		if v := req.Header.Get(k); v != "" {
+			if r.Header == nil {
+				r.Header = map[string]string{}
+			}
+			r.Header[k] = v
+		}
+	}
+	return r
+}
+
+func (r Request) matches(o Request) bool {
+	return r.Method == o.Method && r.URL == o.URL && r.Body == o.Body && reflect.DeepEqual(r.Header, o.Header)
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder records or replays the interactions of a cassette file.
+type Recorder struct {
+	path      string
+	recording bool
+	secrets   []string
+	next      http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+// New returns a Recorder for the cassette at path. In replay mode the
+// cassette must exist. Every occurrence of secrets is replaced by Redacted
+// before an interaction is recorded.
+func New(path string, secrets ...string) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		recording: Recording(),
+		next:      http.DefaultTransport,
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	if r.recording {
+		r.cassette.Vars = map[string]string{}
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %v", path, err)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Open returns the Recorder for the cassette at path together with the cloud
+// config and the node name to test with. In record mode they are read from
+// PHARMER_RECORD_CONFIG and PHARMER_RECORD_NODE and stored in the cassette,
+// with the values returned by secrets scrubbed. In replay mode they are read
+// from the cassette.
+func Open(path string, secrets func(config []byte) ([]string, error)) (*Recorder, []byte, string, error) {
+	if !Recording() {
+		r, err := New(path)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return r, []byte(r.Var("config")), r.Var("node"), nil
+	}
+
+	if os.Getenv(EnvConfig) == "" || os.Getenv(EnvNode) == "" {
+		return nil, nil, "", fmt.Errorf("%s and %s must be set to record %s", EnvConfig, EnvNode, path)
+	}
+	config, err := ioutil.ReadFile(os.Getenv(EnvConfig))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	s, err := secrets(config)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	r, err := New(path, s...)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	r.SetVar("config", string(config))
+	r.SetVar("node", os.Getenv(EnvNode))
+	return r, config, os.Getenv(EnvNode), nil
+}
+
+// Recording returns true if r records new interactions.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Var returns a value stored in the cassette.
+func (r *Recorder) Var(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Vars[key]
+}
+
+// SetVar stores a value in the cassette. It is scrubbed like interactions.
+func (r *Recorder) SetVar(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Vars[key] = r.scrub(value)
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing in replay mode.
+func (r *Recorder) Save() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := yaml.Marshal(r.cassette)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0644)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.recording {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	header := http.Header{}
+	for k, values := range resp.Header {
+		// the length changes when secrets are scrubbed from the body
+		if k == "Set-Cookie" || k == "Content-Length" || k == "Date" {
+			continue
+		}
+		for _, v := range values {
+			header.Add(k, r.scrub(v))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: r.scrubRequest(newRequest(req, string(body))),
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       r.scrub(string(respBody)),
+		},
+	})
+	return resp, nil
+}
+
+// replay answers req with the first interaction that matches its method, URL
+// and body and has not been replayed yet.
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	request := r.scrubRequest(newRequest(req, string(body)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.replayed[i] {
+			continue
+		}
+		if interaction.Request.matches(request) {
+			r.replayed[i] = true
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+				StatusCode:    interaction.Response.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        interaction.Response.Header,
+				Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+				ContentLength: int64(len(interaction.Response.Body)),
+				Request:       req,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("cassette %s has no interaction for %s %s", r.path, request.Method, request.URL)
+}
+
+// Proxy returns a server that sends the requests it receives to upstream
+// through r. It is used for API clients that do not accept an HTTP client,
+// by pointing their endpoint to the server.
+func (r *Recorder) Proxy(upstream string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, in *http.Request) {
+		out, err := http.NewRequest(in.Method, strings.TrimSuffix(upstream, "/")+in.URL.RequestURI(), in.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		out.Header = in.Header
+		resp, err := r.RoundTrip(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, values := range resp.Header {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+}
+
+func (r *Recorder) scrubRequest(req Request) Request {
+	req.URL = r.scrub(req.URL)
+	req.Body = r.scrub(req.Body)
+	return req
+}
+
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
+
+// normalizeURL returns u with sorted query parameters, so that interactions
+// match regardless of the order clients encode them in.
+func normalizeURL(u *url.URL) string {
+	n := *u
+	n.RawQuery = u.Query().Encode()
+	n.ForceQuery = false
+	n.Fragment = ""
+	return n.String()
+}
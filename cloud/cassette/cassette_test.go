@@ -0,0 +1,73 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.yaml")
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"owner":"s3cr3t","page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+	defer api.Close()
+
+	os.Setenv(EnvRecord, "1")
+	rec, err := New(path, "s3cr3t")
+	os.Unsetenv(EnvRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec}
+	for _, page := range []string{"1", "2"} {
+		if _, err := client.Get(api.URL + "/servers?token=s3cr3t&page=" + page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("cassette contains secret:\n%s", data)
+	}
+
+	// replay in a different order, without the API
+	api.Close()
+	rec, err = New(path, "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: rec}
+	for _, page := range []string{"2", "1"} {
+		resp, err := client.Get(api.URL + "/servers?page=" + page + "&token=s3cr3t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if expected := `{"owner":"REDACTED","page":"` + page + `"}`; string(body) != expected {
+			t.Errorf("expected %s, got %s", expected, body)
+		}
+	}
+
+	// every interaction is replayed once
+	if _, err := client.Get(api.URL + "/servers?page=1&token=s3cr3t"); err == nil {
+		t.Error("expected error for interaction that was already replayed")
+	}
+}
@@ -0,0 +1,122 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+// Pharmer marks the instances of a cluster with tags. ClusterTagPrefix
+// followed by the cluster name makes an instance a member of the cluster and
+// MasterTag marks the masters among them. Providers that support a single tag
+// per instance only carry the cluster tag; their masters are recognized by the
+// host name <cluster>-master. The tags only use characters that are valid in
+// the tags of all providers.
+const (
+	ClusterTagPrefix = "KubernetesCluster:"
+	MasterTag        = "KubernetesRole:master"
+)
+
+// ClusterTag returns the tag that makes a resource a member of cluster.
+func ClusterTag(cluster string) string {
+	return ClusterTagPrefix + cluster
+}
+
+// HasClusterTag returns true if tags contain the tag of cluster clusterID.
+func HasClusterTag(tags []string, clusterID string) bool {
+	for _, tag := range tags {
+		if tag == ClusterTag(clusterID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterMember is an instance that belongs to a cluster.
+type ClusterMember struct {
+	Cluster string
+	Name    string
+	Master  bool
+	// Address is the public address of the instance, or the private address
+	// if it has no public one.
+	Address string
+}
+
+// NewClusterMember returns the cluster membership of the instance called
+// name with tags, or false if the instance does not belong to a cluster.
+func NewClusterMember(name string, tags []string, address string) (ClusterMember, bool) {
+	member := ClusterMember{Name: name, Address: address}
+	for _, tag := range tags {
+		switch {
+		case strings.HasPrefix(tag, ClusterTagPrefix):
+			member.Cluster = strings.TrimPrefix(tag, ClusterTagPrefix)
+		case tag == MasterTag:
+			member.Master = true
+		}
+	}
+	if member.Cluster == "" {
+		return ClusterMember{}, false
+	}
+	if name == member.Cluster+"-master" {
+		member.Master = true
+	}
+	return member, true
+}
+
+// ListMembersFunc lists the cluster members visible to a provider. If cluster
+// is not empty, members of other clusters may be omitted.
+type ListMembersFunc func(ctx context.Context, cluster string) ([]ClusterMember, error)
+
+type clusters struct {
+	list ListMembersFunc
+}
+
+// NewClusters returns a cloudprovider.Clusters that finds clusters and their
+// masters in the members returned by list.
+func NewClusters(list ListMembersFunc) cloudprovider.Clusters {
+	return &clusters{list: list}
+}
+
+// ListClusters returns the sorted names of the clusters with at least one
+// member.
+func (c *clusters) ListClusters(ctx context.Context) ([]string, error) {
+	members, err := c.list(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	names := []string{}
+	for _, m := range members {
+		if !found[m.Cluster] {
+			found[m.Cluster] = true
+			names = append(names, m.Cluster)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Master returns the address of the master of clusterName. If the cluster has
+// several masters, the one with the lowest name is returned.
+func (c *clusters) Master(ctx context.Context, clusterName string) (string, error) {
+	members, err := c.list(ctx, clusterName)
+	if err != nil {
+		return "", err
+	}
+	var master *ClusterMember
+	for i, m := range members {
+		if m.Cluster != clusterName || !m.Master || m.Address == "" {
+			continue
+		}
+		if master == nil || m.Name < master.Name {
+			master = &members[i]
+		}
+	}
+	if master == nil {
+		return "", fmt.Errorf("no master found for cluster %s", clusterName)
+	}
+	return master.Address, nil
+}
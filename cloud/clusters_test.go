@@ -0,0 +1,61 @@
+package cloud
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	var members []ClusterMember
+	for _, i := range []struct {
+		name, address string
+		tags          []string
+	}{
+		{"prod-master", "203.0.113.10", []string{ClusterTag("prod")}},
+		{"prod-node-1", "203.0.113.11", []string{ClusterTag("prod")}},
+		{"db-2", "203.0.113.22", []string{"db", MasterTag, ClusterTag("dev")}},
+		{"db-1", "203.0.113.21", []string{ClusterTag("dev"), MasterTag}},
+		{"staging-master", "", []string{ClusterTag("staging")}},
+		{"bastion", "203.0.113.1", []string{MasterTag}},
+	} {
+		if m, ok := NewClusterMember(i.name, i.tags, i.address); ok {
+			members = append(members, m)
+		}
+	}
+	c := NewClusters(func(_ context.Context, cluster string) ([]ClusterMember, error) {
+		return members, nil
+	})
+	ctx := context.Background()
+
+	names, err := c.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"dev", "prod", "staging"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, names)
+	}
+
+	for cluster, expected := range map[string]string{"prod": "203.0.113.10", "dev": "203.0.113.21"} {
+		master, err := c.Master(ctx, cluster)
+		if err != nil || master != expected {
+			t.Errorf("expected master %s of cluster %s, got %q (%v)", expected, cluster, master, err)
+		}
+	}
+	for _, cluster := range []string{"staging", "missing"} {
+		if _, err := c.Master(ctx, cluster); err == nil {
+			t.Errorf("expected error for cluster %s without master address", cluster)
+		}
+	}
+}
+
+func TestHasClusterTag(t *testing.T) {
+	for _, tags := range [][]string{{"db", ClusterTag("prod")}, {ClusterTag("prod"), MasterTag}} {
+		if !HasClusterTag(tags, "prod") {
+			t.Errorf("expected tags %v to have the cluster tag of prod", tags)
+		}
+	}
+	if HasClusterTag([]string{ClusterTag("production"), ClusterTag("dev")}, "prod") {
+		t.Error("expected no cluster tag of prod")
+	}
+}
@@ -0,0 +1,321 @@
+// Package conformance checks that a cloudprovider.Interface implementation
+// honours the contract the cloud controllers rely on. Providers run it in
+// their tests against a stand-in server that serves a known inventory.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/cassette"
+)
+
+const clusterName = "conformance"
+
+// Fixture describes the inventory served to the provider under test.
+type Fixture struct {
+	// Instances must all be known to the provider
+	Instances []Instance
+	// MissingName and MissingProviderID must not be known to the provider
+	MissingName       types.NodeName
+	MissingProviderID string
+}
+
+// Instance is the expected view of a node through the provider.
+type Instance struct {
+	Name       types.NodeName
+	ProviderID string
+	Type       string
+	// Addresses in the order the provider must report them
+	Addresses []v1.NodeAddress
+	Zone      cloudprovider.Zone
+}
+
+// Run runs the conformance suite against c.
+func Run(t *testing.T, c cloudprovider.Interface, fixture Fixture) {
+	if len(fixture.Instances) == 0 {
+		t.Fatal("fixture has no instances")
+	}
+	instances, ok := c.Instances()
+	if !ok {
+		t.Fatalf("%s: instances are not supported", c.ProviderName())
+	}
+	t.Run("ProviderIDRoundTrip", func(t *testing.T) {
+		testProviderIDRoundTrip(t, c.ProviderName(), instances, fixture)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		testNotFound(t, instances, fixture)
+	})
+	t.Run("AddressOrdering", func(t *testing.T) {
+		testAddressOrdering(t, instances, fixture)
+	})
+	t.Run("ZoneConsistency", func(t *testing.T) {
+		zones, ok := c.Zones()
+		if !ok {
+			t.Skip("zones are not supported")
+		}
+		testZoneConsistency(t, zones, fixture)
+	})
+	t.Run("LoadBalancerIdempotency", func(t *testing.T) {
+		lb, ok := c.LoadBalancer()
+		if !ok {
+			t.Skip("load balancers are not supported")
+		}
+		testLoadBalancerIdempotency(t, lb, fixture)
+	})
+}
+
+func testProviderIDRoundTrip(t *testing.T, provider string, instances cloudprovider.Instances, fixture Fixture) {
+	ctx := context.Background()
+	for _, instance := range fixture.Instances {
+		id, err := instances.InstanceID(ctx, instance.Name)
+		if err != nil {
+			t.Errorf("InstanceID(%s): %v", instance.Name, err)
+			continue
+		}
+		if providerID := provider + "://" + id; providerID != instance.ProviderID {
+			t.Errorf("InstanceID(%s): expected provider ID %s, got %s", instance.Name, instance.ProviderID, providerID)
+		}
+
+		exists, err := instances.InstanceExistsByProviderID(ctx, instance.ProviderID)
+		if err != nil || !exists {
+			t.Errorf("InstanceExistsByProviderID(%s): expected true, got %v (%v)", instance.ProviderID, exists, err)
+		}
+
+		byName, err := instances.InstanceType(ctx, instance.Name)
+		if err != nil {
+			t.Errorf("InstanceType(%s): %v", instance.Name, err)
+		}
+		byID, err := instances.InstanceTypeByProviderID(ctx, instance.ProviderID)
+		if err != nil {
+			t.Errorf("InstanceTypeByProviderID(%s): %v", instance.ProviderID, err)
+		}
+		if byName != instance.Type || byID != instance.Type {
+			t.Errorf("%s: expected instance type %s, got %s by name and %s by provider ID", instance.Name, instance.Type, byName, byID)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, instances cloudprovider.Instances, fixture Fixture) {
+	ctx := context.Background()
+	name, providerID := fixture.MissingName, fixture.MissingProviderID
+
+	if _, err := instances.NodeAddresses(ctx, name); err != cloudprovider.InstanceNotFound {
+		t.Errorf("NodeAddresses(%s): expected InstanceNotFound, got %v", name, err)
+	}
+	if _, err := instances.NodeAddressesByProviderID(ctx, providerID); err != cloudprovider.InstanceNotFound {
+		t.Errorf("NodeAddressesByProviderID(%s): expected InstanceNotFound, got %v", providerID, err)
+	}
+	if _, err := instances.InstanceID(ctx, name); err != cloudprovider.InstanceNotFound {
+		t.Errorf("InstanceID(%s): expected InstanceNotFound, got %v", name, err)
+	}
+	if _, err := instances.InstanceType(ctx, name); err != cloudprovider.InstanceNotFound {
+		t.Errorf("InstanceType(%s): expected InstanceNotFound, got %v", name, err)
+	}
+	if _, err := instances.InstanceTypeByProviderID(ctx, providerID); err != cloudprovider.InstanceNotFound {
+		t.Errorf("InstanceTypeByProviderID(%s): expected InstanceNotFound, got %v", providerID, err)
+	}
+
+	// a missing instance is not an error for the node lifecycle controller
+	if exists, err := instances.InstanceExistsByProviderID(ctx, providerID); err != nil || exists {
+		t.Errorf("InstanceExistsByProviderID(%s): expected false, got %v (%v)", providerID, exists, err)
+	}
+	// but a provider ID of another provider is
+	if _, err := instances.InstanceExistsByProviderID(ctx, "other://"+string(name)); err == nil {
+		t.Errorf("InstanceExistsByProviderID(other://%s): expected error", name)
+	}
+}
+
+func testAddressOrdering(t *testing.T, instances cloudprovider.Instances, fixture Fixture) {
+	ctx := context.Background()
+	for _, instance := range fixture.Instances {
+		byName, err := instances.NodeAddresses(ctx, instance.Name)
+		if err != nil {
+			t.Errorf("NodeAddresses(%s): %v", instance.Name, err)
+		} else if !reflect.DeepEqual(byName, instance.Addresses) {
+			t.Errorf("NodeAddresses(%s): expected %v, got %v", instance.Name, instance.Addresses, byName)
+		}
+
+		byID, err := instances.NodeAddressesByProviderID(ctx, instance.ProviderID)
+		if err != nil {
+			t.Errorf("NodeAddressesByProviderID(%s): %v", instance.ProviderID, err)
+		} else if !reflect.DeepEqual(byID, instance.Addresses) {
+			t.Errorf("NodeAddressesByProviderID(%s): expected %v, got %v", instance.ProviderID, instance.Addresses, byID)
+		}
+	}
+}
+
+func testZoneConsistency(t *testing.T, zones cloudprovider.Zones, fixture Fixture) {
+	ctx := context.Background()
+	for _, instance := range fixture.Instances {
+		byName, err := zones.GetZoneByNodeName(ctx, instance.Name)
+		if err != nil {
+			t.Errorf("GetZoneByNodeName(%s): %v", instance.Name, err)
+		} else if byName != instance.Zone {
+			t.Errorf("GetZoneByNodeName(%s): expected %+v, got %+v", instance.Name, instance.Zone, byName)
+		}
+
+		byID, err := zones.GetZoneByProviderID(ctx, instance.ProviderID)
+		if err != nil {
+			t.Errorf("GetZoneByProviderID(%s): %v", instance.ProviderID, err)
+		} else if byID != instance.Zone {
+			t.Errorf("GetZoneByProviderID(%s): expected %+v, got %+v", instance.ProviderID, instance.Zone, byID)
+		}
+	}
+
+	if _, err := zones.GetZoneByNodeName(ctx, fixture.MissingName); err != cloudprovider.InstanceNotFound {
+		t.Errorf("GetZoneByNodeName(%s): expected InstanceNotFound, got %v", fixture.MissingName, err)
+	}
+	if _, err := zones.GetZoneByProviderID(ctx, fixture.MissingProviderID); err != cloudprovider.InstanceNotFound {
+		t.Errorf("GetZoneByProviderID(%s): expected InstanceNotFound, got %v", fixture.MissingProviderID, err)
+	}
+}
+
+func testLoadBalancerIdempotency(t *testing.T, lb cloudprovider.LoadBalancer, fixture Fixture) {
+	ctx := context.Background()
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "conformance", Namespace: metav1.NamespaceDefault, UID: "2a0c1f3e-5b6d-4e7f-8a9b-0c1d2e3f4a5b"},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+			Ports: []v1.ServicePort{
+				{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(8080), NodePort: 30080},
+			},
+		},
+	}
+	var nodes []*v1.Node
+	for _, instance := range fixture.Instances {
+		nodes = append(nodes, &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: string(instance.Name)},
+			Spec:       v1.NodeSpec{ProviderID: instance.ProviderID},
+			Status:     v1.NodeStatus{Addresses: instance.Addresses},
+		})
+	}
+
+	first, err := lb.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+	if err == cloud.ErrLBUnsupported {
+		t.Skip("load balancers are not supported")
+	}
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer: %v", err)
+	}
+	second, err := lb.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer (again): %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("EnsureLoadBalancer: expected the same status twice, got %v and %v", first, second)
+	}
+
+	status, exists, err := lb.GetLoadBalancer(ctx, clusterName, service)
+	if err != nil || !exists {
+		t.Errorf("GetLoadBalancer: expected load balancer to exist, got %v (%v)", exists, err)
+	} else if !reflect.DeepEqual(status, first) {
+		t.Errorf("GetLoadBalancer: expected %v, got %v", first, status)
+	}
+
+	if err := lb.UpdateLoadBalancer(ctx, clusterName, service, nodes[:len(nodes)-1]); err != nil {
+		t.Errorf("UpdateLoadBalancer: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := lb.EnsureLoadBalancerDeleted(ctx, clusterName, service); err != nil {
+			t.Errorf("EnsureLoadBalancerDeleted (%d): %v", i+1, err)
+		}
+	}
+	if _, exists, err := lb.GetLoadBalancer(ctx, clusterName, service); err != nil || exists {
+		t.Errorf("GetLoadBalancer: expected load balancer to be deleted, got %v (%v)", exists, err)
+	}
+}
+
+// RunNode checks that c gives a consistent view of the existing node name. It
+// needs no fixture, so it can run against recorded interactions with a real
+// account.
+func RunNode(t *testing.T, c cloudprovider.Interface, name types.NodeName) {
+	ctx := context.Background()
+	instances, ok := c.Instances()
+	if !ok {
+		t.Fatalf("%s: instances are not supported", c.ProviderName())
+	}
+
+	id, err := instances.InstanceID(ctx, name)
+	if err != nil {
+		t.Fatalf("InstanceID(%s): %v", name, err)
+	}
+	providerID := c.ProviderName() + "://" + id
+
+	exists, err := instances.InstanceExistsByProviderID(ctx, providerID)
+	if err != nil || !exists {
+		t.Errorf("InstanceExistsByProviderID(%s): expected true, got %v (%v)", providerID, exists, err)
+	}
+
+	byName, err := instances.NodeAddresses(ctx, name)
+	if err != nil {
+		t.Errorf("NodeAddresses(%s): %v", name, err)
+	}
+	byID, err := instances.NodeAddressesByProviderID(ctx, providerID)
+	if err != nil {
+		t.Errorf("NodeAddressesByProviderID(%s): %v", providerID, err)
+	}
+	if len(byName) == 0 || !reflect.DeepEqual(byName, byID) {
+		t.Errorf("%s: expected the same addresses by name and provider ID, got %v and %v", name, byName, byID)
+	}
+
+	typeByName, err := instances.InstanceType(ctx, name)
+	if err != nil {
+		t.Errorf("InstanceType(%s): %v", name, err)
+	}
+	typeByID, err := instances.InstanceTypeByProviderID(ctx, providerID)
+	if err != nil {
+		t.Errorf("InstanceTypeByProviderID(%s): %v", providerID, err)
+	}
+	if typeByName != typeByID {
+		t.Errorf("%s: expected the same instance type by name and provider ID, got %s and %s", name, typeByName, typeByID)
+	}
+
+	if zones, ok := c.Zones(); ok {
+		zoneByName, err := zones.GetZoneByNodeName(ctx, name)
+		if err != nil {
+			t.Errorf("GetZoneByNodeName(%s): %v", name, err)
+		}
+		zoneByID, err := zones.GetZoneByProviderID(ctx, providerID)
+		if err != nil {
+			t.Errorf("GetZoneByProviderID(%s): %v", providerID, err)
+		}
+		if zoneByName != zoneByID {
+			t.Errorf("%s: expected the same zone by name and provider ID, got %+v and %+v", name, zoneByName, zoneByID)
+		}
+	}
+}
+
+// Replay runs RunNode against the registered cloud provider with the
+// interactions of the cassette at path, see cassette.Open. secrets returns the
+// credentials in the cloud config, which are scrubbed from the cassette.
+func Replay(t *testing.T, provider, path string, secrets func(config []byte) ([]string, error)) {
+	rec, config, node, err := cassette.Open(path, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud.SetTransport(rec)
+	defer cloud.SetTransport(nil)
+
+	c, err := cloudprovider.GetCloudProvider(provider, bytes.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil {
+		t.Fatalf("cloud provider %s is not registered", provider)
+	}
+	RunNode(t, c, types.NodeName(node))
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+const (
+	EventReasonDryRun = "DryRun"
+)
+
+var dryRun bool
+
+// SetDryRun enables or disables dry-run mode for all cloud providers. It must
+// be called before the cloud provider is initialized.
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+}
+
+// DryRun returns true if mutating cloud API calls must not be sent.
+func DryRun() bool {
+	return dryRun
+}
+
+// Params are the intended parameters of a mutating operation.
+type Params map[string]string
+
+func (p Params) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, p[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Mutator runs mutating cloud API calls. In dry-run mode the calls are only
+// logged and emitted as Events with their intended parameters.
+type Mutator struct {
+	provider  string
+	clusterID string
+	recorder  record.EventRecorder
+}
+
+// NewMutator returns a Mutator for the resources of the cluster clusterID,
+// which may be empty if no cluster ID is configured.
+func NewMutator(provider, clusterID string) *Mutator {
+	return &Mutator{provider: provider, clusterID: clusterID}
+}
+
+// ClusterID returns the ID of the cluster owning the resources the mutator
+// changes. Resources created by a provider must be tagged with it, see
+// ClusterTag.
+func (m *Mutator) ClusterID() string {
+	return m.clusterID
+}
+
+// Initialize sets up the event recorder used to report dry-run operations.
+func (m *Mutator) Initialize(clientBuilder cloudprovider.ControllerClientBuilder) {
+	client := clientBuilder.ClientOrDie(m.provider + "-cloud-provider")
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	m.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: m.provider + "-cloud-provider"})
+}
+
+// Recorder returns the event recorder, or nil if the mutator is not initialized.
+func (m *Mutator) Recorder() record.EventRecorder {
+	return m.recorder
+}
+
+// Do runs fn unless dry-run mode is enabled. The operation is reported
+// against obj, which may be nil for operations not tied to an object.
+func (m *Mutator) Do(obj runtime.Object, operation string, params Params, fn func() error) error {
+	if !dryRun {
+		return fn()
+	}
+
+	message := fmt.Sprintf("would %s %s", operation, params)
+	log.Infof("[dry-run] %s: %s", m.provider, message)
+	if m.recorder != nil && obj != nil {
+		m.recorder.Event(obj, v1.EventTypeNormal, EventReasonDryRun, message)
+	}
+	return nil
+}
+
+// Destroy runs a destructive operation like Do. It fails with ErrNoClusterID
+// if no cluster ID is configured, as the resource could belong to another
+// cluster. Providers still report a cluster ID to the controller manager
+// when none is configured, so that existing deployments without one keep
+// running; only their destructive operations are refused.
+func (m *Mutator) Destroy(obj runtime.Object, operation string, params Params, fn func() error) error {
+	if m.clusterID == "" {
+		return ErrNoClusterID
+	}
+	return m.Do(obj, operation, params, fn)
+}
@@ -0,0 +1,70 @@
+package cloud
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestMutatorDryRun(t *testing.T) {
+	defer SetDryRun(false)
+
+	recorder := record.NewFakeRecorder(1)
+	m := &Mutator{provider: "test", recorder: recorder}
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	called := false
+	fn := func() error {
+		called = true
+		return nil
+	}
+
+	SetDryRun(true)
+	if err := m.Do(svc, "create load balancer", Params{"name": "web", "port": "80"}, fn); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Fatal("operation must not run in dry-run mode")
+	}
+	select {
+	case e := <-recorder.Events:
+		if want := `Normal DryRun would create load balancer name="web" port="80"`; e != want {
+			t.Errorf("expected event %q, got %q", want, e)
+		}
+	default:
+		t.Error("expected a dry-run event")
+	}
+
+	SetDryRun(false)
+	if err := m.Do(svc, "create load balancer", nil, fn); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("operation must run when dry-run mode is disabled")
+	}
+}
+
+func TestMutatorDestroy(t *testing.T) {
+	called := false
+	fn := func() error {
+		called = true
+		return nil
+	}
+
+	if err := NewMutator("test", "").Destroy(nil, "delete load balancer", nil, fn); err != ErrNoClusterID {
+		t.Errorf("expected ErrNoClusterID, got %v", err)
+	}
+	if called {
+		t.Fatal("destructive operation must not run without a cluster ID")
+	}
+
+	m := NewMutator("test", "prod")
+	if err := m.Destroy(nil, "delete load balancer", nil, fn); err != nil {
+		t.Fatal(err)
+	}
+	if !called || m.ClusterID() != "prod" {
+		t.Error("destructive operation must run with a cluster ID")
+	}
+}
@@ -0,0 +1,35 @@
+package cloud
+
+import "sync"
+
+var (
+	endpointsMutex sync.Mutex
+	endpoints      = make(map[string]func() (string, error))
+)
+
+// RegisterAPIEndpoint records the API endpoint of a cloud provider. It is
+// used by startup readiness checks to verify that the provider API is
+// reachable before the controllers are started.
+func RegisterAPIEndpoint(provider, endpoint string) {
+	RegisterAPIEndpointFunc(provider, func() (string, error) {
+		return endpoint, nil
+	})
+}
+
+// RegisterAPIEndpointFunc records a function resolving the API endpoint of a
+// cloud provider whose endpoint depends on where it runs, e.g. on the region
+// of the instance. See RegisterAPIEndpoint.
+func RegisterAPIEndpointFunc(provider string, endpoint func() (string, error)) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	endpoints[provider] = endpoint
+}
+
+// APIEndpoint returns the function resolving the API endpoint registered for
+// provider.
+func APIEndpoint(provider string) (func() (string, error), bool) {
+	endpointsMutex.Lock()
+	defer endpointsMutex.Unlock()
+	endpoint, found := endpoints[provider]
+	return endpoint, found
+}
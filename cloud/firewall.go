@@ -0,0 +1,81 @@
+package cloud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	servicehelper "k8s.io/kubernetes/pkg/api/v1/service"
+)
+
+// DefaultNodePortRange is the default range of the NodePorts of Services, see
+// the --service-node-port-range flag of the API server.
+var DefaultNodePortRange = PortRange{Min: 30000, Max: 32767}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	Min, Max int
+}
+
+// Contains returns true if port is in the range.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Min && port <= r.Max
+}
+
+// NodePortRule allows traffic from a source network to a NodePort.
+type NodePortRule struct {
+	// Protocol is tcp or udp
+	Protocol string
+	Port     int
+	// Source is a network in CIDR notation, e.g. 0.0.0.0/0
+	Source string
+}
+
+func (r NodePortRule) String() string {
+	return fmt.Sprintf("%s/%d from %s", r.Protocol, r.Port, r.Source)
+}
+
+// NodePortRules returns the rules that open the NodePorts of service to its
+// load balancer source ranges, ordered by port, protocol and source. Services
+// that are not of type LoadBalancer have no rules. Ports of protocols other
+// than TCP and UDP are skipped.
+func NodePortRules(service *v1.Service) ([]NodePortRule, error) {
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return nil, nil
+	}
+	sources, err := servicehelper.GetLoadBalancerSourceRanges(service)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []NodePortRule
+	for _, port := range service.Spec.Ports {
+		if port.NodePort == 0 || (port.Protocol != v1.ProtocolTCP && port.Protocol != v1.ProtocolUDP) {
+			continue
+		}
+		for _, source := range sources.StringSlice() {
+			rules = append(rules, NodePortRule{
+				Protocol: strings.ToLower(string(port.Protocol)),
+				Port:     int(port.NodePort),
+				Source:   source,
+			})
+		}
+	}
+	SortNodePortRules(rules)
+	return rules, nil
+}
+
+// SortNodePortRules orders rules by port, protocol and source.
+func SortNodePortRules(rules []NodePortRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Source < b.Source
+	})
+}
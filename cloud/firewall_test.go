@@ -0,0 +1,59 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNodePortRules(t *testing.T) {
+	service := &v1.Service{
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+			Ports: []v1.ServicePort{
+				{Protocol: v1.ProtocolUDP, Port: 53, NodePort: 30053},
+				{Protocol: v1.ProtocolTCP, Port: 443, NodePort: 30443},
+				{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080},
+			},
+			LoadBalancerSourceRanges: []string{"203.0.113.0/24", "198.51.100.7/32"},
+		},
+	}
+	rules, err := NodePortRules(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []NodePortRule{
+		{Protocol: "udp", Port: 30053, Source: "198.51.100.7/32"},
+		{Protocol: "udp", Port: 30053, Source: "203.0.113.0/24"},
+		{Protocol: "tcp", Port: 30080, Source: "198.51.100.7/32"},
+		{Protocol: "tcp", Port: 30080, Source: "203.0.113.0/24"},
+		{Protocol: "tcp", Port: 30443, Source: "198.51.100.7/32"},
+		{Protocol: "tcp", Port: 30443, Source: "203.0.113.0/24"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+
+	// without source ranges the NodePorts are open to all
+	service.Spec.LoadBalancerSourceRanges = nil
+	service.Spec.Ports = service.Spec.Ports[2:]
+	rules, err = NodePortRules(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []NodePortRule{{Protocol: "tcp", Port: 30080, Source: "0.0.0.0/0"}}; !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+
+	service.Spec.Type = v1.ServiceTypeNodePort
+	if rules, err := NodePortRules(service); err != nil || rules != nil {
+		t.Errorf("expected no rules for NodePort service, got %v (%v)", rules, err)
+	}
+
+	service.Spec.Type = v1.ServiceTypeLoadBalancer
+	service.Spec.LoadBalancerSourceRanges = []string{"not-a-cidr"}
+	if _, err := NodePortRules(service); err == nil {
+		t.Error("expected error for invalid source range")
+	}
+}
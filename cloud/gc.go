@@ -0,0 +1,209 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+type GCMode string
+
+const (
+	// GCModeReport logs orphaned resources without deleting them.
+	GCModeReport GCMode = "report"
+	// GCModeDelete deletes orphaned resources.
+	GCModeDelete GCMode = "delete"
+)
+
+// GCOptions configure the garbage collection of orphaned resources.
+type GCOptions struct {
+	Mode GCMode
+	// GracePeriod is the time a resource must be orphaned before it is
+	// deleted or reported
+	GracePeriod time.Duration
+	// Interval is the time between two collections
+	Interval time.Duration
+}
+
+// DefaultGCOptions only report resources orphaned for ten minutes.
+var DefaultGCOptions = GCOptions{Mode: GCModeReport, GracePeriod: 10 * time.Minute, Interval: 5 * time.Minute}
+
+var gcOptions = DefaultGCOptions
+
+// SetGCOptions configures the garbage collectors of all cloud providers. It
+// must be called before the cloud provider is initialized.
+func SetGCOptions(options GCOptions) error {
+	if options.Mode != GCModeReport && options.Mode != GCModeDelete {
+		return fmt.Errorf("invalid gc mode %q, must be %s or %s", options.Mode, GCModeReport, GCModeDelete)
+	}
+	if options.Interval <= 0 {
+		return fmt.Errorf("gc interval must be positive, got %v", options.Interval)
+	}
+	gcOptions = options
+	return nil
+}
+
+// OwnedResource is a cloud resource that a provider created for the load
+// balancer of a Service, or for a NodePort of LoadBalancer Services.
+type OwnedResource struct {
+	// Kind describes the resource, e.g. reserved IP
+	Kind string
+	ID   string
+	// LoadBalancerName is the name of the load balancer the resource was
+	// created for, see cloudprovider.DefaultLoadBalancerName
+	LoadBalancerName string
+	// NodePort is the NodePort the resource was created for if it has no
+	// LoadBalancerName, e.g. a firewall rule opening it. It is in use as long
+	// as a LoadBalancer Service has the NodePort.
+	NodePort int
+}
+
+func (r OwnedResource) String() string {
+	if r.LoadBalancerName == "" {
+		return fmt.Sprintf("%s %s of NodePort %d", r.Kind, r.ID, r.NodePort)
+	}
+	return fmt.Sprintf("%s %s of load balancer %s", r.Kind, r.ID, r.LoadBalancerName)
+}
+
+func (r OwnedResource) params() Params {
+	if r.LoadBalancerName == "" {
+		return Params{"id": r.ID, "nodePort": strconv.Itoa(r.NodePort)}
+	}
+	return Params{"id": r.ID, "loadBalancer": r.LoadBalancerName}
+}
+
+// ResourceSource lists and deletes the resources of one kind that a provider
+// created for load balancers or NodePorts. Resources shared by several load
+// balancers must not be listed.
+type ResourceSource interface {
+	// ListOwned lists the resources tagged with clusterID.
+	ListOwned(ctx context.Context, clusterID string) ([]OwnedResource, error)
+	DeleteOwned(ctx context.Context, resource OwnedResource) error
+}
+
+// Collector finds the resources of registered sources whose Service no longer
+// exists. Depending on the GCOptions, it deletes or reports them once they
+// have been orphaned for the grace period.
+type Collector struct {
+	mutator *Mutator
+	options GCOptions
+	now     func() time.Time
+
+	mu      sync.Mutex
+	sources []ResourceSource
+	// orphans records, per source, when a resource was first found orphaned
+	orphans []map[OwnedResource]time.Time
+}
+
+func NewCollector(mutator *Mutator) *Collector {
+	return &Collector{
+		mutator: mutator,
+		options: gcOptions,
+		now:     time.Now,
+	}
+}
+
+// Register adds a source of owned resources.
+func (c *Collector) Register(source ResourceSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, source)
+	c.orphans = append(c.orphans, map[OwnedResource]time.Time{})
+}
+
+// Start runs the collector until stop is closed. It does nothing if no
+// source is registered or no cluster ID is configured, as resources could
+// not be told apart from those of other clusters.
+func (c *Collector) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mu.Lock()
+	n := len(c.sources)
+	c.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	if c.mutator.ClusterID() == "" {
+		log.Warningf("%s: not collecting orphaned resources, %v", c.mutator.provider, ErrNoClusterID)
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(c.mutator.provider + "-garbage-collector")
+	go wait.Until(func() {
+		services, err := client.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("%s: failed to list services: %v", c.mutator.provider, err)
+			return
+		}
+		if err := c.Collect(context.Background(), services.Items); err != nil {
+			log.Errorf("%s: failed to collect orphaned resources: %v", c.mutator.provider, err)
+		}
+	}, c.options.Interval, stop)
+}
+
+// Collect runs a single collection against services, the existing Services
+// of the cluster.
+func (c *Collector) Collect(ctx context.Context, services []v1.Service) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	inUse := map[string]bool{}
+	nodePorts := map[int]bool{}
+	for i := range services {
+		if services[i].Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+		inUse[cloudprovider.DefaultLoadBalancerName(&services[i])] = true
+		for _, port := range services[i].Spec.Ports {
+			nodePorts[int(port.NodePort)] = true
+		}
+	}
+
+	now := c.now()
+	var errs []error
+	for i, source := range c.sources {
+		resources, err := source.ListOwned(ctx, c.mutator.ClusterID())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		orphans := map[OwnedResource]time.Time{}
+		for _, r := range resources {
+			if (r.LoadBalancerName != "" && inUse[r.LoadBalancerName]) || (r.LoadBalancerName == "" && nodePorts[r.NodePort]) {
+				continue
+			}
+			since, found := c.orphans[i][r]
+			if !found {
+				since = now
+			}
+			orphans[r] = since
+			if now.Sub(since) < c.options.GracePeriod {
+				continue
+			}
+
+			if c.options.Mode != GCModeDelete {
+				log.Warningf("%s: found orphaned %s, orphaned since %s", c.mutator.provider, r, since.Format(time.RFC3339))
+				continue
+			}
+			err := c.mutator.Destroy(nil, "delete orphaned "+r.Kind, r.params(), func() error {
+				return source.DeleteOwned(ctx, r)
+			})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			log.Infof("%s: deleted orphaned %s", c.mutator.provider, r)
+			delete(orphans, r)
+		}
+		c.orphans[i] = orphans
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,154 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+type testSource struct {
+	clusterID string
+	resources []OwnedResource
+	deleted   []string
+	err       error
+}
+
+func (s *testSource) ListOwned(_ context.Context, clusterID string) ([]OwnedResource, error) {
+	s.clusterID = clusterID
+	return s.resources, s.err
+}
+
+func (s *testSource) DeleteOwned(_ context.Context, r OwnedResource) error {
+	s.deleted = append(s.deleted, r.ID)
+	resources := s.resources[:0]
+	for _, o := range s.resources {
+		if o != r {
+			resources = append(resources, o)
+		}
+	}
+	s.resources = resources
+	return nil
+}
+
+func testService(uid string, typ v1.ServiceType) v1.Service {
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc-" + uid, Namespace: "default", UID: types.UID(uid)},
+		Spec:       v1.ServiceSpec{Type: typ},
+	}
+}
+
+func TestCollector(t *testing.T) {
+	web := testService("1111-aaaa", v1.ServiceTypeLoadBalancer)
+	db := testService("2222-bbbb", v1.ServiceTypeClusterIP)
+	source := &testSource{resources: []OwnedResource{
+		{Kind: "reserved IP", ID: "ip-1", LoadBalancerName: cloudprovider.DefaultLoadBalancerName(&web)},
+		{Kind: "reserved IP", ID: "ip-2", LoadBalancerName: cloudprovider.DefaultLoadBalancerName(&db)},
+		{Kind: "reserved IP", ID: "ip-3", LoadBalancerName: "a33333333"},
+	}}
+
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCollector(NewMutator("test", "prod"))
+	c.options = GCOptions{Mode: GCModeDelete, GracePeriod: 10 * time.Minute, Interval: time.Minute}
+	c.now = func() time.Time { return now }
+	c.Register(source)
+	ctx := context.Background()
+	services := []v1.Service{web, db}
+
+	if err := c.Collect(ctx, services); err != nil {
+		t.Fatal(err)
+	}
+	if source.clusterID != "prod" || len(source.deleted) != 0 {
+		t.Fatalf("expected nothing to be deleted within the grace period, got %v", source.deleted)
+	}
+
+	// a Service that comes back is no longer orphaned
+	now = now.Add(5 * time.Minute)
+	db.Spec.Type = v1.ServiceTypeLoadBalancer
+	if err := c.Collect(ctx, []v1.Service{web, db}); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if err := c.Collect(ctx, services); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"ip-3"}; !reflect.DeepEqual(source.deleted, expected) {
+		t.Errorf("expected %v to be deleted, got %v", expected, source.deleted)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if err := c.Collect(ctx, services); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"ip-3", "ip-2"}; !reflect.DeepEqual(source.deleted, expected) {
+		t.Errorf("expected %v to be deleted, got %v", expected, source.deleted)
+	}
+
+	source.err = errors.New("boom")
+	if err := c.Collect(ctx, services); err == nil {
+		t.Error("expected list error to be returned")
+	}
+}
+
+func TestCollectorReport(t *testing.T) {
+	source := &testSource{resources: []OwnedResource{{Kind: "firewall rule", ID: "fw-1", LoadBalancerName: "a1111"}}}
+	c := NewCollector(NewMutator("test", "prod"))
+	c.options = GCOptions{Mode: GCModeReport, Interval: time.Minute}
+	c.Register(source)
+
+	for i := 0; i < 2; i++ {
+		if err := c.Collect(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(source.deleted) != 0 {
+		t.Errorf("report mode must not delete resources, deleted %v", source.deleted)
+	}
+}
+
+func TestCollectorNodePorts(t *testing.T) {
+	web := testService("1111-aaaa", v1.ServiceTypeLoadBalancer)
+	web.Spec.Ports = []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080}}
+	db := testService("2222-bbbb", v1.ServiceTypeNodePort)
+	db.Spec.Ports = []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 5432, NodePort: 30432}}
+	source := &testSource{resources: []OwnedResource{
+		{Kind: "firewall rule", ID: "fw-1", NodePort: 30080},
+		{Kind: "firewall rule", ID: "fw-2", NodePort: 30432},
+	}}
+	c := NewCollector(NewMutator("test", "prod"))
+	c.options = GCOptions{Mode: GCModeDelete, Interval: time.Minute}
+	c.Register(source)
+
+	if err := c.Collect(context.Background(), []v1.Service{web, db}); err != nil {
+		t.Fatal(err)
+	}
+	// only the NodePorts of LoadBalancer Services are in use
+	if expected := []string{"fw-2"}; !reflect.DeepEqual(source.deleted, expected) {
+		t.Errorf("expected %v to be deleted, got %v", expected, source.deleted)
+	}
+}
+
+func TestSetGCOptions(t *testing.T) {
+	defer SetGCOptions(DefaultGCOptions)
+
+	if err := SetGCOptions(GCOptions{Mode: "purge", Interval: time.Minute}); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+	if err := SetGCOptions(GCOptions{Mode: GCModeDelete}); err == nil {
+		t.Error("expected error for invalid interval")
+	}
+	options := GCOptions{Mode: GCModeDelete, GracePeriod: time.Hour, Interval: time.Minute}
+	if err := SetGCOptions(options); err != nil {
+		t.Fatal(err)
+	}
+	if c := NewCollector(NewMutator("test", "prod")); c.options != options {
+		t.Errorf("expected options %v, got %v", options, c.options)
+	}
+}
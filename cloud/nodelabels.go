@@ -0,0 +1,154 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+// LabelDomain is the domain of the provider specific Node labels, which are
+// prefixed with <provider>.LabelDomain, e.g. vultr.pharmer.dev/plan-name.
+const LabelDomain = "pharmer.dev"
+
+// LabelKey returns the key of the Node label name of provider.
+func LabelKey(provider, name string) string {
+	return provider + "." + LabelDomain + "/" + name
+}
+
+// NodeLabelerOptions configure the enrichment of Nodes with provider specific
+// labels.
+type NodeLabelerOptions struct {
+	Enabled bool
+	// Interval is the time between two updates of the labels
+	Interval time.Duration
+}
+
+// DefaultNodeLabelerOptions leave Nodes alone.
+var DefaultNodeLabelerOptions = NodeLabelerOptions{Enabled: false, Interval: 5 * time.Minute}
+
+var nodeLabelerOptions = DefaultNodeLabelerOptions
+
+// SetNodeLabelerOptions configures the node labelers of all cloud providers. It
+// must be called before the cloud provider is initialized.
+func SetNodeLabelerOptions(options NodeLabelerOptions) error {
+	if options.Interval <= 0 {
+		return fmt.Errorf("node label interval must be positive, got %v", options.Interval)
+	}
+	nodeLabelerOptions = options
+	return nil
+}
+
+// NodeLabelsFunc returns the provider specific labels of node, keyed by
+// LabelKey. It returns cloudprovider.InstanceNotFound if node has no instance.
+type NodeLabelsFunc func(ctx context.Context, node *v1.Node) (map[string]string, error)
+
+// NodeLabeler adds the labels returned by a NodeLabelsFunc to all Nodes. Labels
+// are only added or updated; labels the provider no longer returns are kept.
+type NodeLabeler struct {
+	provider string
+	labels   NodeLabelsFunc
+	options  NodeLabelerOptions
+}
+
+func NewNodeLabeler(provider string, labels NodeLabelsFunc) *NodeLabeler {
+	return &NodeLabeler{provider: provider, labels: labels, options: nodeLabelerOptions}
+}
+
+// Start runs the labeler until stop is closed, if it is enabled.
+func (l *NodeLabeler) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !l.options.Enabled {
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(l.provider + "-node-labeler")
+	go wait.Until(func() {
+		if err := l.Sync(context.Background(), client); err != nil {
+			log.Errorf("%s: failed to label nodes: %v", l.provider, err)
+		}
+	}, l.options.Interval, stop)
+}
+
+// Sync updates the labels of all Nodes once.
+func (l *NodeLabeler) Sync(ctx context.Context, client kubernetes.Interface) error {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		changed, err := l.Changed(ctx, node)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+			continue
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": changed},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := client.CoreV1().Nodes().Patch(node.Name, types.MergePatchType, patch); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+			continue
+		}
+		log.Infof("%s: labeled node %s with %v", l.provider, node.Name, changed)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Changed returns the labels of node that are missing or have a different
+// value. Nodes without an instance are skipped, they are removed by the node
+// lifecycle controller.
+func (l *NodeLabeler) Changed(ctx context.Context, node *v1.Node) (map[string]string, error) {
+	labels, err := l.labels(ctx, node)
+	if err == cloudprovider.InstanceNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]string{}
+	for k, v := range labels {
+		v = LabelValue(v)
+		if v == "" {
+			continue
+		}
+		if current, found := node.Labels[k]; !found || current != v {
+			changed[k] = v
+		}
+	}
+	return changed, nil
+}
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// LabelValue turns s into a valid label value: invalid characters are replaced
+// with '-', the value is cut to 63 characters and must start and end with an
+// alphanumeric character. It returns an empty string if nothing is left.
+func LabelValue(s string) string {
+	v := invalidLabelValueChars.ReplaceAllString(s, "-")
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.TrimFunc(v, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-'
+	})
+}
@@ -0,0 +1,83 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+func TestLabelValue(t *testing.T) {
+	for in, want := range map[string]string{
+		"ams1":                  "ams1",
+		"vc2-1c-1gb":            "vc2-1c-1gb",
+		"Amsterdam, NL":         "Amsterdam-NL",
+		"-x86_64.":              "x86_64",
+		"!!!":                   "",
+		strings.Repeat("a", 70): strings.Repeat("a", 63),
+	} {
+		if got := LabelValue(in); got != want {
+			t.Errorf("LabelValue(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestNodeLabelerChanged(t *testing.T) {
+	key := LabelKey("vultr", "plan-name")
+	if key != "vultr.pharmer.dev/plan-name" {
+		t.Fatalf("unexpected label key %s", key)
+	}
+
+	l := NewNodeLabeler("vultr", func(_ context.Context, node *v1.Node) (map[string]string, error) {
+		switch node.Name {
+		case "gone":
+			return nil, cloudprovider.InstanceNotFound
+		case "broken":
+			return nil, errors.New("api down")
+		}
+		return map[string]string{key: "vc2-1c-1gb", LabelKey("vultr", "empty"): "!"}, nil
+	})
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"other": "x"}}}
+	changed, err := l.Changed(context.Background(), node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{key: "vc2-1c-1gb"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected %v, got %v", want, changed)
+	}
+
+	node.Labels[key] = "vc2-1c-1gb"
+	if changed, err := l.Changed(context.Background(), node); err != nil || len(changed) != 0 {
+		t.Errorf("expected no change for labeled node, got %v, %v", changed, err)
+	}
+
+	node.Name = "gone"
+	if changed, err := l.Changed(context.Background(), node); err != nil || len(changed) != 0 {
+		t.Errorf("expected node without instance to be skipped, got %v, %v", changed, err)
+	}
+
+	node.Name = "broken"
+	if _, err := l.Changed(context.Background(), node); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSetNodeLabelerOptions(t *testing.T) {
+	defer SetNodeLabelerOptions(DefaultNodeLabelerOptions)
+
+	if err := SetNodeLabelerOptions(NodeLabelerOptions{Enabled: true}); err == nil {
+		t.Error("expected error for zero interval")
+	}
+	if err := SetNodeLabelerOptions(NodeLabelerOptions{Enabled: true, Interval: DefaultNodeLabelerOptions.Interval}); err != nil {
+		t.Fatal(err)
+	}
+	if l := NewNodeLabeler("fake", nil); !l.options.Enabled {
+		t.Error("expected labeler to be enabled")
+	}
+}
@@ -0,0 +1,112 @@
+package cloud
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AddTaint adds taint to node, unless it already has a taint with the same key
+// and effect. It returns true if node was changed.
+func AddTaint(node *v1.Node, taint v1.Taint) bool {
+	for i, t := range node.Spec.Taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			if t.Value == taint.Value {
+				return false
+			}
+			node.Spec.Taints[i].Value = taint.Value
+			return true
+		}
+	}
+	node.Spec.Taints = append(node.Spec.Taints, taint)
+	return true
+}
+
+// RemoveTaint removes the taint with key and effect from node. It returns true
+// if node was changed.
+func RemoveTaint(node *v1.Node, key string, effect v1.TaintEffect) bool {
+	for i, t := range node.Spec.Taints {
+		if t.Key == key && t.Effect == effect {
+			node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DrainNode evicts the pods of the node called name, respecting their
+// PodDisruptionBudgets. Mirror pods and pods of DaemonSets are kept, like
+// kubectl drain does, and pods that already terminated are ignored. The node
+// must be cordoned first, or the evicted pods may be scheduled on it again.
+func DrainNode(client kubernetes.Interface, name string) error {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pod := range pods.Items {
+		if !evictable(&pod) {
+			continue
+		}
+		err := client.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policy.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func evictable(pod *v1.Pod) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false
+	}
+	if _, mirror := pod.Annotations[v1.MirrorPodAnnotationKey]; mirror {
+		return false
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
+
+// SetNodeCondition sets condition in the status of node. The transition time
+// of an existing condition is kept unless its status changes. It returns true
+// if node was changed.
+func SetNodeCondition(node *v1.Node, condition v1.NodeCondition) bool {
+	for i, c := range node.Status.Conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message {
+			return false
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		node.Status.Conditions[i] = condition
+		return true
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+	return true
+}
+
+// NodeCondition returns the condition of node with type t, or nil.
+func NodeCondition(node *v1.Node, t v1.NodeConditionType) *v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == t {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
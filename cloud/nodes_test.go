@@ -0,0 +1,96 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func TestTaints(t *testing.T) {
+	node := &v1.Node{}
+	taint := v1.Taint{Key: "example.com/spot", Value: "true", Effect: v1.TaintEffectNoSchedule}
+
+	if !AddTaint(node, taint) || AddTaint(node, taint) {
+		t.Error("expected taint to be added once")
+	}
+	taint.Value = "false"
+	if !AddTaint(node, taint) || len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Value != "false" {
+		t.Errorf("expected taint value to be updated, got %v", node.Spec.Taints)
+	}
+	if RemoveTaint(node, taint.Key, v1.TaintEffectNoExecute) {
+		t.Error("expected taint with other effect to be kept")
+	}
+	if !RemoveTaint(node, taint.Key, taint.Effect) || len(node.Spec.Taints) != 0 {
+		t.Errorf("expected taint to be removed, got %v", node.Spec.Taints)
+	}
+}
+
+func testPod(name, node string, modify func(*v1.Pod)) v1.Pod {
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1.PodSpec{NodeName: node},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if modify != nil {
+		modify(&pod)
+	}
+	return pod
+}
+
+func TestDrainNode(t *testing.T) {
+	api := standin.NewKubernetes()
+	defer api.Close()
+	controller := true
+	api.Pods = []v1.Pod{
+		testPod("web", "node-1", nil),
+		testPod("other-node", "node-2", nil),
+		testPod("static", "node-1", func(p *v1.Pod) {
+			p.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "hash"}
+		}),
+		testPod("daemon", "node-1", func(p *v1.Pod) {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: &controller}}
+		}),
+		testPod("done", "node-1", func(p *v1.Pod) { p.Status.Phase = v1.PodSucceeded }),
+	}
+
+	if err := DrainNode(api.Client(), "node-1"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"default/web"}; !reflect.DeepEqual(api.Evictions, expected) {
+		t.Errorf("expected evictions %v, got %v", expected, api.Evictions)
+	}
+}
+
+func TestSetNodeCondition(t *testing.T) {
+	node := &v1.Node{}
+	then := metav1.NewTime(metav1.Now().Add(-time.Hour))
+	condition := v1.NodeCondition{Type: "MaintenanceScheduled", Status: v1.ConditionTrue, Reason: "Maintenance", LastTransitionTime: then}
+
+	if !SetNodeCondition(node, condition) || SetNodeCondition(node, condition) {
+		t.Error("expected condition to be set once")
+	}
+
+	condition.Message = "rescheduled"
+	condition.LastTransitionTime = metav1.Now()
+	if !SetNodeCondition(node, condition) {
+		t.Error("expected condition message to be updated")
+	}
+	if c := NodeCondition(node, condition.Type); c == nil || c.Message != "rescheduled" || !c.LastTransitionTime.Equal(&then) {
+		t.Errorf("expected updated message with the previous transition time, got %+v", c)
+	}
+
+	condition.Status = v1.ConditionFalse
+	if !SetNodeCondition(node, condition) || len(node.Status.Conditions) != 1 {
+		t.Errorf("expected condition to be replaced, got %v", node.Status.Conditions)
+	}
+	if c := NodeCondition(node, condition.Type); c.LastTransitionTime.Equal(&then) {
+		t.Error("expected new transition time")
+	}
+	if NodeCondition(node, "Other") != nil {
+		t.Error("expected no condition")
+	}
+}
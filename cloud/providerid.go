@@ -0,0 +1,41 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IDFromProviderID returns the instance ID from providerID, which the cloud
+// controllers set on Nodes in the format <provider>://<instance-id>.
+func IDFromProviderID(provider, providerID string) (string, error) {
+	if providerID == "" {
+		return "", errors.New("providerID cannot be empty string")
+	}
+
+	split := strings.Split(providerID, "/")
+	if len(split) != 3 || split[1] != "" || split[2] == "" {
+		return "", fmt.Errorf("unexpected providerID format: %s, format should be: %s://12345", providerID, provider)
+	}
+
+	// since split[0] is actually "<provider>:"
+	if strings.TrimSuffix(split[0], ":") != provider {
+		return "", fmt.Errorf("provider name from providerID should be %s: %s", provider, providerID)
+	}
+	return split[2], nil
+}
+
+// NumericIDFromProviderID returns the instance ID from providerID, see
+// IDFromProviderID, for providers with numeric instance IDs.
+func NumericIDFromProviderID(provider, providerID string) (int64, error) {
+	id, err := IDFromProviderID(provider, providerID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected providerID format: %s, instance ID should be a number", providerID)
+	}
+	return n, nil
+}
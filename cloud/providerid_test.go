@@ -0,0 +1,25 @@
+package cloud
+
+import "testing"
+
+func TestIDFromProviderID(t *testing.T) {
+	if id, err := IDFromProviderID("vultr", "vultr://576965"); err != nil || id != "576965" {
+		t.Errorf("expected id 576965, got %q (%v)", id, err)
+	}
+	for _, providerID := range []string{"", "576965", "vultr:///576965", "vultr://", "other://576965", "vultr://a/b"} {
+		if _, err := IDFromProviderID("vultr", providerID); err == nil {
+			t.Errorf("expected error for provider ID %q", providerID)
+		}
+	}
+}
+
+func TestNumericIDFromProviderID(t *testing.T) {
+	if id, err := NumericIDFromProviderID("linode", "linode://12345"); err != nil || id != 12345 {
+		t.Errorf("expected id 12345, got %d (%v)", id, err)
+	}
+	for _, providerID := range []string{"", "12345", "linode:///12345", "other://12345", "linode://master"} {
+		if _, err := NumericIDFromProviderID("linode", providerID); err == nil {
+			t.Errorf("expected error for provider ID %q", providerID)
+		}
+	}
+}
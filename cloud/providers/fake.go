@@ -0,0 +1,10 @@
+//go:build fake
+// +build fake
+
+package providers
+
+// The fake provider is only compiled into binaries built with -tags fake, see
+// hack/deploy/fake.yaml.
+import (
+	_ "pharmer.dev/cloud-controller-manager/cloud/providers/fake"
+)
@@ -0,0 +1,101 @@
+package fake
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+const (
+	ProviderName = "fake"
+)
+
+// Cloud is an offline cloud provider backed by an in-memory inventory. It
+// is meant for end-to-end tests of the controllers without cloud access.
+type Cloud struct {
+	store         *store
+	instances     cloudprovider.Instances
+	zones         cloudprovider.Zones
+	loadbalancers cloudprovider.LoadBalancer
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+}
+
+func init() {
+	cloudprovider.RegisterCloudProvider(
+		ProviderName,
+		func(config io.Reader) (cloudprovider.Interface, error) {
+			return newCloud(config)
+		})
+}
+
+func newCloud(config io.Reader) (*Cloud, error) {
+	inventory := &Inventory{}
+	if config != nil {
+		contents, err := ioutil.ReadAll(config)
+		if err != nil {
+			return nil, err
+		}
+
+		err = yaml.Unmarshal(contents, inventory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := newStore(inventory)
+	mutator := cloud.NewMutator(ProviderName, inventory.ClusterID)
+	collector := cloud.NewCollector(mutator)
+	lbs := newLoadbalancers(s, mutator)
+	collector.Register(lbs.(cloud.ResourceSource))
+	return &Cloud{
+		store:         s,
+		instances:     newInstances(s),
+		zones:         newZones(s),
+		loadbalancers: lbs,
+
+		mutator:   mutator,
+		collector: collector,
+	}, nil
+}
+
+func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+}
+
+func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
+	return c.loadbalancers, true
+}
+
+func (c *Cloud) Instances() (cloudprovider.Instances, bool) {
+	return c.instances, true
+}
+
+func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
+	return c.zones, true
+}
+
+func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
+	return nil, false
+}
+
+func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
+	return nil, false
+}
+
+func (c *Cloud) ProviderName() string {
+	return ProviderName
+}
+
+func (c *Cloud) ScrubDNS(nameservers, searches []string) (nsOut, srchOut []string) {
+	return nil, nil
+}
+
+func (c *Cloud) HasClusterID() bool {
+	return true
+}
@@ -0,0 +1,229 @@
+package fake
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+func newTestCloud(t *testing.T) *Cloud {
+	f, err := os.Open("testdata/inventory.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c, err := newCloud(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRegistered(t *testing.T) {
+	if !cloudprovider.IsCloudProvider(ProviderName) {
+		t.Fatalf("cloud provider %s is not registered", ProviderName)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c := newTestCloud(t)
+	instances, _ := c.Instances()
+	ctx := context.TODO()
+
+	id, err := instances.InstanceID(ctx, "kind-worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "1002" {
+		t.Errorf("expected instance ID 1002, got %s", id)
+	}
+
+	addrs, err := instances.NodeAddressesByProviderID(ctx, "fake://1002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 3 || addrs[0].Address != "kind-worker" || addrs[1].Address != "10.0.0.3" {
+		t.Errorf("unexpected addresses %v", addrs)
+	}
+
+	if _, err := instances.InstanceID(ctx, "missing"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+	exists, err := instances.InstanceExistsByProviderID(ctx, "fake://9999")
+	if err != nil || exists {
+		t.Errorf("expected missing instance, got %v, %v", exists, err)
+	}
+
+	zones, _ := c.Zones()
+	zone, err := zones.GetZoneByNodeName(ctx, "kind-worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone.FailureDomain != "fake-region-1b" {
+		t.Errorf("unexpected zone %v", zone)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	c := newTestCloud(t)
+	c.store.inventory.Faults.Errors = map[string]string{"InstanceType": "boom"}
+	c.store.inventory.Faults.NotFound = []string{"1001"}
+	instances, _ := c.Instances()
+	ctx := context.TODO()
+
+	if _, err := instances.InstanceType(ctx, "kind-worker"); err == nil || err.Error() != "boom" {
+		t.Errorf("expected injected error, got %v", err)
+	}
+	if _, err := instances.InstanceID(ctx, "kind-control-plane"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+
+	c.store.inventory.Faults.Errors = nil
+	c.store.inventory.Faults.ErrorRate = 1
+	if _, err := instances.InstanceType(ctx, "kind-worker"); err != ErrInjected {
+		t.Errorf("expected ErrInjected, got %v", err)
+	}
+}
+
+func TestLoadBalancers(t *testing.T) {
+	c := newTestCloud(t)
+	lbs, _ := c.LoadBalancer()
+	ctx := context.TODO()
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "abc-123"}}
+	nodes := []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "kind-worker"}}}
+
+	status, err := lbs.EnsureLoadBalancer(ctx, "kubernetes", svc, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Ingress[0].IP != "198.51.100.10" {
+		t.Errorf("unexpected status %v", status)
+	}
+
+	// ensuring again must not allocate another IP
+	status, err = lbs.EnsureLoadBalancer(ctx, "kubernetes", svc, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Ingress[0].IP != "198.51.100.10" || len(c.store.inventory.LoadBalancers) != 1 {
+		t.Errorf("EnsureLoadBalancer is not idempotent: %v", c.store.inventory.LoadBalancers)
+	}
+
+	if err := lbs.EnsureLoadBalancerDeleted(ctx, "kubernetes", svc); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists, err := lbs.GetLoadBalancer(ctx, "kubernetes", svc); err != nil || exists {
+		t.Errorf("expected load balancer to be deleted, got %v, %v", exists, err)
+	}
+	if err := lbs.EnsureLoadBalancerDeleted(ctx, "kubernetes", svc); err != nil {
+		t.Errorf("deleting a missing load balancer must succeed, got %v", err)
+	}
+}
+
+func TestInvalidLatency(t *testing.T) {
+	_, err := newCloud(strings.NewReader("faults:\n  latency: 10\n"))
+	if err == nil {
+		t.Error("expected an error for a latency without unit")
+	}
+}
+
+func TestClusterID(t *testing.T) {
+	c := newTestCloud(t)
+	if c.mutator.ClusterID() != "kind" {
+		t.Error("expected cluster ID to be configured")
+	}
+	ctx := context.TODO()
+
+	// an instance of another cluster with the same name must not be found
+	c.store.inventory.Instances = append([]Instance{{ID: "2001", Name: "kind-worker", Tags: []string{"KubernetesCluster:other"}}}, c.store.inventory.Instances...)
+	instances, _ := c.Instances()
+	if id, err := instances.InstanceID(ctx, "kind-worker"); err != nil || id != "1002" {
+		t.Errorf("expected instance id 1002, got %q (%v)", id, err)
+	}
+	if exists, err := instances.InstanceExistsByProviderID(ctx, "fake://2001"); err != nil || exists {
+		t.Errorf("expected instance 2001 of another cluster not to exist, got %v (%v)", exists, err)
+	}
+
+	// load balancers of other clusters are neither found nor deleted
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "abc-123"}}
+	name := cloudprovider.DefaultLoadBalancerName(svc)
+	c.store.inventory.LoadBalancers = []LoadBalancer{{Name: name, IP: "198.51.100.11", Cluster: "other"}}
+	lbs, _ := c.LoadBalancer()
+	status, err := lbs.EnsureLoadBalancer(ctx, "kubernetes", svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Ingress[0].IP != "198.51.100.10" || c.store.inventory.LoadBalancers[1].Cluster != "kind" {
+		t.Errorf("expected a new load balancer for cluster kind, got %v", c.store.inventory.LoadBalancers)
+	}
+	if err := lbs.EnsureLoadBalancerDeleted(ctx, "kubernetes", svc); err != nil {
+		t.Fatal(err)
+	}
+	if lbs := c.store.inventory.LoadBalancers; len(lbs) != 1 || lbs[0].Cluster != "other" {
+		t.Errorf("expected only the load balancer of cluster kind to be deleted, got %v", lbs)
+	}
+
+	// without a cluster ID nothing may be deleted
+	c, err = newCloud(strings.NewReader("ips:\n- 198.51.100.10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.mutator.ClusterID() != "" {
+		t.Error("expected no cluster ID")
+	}
+	// the controller manager refuses to start without a cluster ID
+	if !c.HasClusterID() {
+		t.Error("expected HasClusterID to be true without a cluster ID")
+	}
+	lbs, _ = c.LoadBalancer()
+	if _, err := lbs.EnsureLoadBalancer(ctx, "kubernetes", svc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := lbs.EnsureLoadBalancerDeleted(ctx, "kubernetes", svc); err != cloud.ErrNoClusterID {
+		t.Errorf("expected ErrNoClusterID, got %v", err)
+	}
+}
+
+func TestCollectOrphanedLoadBalancers(t *testing.T) {
+	c := newTestCloud(t)
+	ctx := context.TODO()
+
+	web := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "abc-123"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+	}
+	orphan := web
+	orphan.UID = "def-456"
+	c.store.inventory.LoadBalancers = []LoadBalancer{
+		{Name: cloudprovider.DefaultLoadBalancerName(&web), IP: "198.51.100.10", Cluster: "kind"},
+		{Name: cloudprovider.DefaultLoadBalancerName(&orphan), IP: "198.51.100.11", Cluster: "kind"},
+		{Name: cloudprovider.DefaultLoadBalancerName(&orphan), IP: "198.51.100.12", Cluster: "other"},
+	}
+
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c.loadbalancers.(cloud.ResourceSource))
+	if err := collector.Collect(ctx, []v1.Service{web}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ips []string
+	for _, lb := range c.store.inventory.LoadBalancers {
+		ips = append(ips, lb.IP)
+	}
+	if expected := []string{"198.51.100.10", "198.51.100.12"}; !reflect.DeepEqual(ips, expected) {
+		t.Errorf("expected load balancers %v to be kept, got %v", expected, ips)
+	}
+}
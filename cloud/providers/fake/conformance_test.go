@@ -0,0 +1,40 @@
+package fake
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, newTestCloud(t), conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "kind-control-plane",
+				ProviderID: "fake://1001",
+				Type:       "fake.medium",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "kind-control-plane"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.2"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.2"},
+				},
+				Zone: cloudprovider.Zone{Region: "fake-region-1", FailureDomain: "fake-region-1a"},
+			},
+			{
+				Name:       "kind-worker",
+				ProviderID: "fake://1002",
+				Type:       "fake.small",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "kind-worker"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.3"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.3"},
+				},
+				Zone: cloudprovider.Zone{Region: "fake-region-1", FailureDomain: "fake-region-1b"},
+			},
+		},
+		MissingName:       "kind-worker2",
+		MissingProviderID: "fake://9999",
+	})
+}
@@ -0,0 +1,154 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+type instances struct {
+	store *store
+}
+
+func newInstances(s *store) cloudprovider.Instances {
+	return &instances{s}
+}
+
+func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
+	if err := i.store.call("NodeAddresses"); err != nil {
+		return nil, err
+	}
+	instance, err := i.store.instanceByName(string(name))
+	if err != nil {
+		return nil, err
+	}
+	return nodeAddresses(instance), nil
+}
+
+func (i *instances) NodeAddressesByProviderID(_ context.Context, providerID string) ([]v1.NodeAddress, error) {
+	if err := i.store.call("NodeAddressesByProviderID"); err != nil {
+		return nil, err
+	}
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := i.store.instanceByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return nodeAddresses(instance), nil
+}
+
+func nodeAddresses(instance Instance) []v1.NodeAddress {
+	addresses := []v1.NodeAddress{{Type: v1.NodeHostName, Address: instance.Name}}
+	return cloud.NodeAddresses(append(addresses, instance.Addresses...))
+}
+
+func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
+	return i.InstanceID(ctx, nodeName)
+}
+
+func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
+	if err := i.store.call("InstanceID"); err != nil {
+		return "", err
+	}
+	instance, err := i.store.instanceByName(string(nodeName))
+	if err != nil {
+		return "", err
+	}
+	return instance.ID, nil
+}
+
+func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
+	if err := i.store.call("InstanceType"); err != nil {
+		return "", err
+	}
+	instance, err := i.store.instanceByName(string(nodeName))
+	if err != nil {
+		return "", err
+	}
+	return instance.Type, nil
+}
+
+func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID string) (string, error) {
+	if err := i.store.call("InstanceTypeByProviderID"); err != nil {
+		return "", err
+	}
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return "", err
+	}
+	instance, err := i.store.instanceByID(id)
+	if err != nil {
+		return "", err
+	}
+	return instance.Type, nil
+}
+
+func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
+	return cloud.ErrNotImplemented
+}
+
+func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
+	return types.NodeName(hostname), nil
+}
+
+func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID string) (bool, error) {
+	if err := i.store.call("InstanceExistsByProviderID"); err != nil {
+		return false, err
+	}
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+	_, err = i.store.instanceByID(id)
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (i *instances) InstanceShutdownByProviderID(_ context.Context, providerID string) (bool, error) {
+	if err := i.store.call("InstanceShutdownByProviderID"); err != nil {
+		return false, err
+	}
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+	instance, err := i.store.instanceByID(id)
+	if err != nil {
+		return false, err
+	}
+	return instance.Shutdown, nil
+}
+
+// instanceIDFromProviderID returns an instance's ID from providerID.
+//
+// The providerID spec should be retrievable from the Kubernetes
+// node object. The expected format is: fake://instance-id
+
+func instanceIDFromProviderID(providerID string) (string, error) {
+	if providerID == "" {
+		return "", errors.New("providerID cannot be empty string")
+	}
+
+	split := strings.Split(providerID, "/")
+	if len(split) != 3 {
+		return "", fmt.Errorf("unexpected providerID format: %s, format should be: fake://12345", providerID)
+	}
+
+	// since split[0] is actually "fake:"
+	if strings.TrimSuffix(split[0], ":") != ProviderName {
+		return "", fmt.Errorf("provider name from providerID should be fake: %s", providerID)
+	}
+
+	return split[2], nil
+}
@@ -0,0 +1,163 @@
+package fake
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// Inventory is the cloud config of the fake provider. It describes the
+// resources of an imaginary cloud account and the faults to inject into
+// calls against it.
+type Inventory struct {
+	// ClusterID limits the provider to instances tagged with the cluster tag,
+	// see cloud.ClusterTag, and to the load balancers created for the cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	// Zone is returned by GetZone
+	Zone          Zone           `json:"zone" yaml:"zone"`
+	Instances     []Instance     `json:"instances" yaml:"instances"`
+	IPs           []string       `json:"ips" yaml:"ips"`
+	LoadBalancers []LoadBalancer `json:"loadBalancers" yaml:"loadBalancers"`
+	Faults        Faults         `json:"faults" yaml:"faults"`
+}
+
+type Zone struct {
+	Region        string `json:"region" yaml:"region"`
+	FailureDomain string `json:"failureDomain" yaml:"failureDomain"`
+}
+
+type Instance struct {
+	ID        string           `json:"id" yaml:"id"`
+	Name      string           `json:"name" yaml:"name"`
+	Type      string           `json:"type" yaml:"type"`
+	Zone      Zone             `json:"zone" yaml:"zone"`
+	Addresses []v1.NodeAddress `json:"addresses" yaml:"addresses"`
+	Shutdown  bool             `json:"shutdown" yaml:"shutdown"`
+	Tags      []string         `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+type LoadBalancer struct {
+	Name  string   `json:"name" yaml:"name"`
+	IP    string   `json:"ip" yaml:"ip"`
+	Nodes []string `json:"nodes" yaml:"nodes"`
+	// Cluster is the ID of the cluster the load balancer was created for
+	Cluster string `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+}
+
+// Faults are injected into every call of the fake provider.
+type Faults struct {
+	// Latency is added to every call
+	Latency Duration `json:"latency" yaml:"latency"`
+	// ErrorRate is the probability, between 0 and 1, of a call failing
+	ErrorRate float64 `json:"errorRate" yaml:"errorRate"`
+	// Errors maps a method name, e.g. NodeAddresses, to the error it returns
+	Errors map[string]string `json:"errors" yaml:"errors"`
+	// NotFound lists instance names or IDs that are reported as not found,
+	// even if they are in the inventory
+	NotFound []string `json:"notFound" yaml:"notFound"`
+}
+
+// Duration is a time.Duration that unmarshals from strings like "250ms".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" || s == `""` {
+		d.Duration = 0
+		return nil
+	}
+	if len(s) < 2 || s[0] != '"' {
+		return errors.New("duration must be a string, e.g. \"250ms\"")
+	}
+	v, err := time.ParseDuration(s[1 : len(s)-1])
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// ErrInjected is returned by calls that fail because of the ErrorRate fault.
+var ErrInjected = errors.New("injected fault")
+
+// store holds the inventory in memory and applies faults to every call.
+type store struct {
+	mu        sync.Mutex
+	inventory *Inventory
+	random    *rand.Rand
+}
+
+func newStore(inventory *Inventory) *store {
+	return &store{inventory: inventory, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// call applies the faults configured for method. It must be called without
+// holding the lock.
+func (s *store) call(method string) error {
+	faults := s.inventory.Faults
+	if faults.Latency.Duration > 0 {
+		time.Sleep(faults.Latency.Duration)
+	}
+	if msg, found := faults.Errors[method]; found {
+		return errors.New(msg)
+	}
+	if faults.ErrorRate > 0 {
+		s.mu.Lock()
+		fail := s.random.Float64() < faults.ErrorRate
+		s.mu.Unlock()
+		if fail {
+			return ErrInjected
+		}
+	}
+	return nil
+}
+
+func (s *store) notFound(key string) bool {
+	for _, k := range s.inventory.Faults.NotFound {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceByName returns the instance called name. If a cluster ID is
+// configured, only instances tagged with its cluster tag are considered.
+func (s *store) instanceByName(name string) (Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID := s.inventory.ClusterID
+	for _, instance := range s.inventory.Instances {
+		if clusterID != "" && !cloud.HasClusterTag(instance.Tags, clusterID) {
+			continue
+		}
+		if instance.Name == name && !s.notFound(instance.Name) && !s.notFound(instance.ID) {
+			return instance, nil
+		}
+	}
+	return Instance{}, cloudprovider.InstanceNotFound
+}
+
+// instanceByID returns the instance with id. Like instanceByName, it only
+// considers instances of the configured cluster.
+func (s *store) instanceByID(id string) (Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clusterID := s.inventory.ClusterID
+	for _, instance := range s.inventory.Instances {
+		if clusterID != "" && !cloud.HasClusterTag(instance.Tags, clusterID) {
+			continue
+		}
+		if instance.ID == id && !s.notFound(instance.Name) && !s.notFound(instance.ID) {
+			return instance, nil
+		}
+	}
+	return Instance{}, cloudprovider.InstanceNotFound
+}
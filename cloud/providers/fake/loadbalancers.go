@@ -0,0 +1,200 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+type loadbalancers struct {
+	store   *store
+	mutator *cloud.Mutator
+}
+
+// newLoadbalancers returns a cloudprovider.LoadBalancer whose concrete type is a *loadbalancer.
+func newLoadbalancers(s *store, mutator *cloud.Mutator) cloudprovider.LoadBalancer {
+	return &loadbalancers{store: s, mutator: mutator}
+}
+
+// GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
+// *v1.Service parameter as read-only and not modify it.
+func (l *loadbalancers) GetLoadBalancerName(ctx context.Context, clusterName string, service *v1.Service) string {
+	return cloudprovider.DefaultLoadBalancerName(service)
+}
+
+// GetLoadBalancer returns the *v1.LoadBalancerStatus of service.
+//
+// GetLoadBalancer will not modify service.
+func (l *loadbalancers) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (*v1.LoadBalancerStatus, bool, error) {
+	if err := l.store.call("GetLoadBalancer"); err != nil {
+		return nil, false, err
+	}
+	lb, found := l.find(l.GetLoadBalancerName(ctx, clusterName, service))
+	if !found {
+		return nil, false, nil
+	}
+	return lbStatus(lb), true, nil
+}
+
+// EnsureLoadBalancer ensures that the cluster is running a load balancer for
+// service.
+//
+// EnsureLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
+	if err := l.store.call("EnsureLoadBalancer"); err != nil {
+		return nil, err
+	}
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	if lb, found := l.find(name); found {
+		if err := l.setNodes(service, name, nodes); err != nil {
+			return nil, err
+		}
+		return lbStatus(lb), nil
+	}
+
+	ip, err := l.freeIP()
+	if err != nil {
+		return nil, err
+	}
+	lb := LoadBalancer{Name: name, IP: ip, Nodes: nodeNames(nodes), Cluster: l.mutator.ClusterID()}
+	err = l.mutator.Do(service, "create load balancer", cloud.Params{"name": name, "ip": ip, "nodes": strings.Join(lb.Nodes, ","), "cluster": lb.Cluster}, func() error {
+		l.store.mu.Lock()
+		defer l.store.mu.Unlock()
+		l.store.inventory.LoadBalancers = append(l.store.inventory.LoadBalancers, lb)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lbStatus(lb), nil
+}
+
+// UpdateLoadBalancer updates the load balancer for service to balance across
+// the droplets in nodes.
+//
+// UpdateLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
+	if err := l.store.call("UpdateLoadBalancer"); err != nil {
+		return err
+	}
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	if _, found := l.find(name); !found {
+		return fmt.Errorf("load balancer %s not found", name)
+	}
+	return l.setNodes(service, name, nodes)
+}
+
+// EnsureLoadBalancerDeleted deletes the specified loadbalancer if it exists.
+// nil is returned if the load balancer for service does not exist or is
+// successfully deleted.
+//
+// EnsureLoadBalancerDeleted will not modify service.
+func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) error {
+	if err := l.store.call("EnsureLoadBalancerDeleted"); err != nil {
+		return err
+	}
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	if _, found := l.find(name); !found {
+		return nil
+	}
+	return l.mutator.Destroy(service, "delete load balancer", cloud.Params{"name": name, "cluster": l.mutator.ClusterID()}, func() error {
+		l.remove(name)
+		return nil
+	})
+}
+
+// ListOwned lists the load balancers created for cluster clusterID, so that
+// the load balancers of deleted Services are collected.
+func (l *loadbalancers) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	if err := l.store.call("ListOwned"); err != nil {
+		return nil, err
+	}
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	var owned []cloud.OwnedResource
+	for _, lb := range l.store.inventory.LoadBalancers {
+		if lb.Cluster == clusterID {
+			owned = append(owned, cloud.OwnedResource{Kind: "load balancer", ID: lb.Name, LoadBalancerName: lb.Name})
+		}
+	}
+	return owned, nil
+}
+
+func (l *loadbalancers) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	if err := l.store.call("DeleteOwned"); err != nil {
+		return err
+	}
+	l.remove(resource.ID)
+	return nil
+}
+
+// remove deletes the load balancer called name of the cluster.
+func (l *loadbalancers) remove(name string) {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	lbs := l.store.inventory.LoadBalancers[:0]
+	for _, lb := range l.store.inventory.LoadBalancers {
+		if lb.Name != name || lb.Cluster != l.mutator.ClusterID() {
+			lbs = append(lbs, lb)
+		}
+	}
+	l.store.inventory.LoadBalancers = lbs
+}
+
+// find returns the load balancer called name of the cluster.
+func (l *loadbalancers) find(name string) (LoadBalancer, bool) {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	for _, lb := range l.store.inventory.LoadBalancers {
+		if lb.Name == name && lb.Cluster == l.mutator.ClusterID() {
+			return lb, true
+		}
+	}
+	return LoadBalancer{}, false
+}
+
+func (l *loadbalancers) setNodes(service *v1.Service, name string, nodes []*v1.Node) error {
+	names := nodeNames(nodes)
+	return l.mutator.Do(service, "update load balancer", cloud.Params{"name": name, "nodes": strings.Join(names, ",")}, func() error {
+		l.store.mu.Lock()
+		defer l.store.mu.Unlock()
+		for i := range l.store.inventory.LoadBalancers {
+			if lb := l.store.inventory.LoadBalancers[i]; lb.Name == name && lb.Cluster == l.mutator.ClusterID() {
+				l.store.inventory.LoadBalancers[i].Nodes = names
+			}
+		}
+		return nil
+	})
+}
+
+// freeIP returns the first IP of the inventory that is not used by a load balancer.
+func (l *loadbalancers) freeIP() (string, error) {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	used := map[string]bool{}
+	for _, lb := range l.store.inventory.LoadBalancers {
+		used[lb.IP] = true
+	}
+	for _, ip := range l.store.inventory.IPs {
+		if !used[ip] {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("no free IP left in inventory")
+}
+
+func lbStatus(lb LoadBalancer) *v1.LoadBalancerStatus {
+	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: lb.IP}}}
+}
+
+func nodeNames(nodes []*v1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
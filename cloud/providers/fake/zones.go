@@ -0,0 +1,53 @@
+package fake
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+type zones struct {
+	store *store
+}
+
+func newZones(s *store) cloudprovider.Zones {
+	return zones{s}
+}
+
+func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
+	if err := z.store.call("GetZone"); err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return toZone(z.store.inventory.Zone), nil
+}
+
+func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudprovider.Zone, error) {
+	if err := z.store.call("GetZoneByProviderID"); err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	instance, err := z.store.instanceByID(id)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return toZone(instance.Zone), nil
+}
+
+func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
+	if err := z.store.call("GetZoneByNodeName"); err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	instance, err := z.store.instanceByName(string(nodeName))
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return toZone(instance.Zone), nil
+}
+
+func toZone(zone Zone) cloudprovider.Zone {
+	return cloudprovider.Zone{Region: zone.Region, FailureDomain: zone.FailureDomain}
+}
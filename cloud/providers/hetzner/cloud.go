@@ -0,0 +1,143 @@
+package hetzner
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+const (
+	ProviderName = "hcloud"
+
+	// clusterLabel is the key of the label form of the cluster tag, see
+	// cloud.ClusterTag, as Hetzner label keys can not contain colons. The
+	// servers of a cluster are labelled KubernetesCluster=<cluster ID>.
+	clusterLabel = "KubernetesCluster"
+
+	defaultLoadBalancerType = "lb11"
+)
+
+type config struct {
+	Token string `json:"token" yaml:"token"`
+	// Endpoint overrides the base URL of the Hetzner Cloud API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// MetadataURL overrides the base URL of the instance metadata service
+	MetadataURL string `json:"metadataURL,omitempty" yaml:"metadataURL,omitempty"`
+	// ClusterID limits the controller to servers labelled with the cluster
+	// label, see clusterLabel, and to the load balancers it created for the
+	// cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	// Network is the ID of the private network of the cluster. Only the
+	// private addresses of servers in this network are reported, and load
+	// balancers are attached to it and reach their targets by private IP.
+	Network int64 `json:"network,omitempty" yaml:"network,omitempty"`
+	// Location is the location of the load balancers, e.g. fsn1. It defaults
+	// to the location of the first server behind a load balancer.
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	// LoadBalancerType is the type of the load balancers, lb11 by default
+	LoadBalancerType string `json:"loadBalancerType,omitempty" yaml:"loadBalancerType,omitempty"`
+}
+
+type Cloud struct {
+	client        *hcloud.Client
+	instances     cloudprovider.Instances
+	zones         cloudprovider.Zones
+	loadbalancers cloudprovider.LoadBalancer
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+}
+
+func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, hcloud.DefaultEndpoint)
+	cloudprovider.RegisterCloudProvider(
+		ProviderName,
+		func(config io.Reader) (cloudprovider.Interface, error) {
+			return newCloud(config)
+		})
+}
+
+func newCloud(reader io.Reader) (*Cloud, error) {
+	conf := &config{}
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(contents, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.MetadataURL == "" {
+		conf.MetadataURL = metadataURL
+	}
+	if conf.LoadBalancerType == "" {
+		conf.LoadBalancerType = defaultLoadBalancerType
+	}
+
+	client := hcloud.NewClient(conf.Token, conf.Endpoint, cloud.HTTPClient())
+	mutator := cloud.NewMutator(ProviderName, conf.ClusterID)
+	collector := cloud.NewCollector(mutator)
+	lbs := newLoadbalancers(client, mutator, conf)
+	collector.Register(lbs.(cloud.ResourceSource))
+	return &Cloud{
+		client:        client,
+		instances:     newInstances(client, conf.Network, mutator),
+		zones:         newZones(client, conf.MetadataURL, conf.ClusterID),
+		loadbalancers: lbs,
+
+		mutator:   mutator,
+		collector: collector,
+	}, nil
+}
+
+func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+}
+
+func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
+	return c.loadbalancers, true
+}
+
+func (c *Cloud) Instances() (cloudprovider.Instances, bool) {
+	return c.instances, true
+}
+
+func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
+	return c.zones, true
+}
+
+func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
+	return nil, false
+}
+
+func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
+	return nil, false
+}
+
+func (c *Cloud) ProviderName() string {
+	return ProviderName
+}
+
+func (c *Cloud) ScrubDNS(nameservers, searches []string) (nsOut, srchOut []string) {
+	return nil, nil
+}
+
+func (c *Cloud) HasClusterID() bool {
+	return true
+}
+
+// clusterSelector returns the label selector of the resources of cluster
+// clusterID, or an empty selector if clusterID is empty.
+func clusterSelector(clusterID string) string {
+	if clusterID == "" {
+		return ""
+	}
+	return clusterLabel + "=" + clusterID
+}
@@ -0,0 +1,43 @@
+package hetzner
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "master",
+				ProviderID: "hcloud://4711",
+				Type:       "cx21",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "master"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.2"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "fsn1", FailureDomain: "fsn1-dc14"},
+			},
+			{
+				Name:       "node-1",
+				ProviderID: "hcloud://4712",
+				Type:       "cpx31",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "node-1"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.3"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.11"},
+				},
+				Zone: cloudprovider.Zone{Region: "nbg1", FailureDomain: "nbg1-dc3"},
+			},
+		},
+		MissingName:       "node-2",
+		MissingProviderID: "hcloud://4713",
+	})
+}
@@ -0,0 +1,223 @@
+// Package hcloud is a minimal client of the Hetzner Cloud API, see
+// https://docs.hetzner.cloud. It covers the servers and load balancers used by
+// the hetzner cloud provider and is shared with the stand-in of the API.
+package hcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the base URL of the Hetzner Cloud API.
+const DefaultEndpoint = "https://api.hetzner.cloud/v1/"
+
+// perPage is the page size of list calls, the maximum of the API
+const perPage = 50
+
+var (
+	// ActionPollInterval is the time between two polls of a running action.
+	ActionPollInterval = time.Second
+	// ActionTimeout is the time WaitAction waits for an action to finish.
+	ActionTimeout = 5 * time.Minute
+)
+
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client authenticating with token. An empty endpoint
+// selects DefaultEndpoint and a nil httpClient http.DefaultClient.
+func NewClient(token, endpoint string, httpClient *http.Client) *Client {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{endpoint: strings.TrimSuffix(endpoint, "/") + "/", token: token, httpClient: httpClient}
+}
+
+// Error is the error returned by the API for a failed call.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hcloud: %s (%s)", e.Message, e.Code)
+}
+
+// IsNotFound returns true if err is the error of a missing resource.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && (e.StatusCode == http.StatusNotFound || e.Code == "not_found")
+}
+
+// ListOpts filter the resources of list calls.
+type ListOpts struct {
+	Name string
+	// LabelSelector selects resources by label, e.g. role=master
+	LabelSelector string
+}
+
+func (o ListOpts) values() url.Values {
+	values := url.Values{}
+	if o.Name != "" {
+		values.Set("name", o.Name)
+	}
+	if o.LabelSelector != "" {
+		values.Set("label_selector", o.LabelSelector)
+	}
+	return values
+}
+
+// Meta is the metadata of a list response.
+type Meta struct {
+	Pagination Pagination `json:"pagination"`
+}
+
+type Pagination struct {
+	Page     int  `json:"page"`
+	PerPage  int  `json:"per_page"`
+	NextPage *int `json:"next_page"`
+}
+
+// Action is an asynchronous operation started by a call.
+type Action struct {
+	ID      int64        `json:"id"`
+	Command string       `json:"command"`
+	Status  string       `json:"status"`
+	Error   *ActionError `json:"error"`
+}
+
+type ActionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	ActionStatusRunning = "running"
+	ActionStatusSuccess = "success"
+	ActionStatusError   = "error"
+)
+
+// do sends a request to path, relative to the endpoint, with in encoded as
+// JSON body unless it is nil, and decodes the response into out unless it is
+// nil.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Error Error `json:"error"`
+		}
+		if err := json.Unmarshal(data, &e); err != nil || e.Error.Code == "" {
+			return &Error{StatusCode: resp.StatusCode, Code: strconv.Itoa(resp.StatusCode), Message: resp.Status}
+		}
+		e.Error.StatusCode = resp.StatusCode
+		return &e.Error
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// list calls fn with the body of every page of the resources at path.
+func (c *Client) list(path string, opts ListOpts, fn func(body []byte) error) error {
+	values := opts.values()
+	values.Set("per_page", strconv.Itoa(perPage))
+	for page := 1; ; {
+		values.Set("page", strconv.Itoa(page))
+		var body json.RawMessage
+		if err := c.do(http.MethodGet, path+"?"+values.Encode(), nil, &body); err != nil {
+			return err
+		}
+		if err := fn(body); err != nil {
+			return err
+		}
+		var resp struct {
+			Meta Meta `json:"meta"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		if resp.Meta.Pagination.NextPage == nil {
+			return nil
+		}
+		page = *resp.Meta.Pagination.NextPage
+	}
+}
+
+// WaitAction waits until action is no longer running and returns its error,
+// if it failed. It gives up after ActionTimeout, the action may still finish.
+func (c *Client) WaitAction(action *Action) error {
+	deadline := time.Now().Add(ActionTimeout)
+	for action.Status == ActionStatusRunning {
+		if time.Now().After(deadline) {
+			return &Error{Code: "action_timeout", Message: fmt.Sprintf("action %s did not finish within %v", action.Command, ActionTimeout)}
+		}
+		time.Sleep(ActionPollInterval)
+		var resp struct {
+			Action Action `json:"action"`
+		}
+		if err := c.do(http.MethodGet, fmt.Sprintf("actions/%d", action.ID), nil, &resp); err != nil {
+			return err
+		}
+		action = &resp.Action
+	}
+	if action.Status == ActionStatusError {
+		e := &Error{Code: "action_failed", Message: fmt.Sprintf("action %s failed", action.Command)}
+		if action.Error != nil {
+			e.Code, e.Message = action.Error.Code, action.Error.Message
+		}
+		return e
+	}
+	return nil
+}
+
+// doAction runs the action command of the resource at path and waits for it.
+func (c *Client) doAction(path, command string, in interface{}) error {
+	var resp struct {
+		Action Action `json:"action"`
+	}
+	if err := c.do(http.MethodPost, path+"/actions/"+command, in, &resp); err != nil {
+		return err
+	}
+	return c.WaitAction(&resp.Action)
+}
@@ -0,0 +1,28 @@
+package hcloud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitActionTimeout(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		ActionPollInterval, ActionTimeout = interval, timeout
+	}(ActionPollInterval, ActionTimeout)
+	ActionPollInterval, ActionTimeout = time.Millisecond, 20*time.Millisecond
+
+	// the action never finishes
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]Action{"action": {ID: 1, Command: "add_target", Status: ActionStatusRunning}})
+	}))
+	defer server.Close()
+	c := NewClient("secret", server.URL, nil)
+
+	err := c.WaitAction(&Action{ID: 1, Command: "add_target", Status: ActionStatusRunning})
+	if e, ok := err.(*Error); !ok || e.Code != "action_timeout" {
+		t.Errorf("expected action timeout, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package hcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	LoadBalancerServiceProtocolTCP = "tcp"
+	LoadBalancerTargetTypeServer   = "server"
+)
+
+type LoadBalancer struct {
+	ID               int64                    `json:"id"`
+	Name             string                   `json:"name"`
+	PublicNet        LoadBalancerPublicNet    `json:"public_net"`
+	PrivateNet       []LoadBalancerPrivateNet `json:"private_net"`
+	Location         Location                 `json:"location"`
+	LoadBalancerType LoadBalancerType         `json:"load_balancer_type"`
+	Services         []LoadBalancerService    `json:"services"`
+	Targets          []LoadBalancerTarget     `json:"targets"`
+	Labels           map[string]string        `json:"labels"`
+}
+
+type LoadBalancerPublicNet struct {
+	Enabled bool                   `json:"enabled"`
+	IPv4    LoadBalancerPublicIPv4 `json:"ipv4"`
+	IPv6    LoadBalancerPublicIPv6 `json:"ipv6"`
+}
+
+type LoadBalancerPublicIPv4 struct {
+	IP string `json:"ip"`
+}
+
+type LoadBalancerPublicIPv6 struct {
+	IP string `json:"ip"`
+}
+
+type LoadBalancerPrivateNet struct {
+	Network int64  `json:"network"`
+	IP      string `json:"ip"`
+}
+
+type LoadBalancerType struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// LoadBalancerService forwards a listen port of a load balancer to a port of
+// its targets.
+type LoadBalancerService struct {
+	Protocol        string `json:"protocol"`
+	ListenPort      int    `json:"listen_port"`
+	DestinationPort int    `json:"destination_port"`
+	Proxyprotocol   bool   `json:"proxyprotocol"`
+}
+
+// LoadBalancerTarget is a target of a load balancer. Only server targets are
+// supported.
+type LoadBalancerTarget struct {
+	Type         string                    `json:"type"`
+	Server       *LoadBalancerTargetServer `json:"server,omitempty"`
+	UsePrivateIP bool                      `json:"use_private_ip"`
+}
+
+type LoadBalancerTargetServer struct {
+	ID int64 `json:"id"`
+}
+
+type LoadBalancerCreateRequest struct {
+	Name             string                `json:"name"`
+	LoadBalancerType string                `json:"load_balancer_type"`
+	Location         string                `json:"location,omitempty"`
+	Network          *int64                `json:"network,omitempty"`
+	Labels           map[string]string     `json:"labels,omitempty"`
+	Services         []LoadBalancerService `json:"services,omitempty"`
+	Targets          []LoadBalancerTarget  `json:"targets,omitempty"`
+}
+
+// ListLoadBalancers returns the load balancers of the project matching opts.
+func (c *Client) ListLoadBalancers(opts ListOpts) ([]LoadBalancer, error) {
+	var lbs []LoadBalancer
+	err := c.list("load_balancers", opts, func(body []byte) error {
+		var page struct {
+			LoadBalancers []LoadBalancer `json:"load_balancers"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		lbs = append(lbs, page.LoadBalancers...)
+		return nil
+	})
+	return lbs, err
+}
+
+// CreateLoadBalancer creates a load balancer and waits until it is running.
+func (c *Client) CreateLoadBalancer(req LoadBalancerCreateRequest) (*LoadBalancer, error) {
+	var resp struct {
+		LoadBalancer LoadBalancer `json:"load_balancer"`
+		Action       Action       `json:"action"`
+	}
+	if err := c.do(http.MethodPost, "load_balancers", req, &resp); err != nil {
+		return nil, err
+	}
+	if err := c.WaitAction(&resp.Action); err != nil {
+		return nil, err
+	}
+	return &resp.LoadBalancer, nil
+}
+
+// SetLoadBalancerLabels replaces the labels of the load balancer.
+func (c *Client) SetLoadBalancerLabels(id int64, labels map[string]string) error {
+	return c.do(http.MethodPut, fmt.Sprintf("load_balancers/%d", id), map[string]map[string]string{"labels": labels}, nil)
+}
+
+func (c *Client) DeleteLoadBalancer(id int64) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("load_balancers/%d", id), nil, nil)
+}
+
+func (c *Client) AddTarget(id int64, target LoadBalancerTarget) error {
+	return c.doAction(fmt.Sprintf("load_balancers/%d", id), "add_target", target)
+}
+
+func (c *Client) RemoveTarget(id int64, target LoadBalancerTarget) error {
+	return c.doAction(fmt.Sprintf("load_balancers/%d", id), "remove_target", target)
+}
+
+func (c *Client) AddService(id int64, service LoadBalancerService) error {
+	return c.doAction(fmt.Sprintf("load_balancers/%d", id), "add_service", service)
+}
+
+// UpdateService updates the service of the load balancer with the listen
+// port of service.
+func (c *Client) UpdateService(id int64, service LoadBalancerService) error {
+	return c.doAction(fmt.Sprintf("load_balancers/%d", id), "update_service", service)
+}
+
+func (c *Client) DeleteService(id int64, listenPort int) error {
+	return c.doAction(fmt.Sprintf("load_balancers/%d", id), "delete_service", map[string]int{"listen_port": listenPort})
+}
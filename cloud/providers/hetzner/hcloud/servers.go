@@ -0,0 +1,89 @@
+package hcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	ServerStatusRunning = "running"
+	ServerStatusOff     = "off"
+)
+
+type Server struct {
+	ID         int64              `json:"id"`
+	Name       string             `json:"name"`
+	Status     string             `json:"status"`
+	PublicNet  ServerPublicNet    `json:"public_net"`
+	PrivateNet []ServerPrivateNet `json:"private_net"`
+	ServerType ServerType         `json:"server_type"`
+	Datacenter Datacenter         `json:"datacenter"`
+	Labels     map[string]string  `json:"labels"`
+}
+
+type ServerPublicNet struct {
+	IPv4 ServerPublicIPv4 `json:"ipv4"`
+	IPv6 ServerPublicIPv6 `json:"ipv6"`
+}
+
+type ServerPublicIPv4 struct {
+	IP string `json:"ip"`
+}
+
+// ServerPublicIPv6 is the IPv6 network of a server, e.g. 2001:db8::/64.
+type ServerPublicIPv6 struct {
+	IP string `json:"ip"`
+}
+
+// ServerPrivateNet is the attachment of a server to a private network.
+type ServerPrivateNet struct {
+	Network  int64    `json:"network"`
+	IP       string   `json:"ip"`
+	AliasIPs []string `json:"alias_ips"`
+}
+
+type ServerType struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type Datacenter struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Location Location `json:"location"`
+}
+
+type Location struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	NetworkZone string `json:"network_zone"`
+}
+
+// ListServers returns the servers of the project matching opts.
+func (c *Client) ListServers(opts ListOpts) ([]Server, error) {
+	var servers []Server
+	err := c.list("servers", opts, func(body []byte) error {
+		var page struct {
+			Servers []Server `json:"servers"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return err
+		}
+		servers = append(servers, page.Servers...)
+		return nil
+	})
+	return servers, err
+}
+
+// GetServer returns the server with id. The error of a missing server
+// satisfies IsNotFound.
+func (c *Client) GetServer(id int64) (*Server, error) {
+	var resp struct {
+		Server Server `json:"server"`
+	}
+	if err := c.do(http.MethodGet, fmt.Sprintf("servers/%d", id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Server, nil
+}
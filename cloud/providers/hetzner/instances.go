@@ -0,0 +1,153 @@
+package hetzner
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+type instances struct {
+	client  *hcloud.Client
+	network int64
+	mutator *cloud.Mutator
+}
+
+func newInstances(client *hcloud.Client, network int64, mutator *cloud.Mutator) cloudprovider.Instances {
+	return &instances{client: client, network: network, mutator: mutator}
+}
+
+func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
+	server, err := serverByName(i.client, i.mutator.ClusterID(), name)
+	if err != nil {
+		return nil, err
+	}
+	return i.nodeAddresses(server), nil
+}
+
+func (i *instances) NodeAddressesByProviderID(_ context.Context, providerID string) ([]v1.NodeAddress, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return nil, err
+	}
+	server, err := serverByID(i.client, id)
+	if err != nil {
+		return nil, err
+	}
+	return i.nodeAddresses(server), nil
+}
+
+// nodeAddresses returns the private addresses of server in the configured
+// network, or in all networks if none is configured, and its public
+// addresses. The public IPv6 address is the first one of its /64 network.
+func (i *instances) nodeAddresses(server *hcloud.Server) []v1.NodeAddress {
+	addresses := []v1.NodeAddress{{Type: v1.NodeHostName, Address: server.Name}}
+	for _, private := range server.PrivateNet {
+		if i.network == 0 || private.Network == i.network {
+			addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: private.IP})
+		}
+	}
+	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: server.PublicNet.IPv4.IP})
+	if ip, network, err := net.ParseCIDR(server.PublicNet.IPv6.IP); err == nil && ip.To4() == nil {
+		ip = network.IP
+		ip[len(ip)-1] |= 1
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: ip.String()})
+	}
+	return cloud.NodeAddresses(addresses)
+}
+
+func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
+	return i.InstanceID(ctx, nodeName)
+}
+
+func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(server.ID, 10), nil
+}
+
+func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
+	if err != nil {
+		return "", err
+	}
+	return server.ServerType.Name, nil
+}
+
+func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID string) (string, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return "", err
+	}
+	server, err := serverByID(i.client, id)
+	if err != nil {
+		return "", err
+	}
+	return server.ServerType.Name, nil
+}
+
+// AddSSHKeyToAllInstances is not supported, Hetzner only installs SSH keys
+// when a server is created.
+func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
+	return cloud.ErrNotImplemented
+}
+
+func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
+	return types.NodeName(hostname), nil
+}
+
+func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID string) (bool, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return false, err
+	}
+	_, err = serverByID(i.client, id)
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// InstanceShutdownByProviderID returns true if the server is powered off.
+func (i *instances) InstanceShutdownByProviderID(_ context.Context, providerID string) (bool, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return false, err
+	}
+	server, err := serverByID(i.client, id)
+	if err != nil {
+		return false, err
+	}
+	return server.Status == hcloud.ServerStatusOff, nil
+}
+
+func serverByID(client *hcloud.Client, id int64) (*hcloud.Server, error) {
+	server, err := client.GetServer(id)
+	if hcloud.IsNotFound(err) {
+		return nil, cloudprovider.InstanceNotFound
+	}
+	return server, err
+}
+
+// serverByName returns the server called nodeName. If clusterID is not
+// empty, only servers labelled with its cluster label are considered, so that
+// servers of other clusters with the same name do not collide.
+func serverByName(client *hcloud.Client, clusterID string, nodeName types.NodeName) (*hcloud.Server, error) {
+	servers, err := client.ListServers(hcloud.ListOpts{Name: string(nodeName), LabelSelector: clusterSelector(clusterID)})
+	if err != nil {
+		return nil, err
+	}
+	for i := range servers {
+		if servers[i].Name == string(nodeName) {
+			return &servers[i], nil
+		}
+	}
+	return nil, cloudprovider.InstanceNotFound
+}
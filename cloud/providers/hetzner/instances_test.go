@@ -0,0 +1,119 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func testServer(id int64, name, ipv4 string, labels map[string]string) hcloud.Server {
+	server := hcloud.Server{
+		ID:         id,
+		Name:       name,
+		Status:     hcloud.ServerStatusRunning,
+		ServerType: hcloud.ServerType{Name: "cx21"},
+		Datacenter: hcloud.Datacenter{Name: "fsn1-dc14", Location: hcloud.Location{Name: "fsn1", NetworkZone: "eu-central"}},
+		Labels:     labels,
+	}
+	server.PublicNet.IPv4.IP = ipv4
+	return server
+}
+
+func testServers() []hcloud.Server {
+	master := testServer(4711, "master", "203.0.113.10", map[string]string{clusterLabel: "prod"})
+	master.PrivateNet = []hcloud.ServerPrivateNet{{Network: 42, IP: "10.0.0.2"}}
+	node := testServer(4712, "node-1", "203.0.113.11", map[string]string{clusterLabel: "prod"})
+	node.ServerType.Name = "cpx31"
+	node.Datacenter = hcloud.Datacenter{Name: "nbg1-dc3", Location: hcloud.Location{Name: "nbg1", NetworkZone: "eu-central"}}
+	node.PrivateNet = []hcloud.ServerPrivateNet{{Network: 42, IP: "10.0.0.3"}}
+	return []hcloud.Server{master, node}
+}
+
+func newTestCloud(t *testing.T, config string) (*Cloud, *standin.Hetzner) {
+	api := standin.NewHetzner(testServers()...)
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("token: secret\nendpoint: %s\n%s", api.Endpoint(), config)))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c, api
+}
+
+func TestServerByName(t *testing.T) {
+	api := standin.NewHetzner(
+		testServer(1, "master", "203.0.113.30", map[string]string{clusterLabel: "dev"}),
+		testServer(2, "master", "203.0.113.31", map[string]string{clusterLabel: "prod"}),
+	)
+	defer api.Close()
+	client := hcloud.NewClient("secret", api.Endpoint(), nil)
+
+	server, err := serverByName(client, "prod", "master")
+	if err != nil || server.ID != 2 {
+		t.Errorf("expected server 2 of cluster prod, got %v (%v)", server, err)
+	}
+	if _, err := serverByName(client, "staging", "master"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestNodeAddresses(t *testing.T) {
+	c, api := newTestCloud(t, "network: 42\n")
+	defer api.Close()
+	api.Servers[0].PrivateNet = append(api.Servers[0].PrivateNet, hcloud.ServerPrivateNet{Network: 7, IP: "10.7.0.2"})
+	api.Servers[0].PublicNet.IPv6.IP = "2001:db8:1::/64"
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "hcloud://4711")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.2"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8:1::1"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+}
+
+func TestInstanceShutdownByProviderID(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+
+	if shutdown, err := c.instances.InstanceShutdownByProviderID(ctx, "hcloud://4711"); err != nil || shutdown {
+		t.Errorf("expected running server, got shutdown %v (%v)", shutdown, err)
+	}
+	api.Servers[0].Status = hcloud.ServerStatusOff
+	if shutdown, err := c.instances.InstanceShutdownByProviderID(ctx, "hcloud://4711"); err != nil || !shutdown {
+		t.Errorf("expected server to be shut down, got %v (%v)", shutdown, err)
+	}
+	if _, err := c.instances.InstanceShutdownByProviderID(ctx, "hcloud://1"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestListPages(t *testing.T) {
+	var servers []hcloud.Server
+	for i := 0; i < 120; i++ {
+		servers = append(servers, testServer(int64(i+1), fmt.Sprintf("node-%d", i), "", nil))
+	}
+	api := standin.NewHetzner(servers...)
+	defer api.Close()
+
+	listed, err := hcloud.NewClient("secret", api.Endpoint(), nil).ListServers(hcloud.ListOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != len(servers) || listed[119].Name != "node-119" {
+		t.Errorf("expected %d servers, got %d", len(servers), len(listed))
+	}
+}
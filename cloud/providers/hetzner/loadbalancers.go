@@ -0,0 +1,371 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+type loadbalancers struct {
+	client   *hcloud.Client
+	mutator  *cloud.Mutator
+	network  int64
+	location string
+	lbType   string
+}
+
+// newLoadbalancers returns a cloudprovider.LoadBalancer whose concrete type is a *loadbalancer.
+func newLoadbalancers(client *hcloud.Client, mutator *cloud.Mutator, conf *config) cloudprovider.LoadBalancer {
+	return &loadbalancers{
+		client:   client,
+		mutator:  mutator,
+		network:  conf.Network,
+		location: conf.Location,
+		lbType:   conf.LoadBalancerType,
+	}
+}
+
+// GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
+// *v1.Service parameter as read-only and not modify it.
+func (l *loadbalancers) GetLoadBalancerName(_ context.Context, clusterName string, service *v1.Service) string {
+	return cloudprovider.DefaultLoadBalancerName(service)
+}
+
+// GetLoadBalancer returns the *v1.LoadBalancerStatus of service.
+//
+// GetLoadBalancer will not modify service.
+func (l *loadbalancers) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (*v1.LoadBalancerStatus, bool, error) {
+	lb, err := l.find(l.GetLoadBalancerName(ctx, clusterName, service))
+	if err != nil || lb == nil {
+		return nil, false, err
+	}
+	return lbStatus(lb), true, nil
+}
+
+// EnsureLoadBalancer ensures that the cluster is running a load balancer for
+// service, forwarding its ports to their NodePorts on the servers of nodes.
+//
+// EnsureLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	services, err := lbServices(service)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := l.targets(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	lb, err := l.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if lb != nil {
+		if err := l.syncServices(service, lb, services); err != nil {
+			return nil, err
+		}
+		if err := l.syncTargets(service, lb, targets); err != nil {
+			return nil, err
+		}
+		return lbStatus(lb), nil
+	}
+
+	req := hcloud.LoadBalancerCreateRequest{
+		Name:             name,
+		LoadBalancerType: l.lbType,
+		Location:         l.location,
+		Services:         services,
+		Targets:          targets,
+	}
+	if req.Location == "" && len(targets) > 0 {
+		server, err := serverByID(l.client, targets[0].Server.ID)
+		if err != nil {
+			return nil, err
+		}
+		req.Location = server.Datacenter.Location.Name
+	}
+	if l.network != 0 {
+		req.Network = &l.network
+	}
+	if l.mutator.ClusterID() != "" {
+		req.Labels = map[string]string{clusterLabel: l.mutator.ClusterID()}
+	}
+	err = l.mutator.Do(service, "create load balancer", cloud.Params{"name": name, "type": req.LoadBalancerType, "location": req.Location, "cluster": l.mutator.ClusterID()}, func() error {
+		lb, err = l.client.CreateLoadBalancer(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lb == nil {
+		// dry-run mode
+		return &v1.LoadBalancerStatus{}, nil
+	}
+	log.Infof("%s: created load balancer %s", ProviderName, name)
+	return lbStatus(lb), nil
+}
+
+// UpdateLoadBalancer updates the load balancer for service to balance across
+// the servers of nodes.
+//
+// UpdateLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	targets, err := l.targets(nodes)
+	if err != nil {
+		return err
+	}
+	lb, err := l.find(name)
+	if err != nil {
+		return err
+	}
+	if lb == nil {
+		return fmt.Errorf("load balancer %s not found", name)
+	}
+	return l.syncTargets(service, lb, targets)
+}
+
+// EnsureLoadBalancerDeleted deletes the specified loadbalancer if it exists.
+// nil is returned if the load balancer for service does not exist or is
+// successfully deleted.
+//
+// EnsureLoadBalancerDeleted will not modify service.
+func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) error {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	lb, err := l.find(name)
+	if err != nil || lb == nil {
+		return err
+	}
+	err = l.mutator.Destroy(service, "delete load balancer", cloud.Params{"name": name, "id": strconv.FormatInt(lb.ID, 10), "cluster": l.mutator.ClusterID()}, func() error {
+		return l.client.DeleteLoadBalancer(lb.ID)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: deleted load balancer %s", ProviderName, name)
+	return nil
+}
+
+// ListOwned lists the load balancers labelled with cluster clusterID, so that
+// the load balancers of deleted Services are collected.
+func (l *loadbalancers) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	lbs, err := l.client.ListLoadBalancers(hcloud.ListOpts{LabelSelector: clusterSelector(clusterID)})
+	if err != nil {
+		return nil, err
+	}
+	var owned []cloud.OwnedResource
+	for _, lb := range lbs {
+		owned = append(owned, cloud.OwnedResource{Kind: "load balancer", ID: strconv.FormatInt(lb.ID, 10), LoadBalancerName: lb.Name})
+	}
+	return owned, nil
+}
+
+func (l *loadbalancers) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	id, err := strconv.ParseInt(resource.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	err = l.client.DeleteLoadBalancer(id)
+	if hcloud.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// find returns the load balancer called name of the cluster, or nil if there
+// is none. An unlabelled load balancer, created before the cluster ID was
+// configured, is labelled with the cluster and returned.
+func (l *loadbalancers) find(name string) (*hcloud.LoadBalancer, error) {
+	lbs, err := l.client.ListLoadBalancers(hcloud.ListOpts{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	clusterID := l.mutator.ClusterID()
+	for i := range lbs {
+		lb := &lbs[i]
+		if lb.Name != name {
+			continue
+		}
+		cluster, labelled := lb.Labels[clusterLabel]
+		switch {
+		case clusterID == "" || cluster == clusterID:
+			return lb, nil
+		case labelled:
+			// the load balancer of another cluster
+			continue
+		}
+
+		labels := map[string]string{clusterLabel: clusterID}
+		for k, v := range lb.Labels {
+			labels[k] = v
+		}
+		err := l.mutator.Do(nil, "label load balancer", cloud.Params{"name": name, "id": strconv.FormatInt(lb.ID, 10), "cluster": clusterID}, func() error {
+			return l.client.SetLoadBalancerLabels(lb.ID, labels)
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("%s: labelled load balancer %s with cluster %s", ProviderName, name, clusterID)
+		lb.Labels = labels
+		return lb, nil
+	}
+	return nil, nil
+}
+
+// targets returns the server targets of nodes, found by provider ID or, if
+// it is not set, by name.
+func (l *loadbalancers) targets(nodes []*v1.Node) ([]hcloud.LoadBalancerTarget, error) {
+	var targets []hcloud.LoadBalancerTarget
+	for _, node := range nodes {
+		var id int64
+		if node.Spec.ProviderID != "" {
+			serverID, err := cloud.NumericIDFromProviderID(ProviderName, node.Spec.ProviderID)
+			if err != nil {
+				return nil, err
+			}
+			id = serverID
+		} else {
+			server, err := serverByName(l.client, l.mutator.ClusterID(), types.NodeName(node.Name))
+			if err != nil {
+				return nil, fmt.Errorf("node %s: %v", node.Name, err)
+			}
+			id = server.ID
+		}
+		targets = append(targets, hcloud.LoadBalancerTarget{
+			Type:         hcloud.LoadBalancerTargetTypeServer,
+			Server:       &hcloud.LoadBalancerTargetServer{ID: id},
+			UsePrivateIP: l.network != 0,
+		})
+	}
+	return targets, nil
+}
+
+// syncServices makes the services of lb forward the listen ports of desired.
+func (l *loadbalancers) syncServices(service *v1.Service, lb *hcloud.LoadBalancer, desired []hcloud.LoadBalancerService) error {
+	missing := map[int]hcloud.LoadBalancerService{}
+	for _, s := range desired {
+		missing[s.ListenPort] = s
+	}
+	for _, current := range lb.Services {
+		s, found := missing[current.ListenPort]
+		delete(missing, current.ListenPort)
+		switch {
+		case !found:
+			err := l.mutator.Destroy(service, "delete load balancer service", cloud.Params{"name": lb.Name, "listenPort": strconv.Itoa(current.ListenPort)}, func() error {
+				return l.client.DeleteService(lb.ID, current.ListenPort)
+			})
+			if err != nil {
+				return err
+			}
+		case s.Protocol != current.Protocol || s.DestinationPort != current.DestinationPort:
+			err := l.mutator.Do(service, "update load balancer service", serviceParams(lb, s), func() error {
+				return l.client.UpdateService(lb.ID, s)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range desired {
+		if _, found := missing[s.ListenPort]; !found {
+			continue
+		}
+		s := s
+		err := l.mutator.Do(service, "add load balancer service", serviceParams(lb, s), func() error {
+			return l.client.AddService(lb.ID, s)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncTargets makes the server targets of lb match desired.
+func (l *loadbalancers) syncTargets(service *v1.Service, lb *hcloud.LoadBalancer, desired []hcloud.LoadBalancerTarget) error {
+	missing := map[int64]hcloud.LoadBalancerTarget{}
+	for _, t := range desired {
+		missing[t.Server.ID] = t
+	}
+	for _, current := range lb.Targets {
+		if current.Type != hcloud.LoadBalancerTargetTypeServer || current.Server == nil {
+			continue
+		}
+		if _, found := missing[current.Server.ID]; found {
+			delete(missing, current.Server.ID)
+			continue
+		}
+		current := current
+		err := l.mutator.Destroy(service, "remove load balancer target", targetParams(lb, current), func() error {
+			return l.client.RemoveTarget(lb.ID, current)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range desired {
+		if _, found := missing[t.Server.ID]; !found {
+			continue
+		}
+		t := t
+		err := l.mutator.Do(service, "add load balancer target", targetParams(lb, t), func() error {
+			return l.client.AddTarget(lb.ID, t)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lbServices returns the load balancer services forwarding the ports of
+// service to their NodePorts. Hetzner load balancers only forward TCP.
+func lbServices(service *v1.Service) ([]hcloud.LoadBalancerService, error) {
+	var services []hcloud.LoadBalancerService
+	for _, port := range service.Spec.Ports {
+		if port.Protocol != v1.ProtocolTCP {
+			return nil, fmt.Errorf("port %d of service %s/%s: protocol %s is not supported, only TCP", port.Port, service.Namespace, service.Name, port.Protocol)
+		}
+		services = append(services, hcloud.LoadBalancerService{
+			Protocol:        hcloud.LoadBalancerServiceProtocolTCP,
+			ListenPort:      int(port.Port),
+			DestinationPort: int(port.NodePort),
+		})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ListenPort < services[j].ListenPort
+	})
+	return services, nil
+}
+
+func serviceParams(lb *hcloud.LoadBalancer, s hcloud.LoadBalancerService) cloud.Params {
+	return cloud.Params{
+		"name":            lb.Name,
+		"protocol":        s.Protocol,
+		"listenPort":      strconv.Itoa(s.ListenPort),
+		"destinationPort": strconv.Itoa(s.DestinationPort),
+	}
+}
+
+func targetParams(lb *hcloud.LoadBalancer, t hcloud.LoadBalancerTarget) cloud.Params {
+	return cloud.Params{"name": lb.Name, "server": strconv.FormatInt(t.Server.ID, 10)}
+}
+
+func lbStatus(lb *hcloud.LoadBalancer) *v1.LoadBalancerStatus {
+	status := &v1.LoadBalancerStatus{}
+	for _, ip := range []string{lb.PublicNet.IPv4.IP, lb.PublicNet.IPv6.IP} {
+		if ip != "" {
+			status.Ingress = append(status.Ingress, v1.LoadBalancerIngress{IP: ip})
+		}
+	}
+	return status
+}
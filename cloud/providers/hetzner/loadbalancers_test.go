@@ -0,0 +1,188 @@
+package hetzner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+// testLBName is the load balancer name of the loadBalancerService, see
+// cloudprovider.DefaultLoadBalancerName
+const testLBName = "a9f2b7c1e0d3a4b5c8e6f7a8b9c0d1e2"
+
+func loadBalancerService(ports ...v1.ServicePort) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: metav1.NamespaceDefault, UID: types.UID("9f2b7c1e-0d3a-4b5c-8e6f-7a8b9c0d1e2f")},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer, Ports: ports},
+	}
+}
+
+func testNodes() []*v1.Node {
+	return []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}, Spec: v1.NodeSpec{ProviderID: "hcloud://4711"}},
+		// found by name
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	}
+}
+
+func targetIDs(lb hcloud.LoadBalancer) []int64 {
+	var ids []int64
+	for _, t := range lb.Targets {
+		ids = append(ids, t.Server.ID)
+	}
+	return ids
+}
+
+func TestEnsureLoadBalancer(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\nnetwork: 42\n")
+	defer api.Close()
+	ctx := context.Background()
+
+	service := loadBalancerService(
+		v1.ServicePort{Name: "https", Protocol: v1.ProtocolTCP, Port: 443, NodePort: 30443},
+		v1.ServicePort{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080},
+	)
+	status, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "198.51.100.1"}, {IP: "2001:db8:100::1"}}}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("expected status %v, got %v", expected, status)
+	}
+	if len(api.LoadBalancers) != 1 {
+		t.Fatalf("expected 1 load balancer, got %v", api.LoadBalancers)
+	}
+	lb := api.LoadBalancers[0]
+	if lb.Name != testLBName || lb.Location.Name != "fsn1" || lb.LoadBalancerType.Name != "lb11" || lb.Labels[clusterLabel] != "prod" {
+		t.Errorf("unexpected load balancer %+v", lb)
+	}
+	if len(lb.PrivateNet) != 1 || lb.PrivateNet[0].Network != 42 || !lb.Targets[0].UsePrivateIP {
+		t.Errorf("expected load balancer to reach its targets in network 42, got %+v", lb)
+	}
+	services := []hcloud.LoadBalancerService{
+		{Protocol: "tcp", ListenPort: 80, DestinationPort: 30080},
+		{Protocol: "tcp", ListenPort: 443, DestinationPort: 30443},
+	}
+	if !reflect.DeepEqual(lb.Services, services) {
+		t.Errorf("expected services %v, got %v", services, lb.Services)
+	}
+	if ids := targetIDs(lb); !reflect.DeepEqual(ids, []int64{4711, 4712}) {
+		t.Errorf("expected targets 4711 and 4712, got %v", ids)
+	}
+
+	// changed ports are synced
+	service.Spec.Ports = []v1.ServicePort{
+		{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30081},
+		{Name: "ssh", Protocol: v1.ProtocolTCP, Port: 22, NodePort: 30022},
+	}
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()[1:]); err != nil {
+		t.Fatal(err)
+	}
+	services = []hcloud.LoadBalancerService{
+		{Protocol: "tcp", ListenPort: 80, DestinationPort: 30081},
+		{Protocol: "tcp", ListenPort: 22, DestinationPort: 30022},
+	}
+	if lb := api.LoadBalancers[0]; !reflect.DeepEqual(lb.Services, services) {
+		t.Errorf("expected services %v, got %v", services, lb.Services)
+	}
+	if ids := targetIDs(api.LoadBalancers[0]); !reflect.DeepEqual(ids, []int64{4712}) {
+		t.Errorf("expected target 4712, got %v", ids)
+	}
+
+	service.Spec.Ports = append(service.Spec.Ports, v1.ServicePort{Name: "dns", Protocol: v1.ProtocolUDP, Port: 53, NodePort: 30053})
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err == nil {
+		t.Error("expected error for UDP port")
+	}
+}
+
+func TestLoadBalancerOfOtherCluster(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\nlocation: hel1\nloadBalancerType: lb21\n")
+	defer api.Close()
+	ctx := context.Background()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	api.LoadBalancers = []hcloud.LoadBalancer{{ID: 1, Name: testLBName, Labels: map[string]string{clusterLabel: "staging"}}}
+
+	if _, exists, err := c.loadbalancers.GetLoadBalancer(ctx, "prod", service); err != nil || exists {
+		t.Errorf("expected load balancer of another cluster to be ignored, got %v (%v)", exists, err)
+	}
+	if err := c.loadbalancers.EnsureLoadBalancerDeleted(ctx, "prod", service); err != nil || len(api.LoadBalancers) != 1 {
+		t.Errorf("expected load balancer of another cluster to be kept, got %v (%v)", api.LoadBalancers, err)
+	}
+	// its name is taken
+	api.LoadBalancers[0].Name = "other"
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err != nil {
+		t.Fatal(err)
+	}
+	if lb := api.LoadBalancers[1]; lb.Location.Name != "hel1" || lb.LoadBalancerType.Name != "lb21" {
+		t.Errorf("expected configured location and type, got %+v", lb)
+	}
+}
+
+func TestUnlabelledLoadBalancer(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	ctx := context.Background()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	// created before the cluster ID was configured
+	api.LoadBalancers = []hcloud.LoadBalancer{{ID: 1, Name: testLBName, Labels: map[string]string{"team": "web"}}}
+
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.LoadBalancers) != 1 {
+		t.Fatalf("expected the load balancer to be reused, got %v", api.LoadBalancers)
+	}
+	if expected := map[string]string{clusterLabel: "prod", "team": "web"}; !reflect.DeepEqual(api.LoadBalancers[0].Labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, api.LoadBalancers[0].Labels)
+	}
+}
+
+func TestCollectLoadBalancers(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	api.LoadBalancers = []hcloud.LoadBalancer{
+		{ID: 1, Name: testLBName, Labels: map[string]string{clusterLabel: "prod"}},
+		{ID: 2, Name: "aorphaned", Labels: map[string]string{clusterLabel: "prod"}},
+		{ID: 3, Name: "astaging", Labels: map[string]string{clusterLabel: "staging"}},
+	}
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: cloud.DefaultGCOptions.Interval}); err != nil {
+		t.Fatal(err)
+	}
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c.loadbalancers.(cloud.ResourceSource))
+	if err := collector.Collect(context.Background(), []v1.Service{*loadBalancerService()}); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, lb := range api.LoadBalancers {
+		names = append(names, lb.Name)
+	}
+	if expected := []string{testLBName, "astaging"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected load balancers %v, got %v", expected, names)
+	}
+}
+
+func TestEnsureLoadBalancerDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	status, err := c.loadbalancers.EnsureLoadBalancer(context.Background(), "prod", service, testNodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Ingress) != 0 || len(api.LoadBalancers) != 0 {
+		t.Errorf("expected no load balancer in dry-run mode, got %v", api.LoadBalancers)
+	}
+}
@@ -0,0 +1,16 @@
+package hetzner
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestReplay(t *testing.T) {
+	conformance.Replay(t, ProviderName, "testdata/replay.yaml", func(data []byte) ([]string, error) {
+		conf := &config{}
+		err := yaml.Unmarshal(data, conf)
+		return []string{conf.Token}, err
+	})
+}
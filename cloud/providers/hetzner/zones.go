@@ -0,0 +1,89 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+const (
+	metadataURL  = "http://169.254.169.254/"
+	serverIDPath = "hetzner/v1/metadata/instance-id"
+)
+
+type zones struct {
+	client      *hcloud.Client
+	metadataURL string
+	clusterID   string
+}
+
+func newZones(client *hcloud.Client, metadataURL, clusterID string) cloudprovider.Zones {
+	return zones{client, metadataURL, clusterID}
+}
+
+func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
+	id, err := fetchServerID(z.metadataURL)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	server, err := serverByID(z.client, id)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return serverZone(server), nil
+}
+
+func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudprovider.Zone, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	server, err := serverByID(z.client, id)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return serverZone(server), nil
+}
+
+func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
+	server, err := serverByName(z.client, z.clusterID, nodeName)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return serverZone(server), nil
+}
+
+// serverZone returns the location of server as region, e.g. fsn1, and its
+// datacenter as failure domain, e.g. fsn1-dc14.
+func serverZone(server *hcloud.Server) cloudprovider.Zone {
+	return cloudprovider.Zone{Region: server.Datacenter.Location.Name, FailureDomain: server.Datacenter.Name}
+}
+
+func fetchServerID(metadataURL string) (int64, error) {
+	client := cloud.HTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(strings.TrimSuffix(metadataURL, "/") + "/" + serverIDPath)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to fetch server id from metadata service: %s", resp.Status)
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
@@ -1,9 +1,11 @@
 package lightsail
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	_aws "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -11,24 +13,48 @@ import (
 	"github.com/aws/aws-sdk-go/service/lightsail"
 	"github.com/ghodss/yaml"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
-const (
-	ProviderName = "lightsail"
-)
+const ProviderName = "lightsail"
+
+// apiEndpoint returns the Lightsail API endpoint of the region of the
+// instance, read from the instance metadata.
+func apiEndpoint() (string, error) {
+	zone, err := getZone(metadataURL)
+	if err != nil {
+		return "", err
+	}
+	return "https://lightsail." + zone.Region + ".amazonaws.com/", nil
+}
 
 type tokenSource struct {
 	AccessKeyID     string `json:"accessKeyID" yaml:"accessKeyID"`
 	SecretAccessKey string `json:"secretAccessKey" yaml:"secretAccessKey"`
+	// Endpoint overrides the URL of the Lightsail API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// MetadataURL overrides the base URL of the instance metadata service
+	MetadataURL string `json:"metadataURL,omitempty" yaml:"metadataURL,omitempty"`
+	// ClusterID limits the controller to the resources it created for the
+	// cluster. Instances are not filtered, Lightsail instance names are unique
+	// per region and the API version in use does not support tags
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+
+	portOptions
 }
 
 type Cloud struct {
 	client    *lightsail.Lightsail
 	instances cloudprovider.Instances
 	zones     cloudprovider.Zones
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+	ports     *portController
 }
 
 func init() {
+	cloud.RegisterAPIEndpointFunc(ProviderName, apiEndpoint)
 	cloudprovider.RegisterCloudProvider(
 		ProviderName,
 		func(config io.Reader) (cloudprovider.Interface, error) {
@@ -47,7 +73,10 @@ func newCloud(config io.Reader) (cloudprovider.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	zone, err := getZone()
+	if tokenSource.MetadataURL == "" {
+		tokenSource.MetadataURL = metadataURL
+	}
+	zone, err := getZone(tokenSource.MetadataURL)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +84,10 @@ func newCloud(config io.Reader) (cloudprovider.Interface, error) {
 	conf := &_aws.Config{
 		Region:      &zone.Region,
 		Credentials: credentials.NewStaticCredentials(tokenSource.AccessKeyID, tokenSource.SecretAccessKey, ""),
+		HTTPClient:  cloud.HTTPClient(),
+	}
+	if tokenSource.Endpoint != "" {
+		conf.Endpoint = &tokenSource.Endpoint
 	}
 
 	sess, err := session.NewSession(conf)
@@ -63,14 +96,23 @@ func newCloud(config io.Reader) (cloudprovider.Interface, error) {
 	}
 	lightsailClient := lightsail.New(sess)
 
+	mutator := cloud.NewMutator(ProviderName, tokenSource.ClusterID)
+	collector := cloud.NewCollector(mutator)
 	return &Cloud{
 		client:    lightsailClient,
-		instances: newInstances(lightsailClient),
-		zones:     newZones(lightsailClient),
+		instances: newInstances(lightsailClient, mutator),
+		zones:     newZones(lightsailClient, tokenSource.MetadataURL),
+
+		mutator:   mutator,
+		collector: collector,
+		ports:     newPortController(lightsailClient, mutator, tokenSource.portOptions),
 	}, nil
 }
 
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+	c.ports.Start(clientBuilder, stop)
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
@@ -105,13 +147,24 @@ func (c *Cloud) HasClusterID() bool {
 	return true
 }
 
-func GetMetadata(path string) (string, error) {
-	resp, err := http.Get(metadataURL + path)
+// GetMetadata fetches path from the instance metadata service at metadataURL.
+func GetMetadata(metadataURL, path string) (string, error) {
+	client := cloud.HTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(strings.TrimSuffix(metadataURL, "/") + "/" + metadataPath + path)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
-	return string(body), err
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s from metadata service: %s", path, resp.Status)
+	}
+	return string(body), nil
 }
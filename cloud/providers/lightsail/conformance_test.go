@@ -0,0 +1,33 @@
+package lightsail
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, closeAll := newTestCloud(t)
+	defer closeAll()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				// instances are identified by name
+				Name:       "ls5-master",
+				ProviderID: "lightsail://ls5-master",
+				Type:       "medium_2_0",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "ls5-master"},
+					{Type: v1.NodeInternalIP, Address: "172.26.0.10"},
+					{Type: v1.NodeExternalIP, Address: "34.210.0.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "us-west-2", FailureDomain: "us-west-2a"},
+			},
+		},
+		MissingName:       "ls5-missing",
+		MissingProviderID: "lightsail://ls5-missing",
+	})
+}
@@ -1,42 +1,150 @@
 package lightsail
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	_aws "github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/lightsail"
+	v1 "k8s.io/api/core/v1"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
 )
 
-func TestInstances(t *testing.T) {
-	client := getClient()
-	token := ""
-	instances, err := client.GetInstances(&lightsail.GetInstancesInput{PageToken: &token})
-	fmt.Println(err)
-	fmt.Println(instances, instances.NextPageToken)
+func testInstance(name, privateIP, publicIP string) *lightsail.Instance {
+	return &lightsail.Instance{
+		Name:             _aws.String(name),
+		BundleId:         _aws.String("medium_2_0"),
+		PrivateIpAddress: _aws.String(privateIP),
+		PublicIpAddress:  _aws.String(publicIP),
+		Location: &lightsail.ResourceLocation{
+			RegionName:       _aws.String("us-west-2"),
+			AvailabilityZone: _aws.String("us-west-2a"),
+		},
+	}
 }
 
-func TestInstance(t *testing.T) {
-	client := getClient()
-	instanceName := "ls5-master"
-	ins, err := client.GetInstance(&lightsail.GetInstanceInput{
-		InstanceName: _aws.String(instanceName),
+func newTestCloud(t *testing.T) (*Cloud, func()) {
+	api := standin.NewLightsail(
+		testInstance("ls5-master", "172.26.0.10", "34.210.0.10"),
+		testInstance("ls5-node", "172.26.0.11", "34.210.0.11"),
+	)
+	metadata := standin.NewMetadata(map[string]string{
+		"/latest/meta-data/placement/availability-zone": "us-west-2a",
 	})
-	fmt.Println(err)
-	fmt.Println(ins.Instance)
+	closeAll := func() {
+		api.Close()
+		metadata.Close()
+	}
+
+	config := fmt.Sprintf("accessKeyID: id\nsecretAccessKey: secret\nendpoint: %s\nmetadataURL: %s\n", api.Endpoint(), metadata.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		closeAll()
+		t.Fatal(err)
+	}
+	return c.(*Cloud), closeAll
 }
 
-func getClient() *lightsail.Lightsail {
-	region := "us-west-2"
-	conf := &_aws.Config{
-		Region: &region,
-		//Credentials: credentials.NewStaticCredentials("", "", ""),
+func TestInstanceByName(t *testing.T) {
+	c, closeAll := newTestCloud(t)
+	defer closeAll()
+
+	instance, err := instanceByName(c.client, "ls5-master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *instance.PublicIpAddress != "34.210.0.10" {
+		t.Errorf("expected public ip 34.210.0.10, got %s", *instance.PublicIpAddress)
 	}
 
-	sess, err := session.NewSession(conf)
+	if _, err := instanceByName(c.client, "ls5-missing"); err == nil {
+		t.Error("expected error for missing instance")
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c, closeAll := newTestCloud(t)
+	defer closeAll()
+	ctx := context.Background()
+
+	addresses, err := c.instances.NodeAddressesByProviderID(ctx, "lightsail://ls5-node")
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "ls5-node"},
+		{Type: v1.NodeInternalIP, Address: "172.26.0.11"},
+		{Type: v1.NodeExternalIP, Address: "34.210.0.11"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	instanceType, err := c.instances.InstanceType(ctx, "ls5-master")
+	if err != nil || instanceType != "medium_2_0" {
+		t.Errorf("expected instance type medium_2_0, got %q (%v)", instanceType, err)
+	}
+}
+
+func TestZone(t *testing.T) {
+	c, closeAll := newTestCloud(t)
+	defer closeAll()
+
+	zone, err := c.zones.GetZone(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone.Region != "us-west-2" || zone.FailureDomain != "us-west-2a" {
+		t.Errorf("expected zone us-west-2/us-west-2a, got %s/%s", zone.Region, zone.FailureDomain)
+	}
+}
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOyiAiEqGOnlV2yPeHsqMmGnUhpcNTROCwsmk0vxihM+ alice@example.com"
+
+func TestAddSSHKeyToAllInstances(t *testing.T) {
+	c, closeAll := newTestCloud(t)
+	defer closeAll()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := c.instances.AddSSHKeyToAllInstances(ctx, "alice", []byte(testSSHKey)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the same key of another user must not be imported again
+	if err := c.instances.AddSSHKeyToAllInstances(ctx, "bob", []byte(testSSHKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := c.client.GetKeyPairs(&lightsail.GetKeyPairsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.KeyPairs) != 1 {
+		t.Fatalf("expected 1 key pair, got %v", out.KeyPairs)
+	}
+	if name := _aws.StringValue(out.KeyPairs[0].Name); !strings.HasPrefix(name, "alice-") {
+		t.Errorf("unexpected key pair name %q", name)
+	}
+}
+
+func TestDualStackAddresses(t *testing.T) {
+	instance := testInstance("ls5-master", "172.26.0.10", "34.210.0.10")
+	instance.Ipv6Address = _aws.String("2600:1f14::10")
+	addresses, err := nodeAddresses(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "ls5-master"},
+		{Type: v1.NodeInternalIP, Address: "172.26.0.10"},
+		{Type: v1.NodeExternalIP, Address: "34.210.0.10"},
+		{Type: v1.NodeExternalIP, Address: "2600:1f14::10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
 	}
-	return lightsail.New(sess)
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	. "github.com/appscode/go/types"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/lightsail"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
@@ -15,11 +16,12 @@ import (
 )
 
 type instances struct {
-	client *lightsail.Lightsail
+	client  *lightsail.Lightsail
+	mutator *cloud.Mutator
 }
 
-func newInstances(client *lightsail.Lightsail) cloudprovider.Instances {
-	return &instances{client}
+func newInstances(client *lightsail.Lightsail, mutator *cloud.Mutator) cloudprovider.Instances {
+	return &instances{client: client, mutator: mutator}
 }
 
 func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
@@ -57,15 +59,22 @@ func nodeAddresses(instance *lightsail.Instance) ([]v1.NodeAddress, error) {
 	}
 	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: String(instance.PublicIpAddress)})
 
-	return addresses, nil
+	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: String(instance.Ipv6Address)})
+
+	return cloud.NodeAddresses(addresses), nil
 }
 
-func (i *instances) ExternalID(_ context.Context, nodeName types.NodeName) (string, error) {
-	return string(nodeName), nil
+func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
+	return i.InstanceID(ctx, nodeName)
 }
 
+// InstanceID returns the name of the instance, which identifies it in Lightsail.
 func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
-	return string(nodeName), nil
+	instance, err := instanceByName(i.client, nodeName)
+	if err != nil {
+		return "", err
+	}
+	return String(instance.Name), nil
 }
 
 func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
@@ -88,8 +97,37 @@ func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID strin
 	return *instance.BundleId, nil
 }
 
+// AddSSHKeyToAllInstances imports the key as a key pair of the region, unless
+// a key pair with the same fingerprint exists. Instances launched with the key
+// pair accept the key.
 func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
-	return cloud.ErrNotImplemented
+	key, err := cloud.ParseSSHKey(user, keyData)
+	if err != nil {
+		return err
+	}
+	input := &lightsail.GetKeyPairsInput{}
+	for {
+		out, err := i.client.GetKeyPairs(input)
+		if err != nil {
+			return err
+		}
+		for _, kp := range out.KeyPairs {
+			if String(kp.Fingerprint) == key.Fingerprint || String(kp.Name) == key.Name {
+				return nil
+			}
+		}
+		if String(out.NextPageToken) == "" {
+			break
+		}
+		input.PageToken = out.NextPageToken
+	}
+	return i.mutator.Do(nil, "import key pair", cloud.Params{"name": key.Name, "fingerprint": key.Fingerprint}, func() error {
+		_, err := i.client.ImportKeyPair(&lightsail.ImportKeyPairInput{
+			KeyPairName:     StringP(key.Name),
+			PublicKeyBase64: StringP(key.PublicKey),
+		})
+		return err
+	})
 }
 
 func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
@@ -103,11 +141,10 @@ func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID str
 	}
 
 	_, err = instanceByID(i.client, id)
-	if err == nil {
-		return true, nil
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
 	}
-
-	return false, nil
+	return err == nil, err
 }
 
 func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID string) (bool, error) {
@@ -118,6 +155,9 @@ func instanceByName(client *lightsail.Lightsail, nodeName types.NodeName) (*ligh
 	host, err := client.GetInstance(&lightsail.GetInstanceInput{
 		InstanceName: StringP(string(nodeName)),
 	})
+	if e, ok := err.(awserr.Error); ok && e.Code() == lightsail.ErrCodeNotFoundException {
+		return nil, cloudprovider.InstanceNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
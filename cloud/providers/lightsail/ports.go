@@ -0,0 +1,307 @@
+package lightsail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appscode/go/log"
+	. "github.com/appscode/go/types"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// portInterval is the time between two syncs of the instance public ports
+const portInterval = time.Minute
+
+// allSources is the only source range public ports can be opened to, as the
+// Lightsail API version in use does not restrict the source of a port.
+const allSources = "0.0.0.0/0"
+
+// publicPortsAnnotation records on a Node the public ports of its instance
+// opened by the controller, e.g. tcp/30080,udp/30053. Only these are closed.
+var publicPortsAnnotation = cloud.LabelKey(ProviderName, "public-ports")
+
+// portOptions configure the public ports of the instances.
+type portOptions struct {
+	// ManagePublicPorts opens the NodePorts of NodePort and LoadBalancer
+	// Services in the public ports of the instances of the Nodes, and closes
+	// them once they are no longer used. Ports opened by hand are never
+	// closed, and ports are only closed with a ClusterID configured. Ports of
+	// Services restricted by loadBalancerSourceRanges are not opened, as
+	// public ports are open to all sources.
+	ManagePublicPorts bool `json:"managePublicPorts,omitempty" yaml:"managePublicPorts,omitempty"`
+}
+
+// publicPort is a NodePort of a protocol, tcp or udp.
+type publicPort struct {
+	protocol string
+	port     int64
+}
+
+func (p publicPort) String() string {
+	return fmt.Sprintf("%s/%d", p.protocol, p.port)
+}
+
+// portController keeps the public ports of the instances of the Nodes in sync
+// with the NodePorts of the Services.
+type portController struct {
+	client  *lightsail.Lightsail
+	mutator *cloud.Mutator
+	options portOptions
+}
+
+func newPortController(client *lightsail.Lightsail, mutator *cloud.Mutator, options portOptions) *portController {
+	return &portController{client: client, mutator: mutator, options: options}
+}
+
+// Start runs the controller until stop is closed, if it is enabled.
+func (c *portController) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !c.options.ManagePublicPorts {
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-port-controller")
+	go wait.Until(func() {
+		if err := c.Sync(context.Background(), client); err != nil {
+			log.Errorf("%s: failed to sync instance public ports: %v", ProviderName, err)
+		}
+	}, portInterval, stop)
+}
+
+// Sync opens the NodePorts of the Services on the instances of all Nodes and
+// closes the ports it opened that are no longer used.
+func (c *portController) Sync(_ context.Context, kube kubernetes.Interface) error {
+	services, err := kube.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	nodes, err := kube.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	desired := map[publicPort]bool{}
+	for i := range services.Items {
+		service := &services.Items[i]
+		ports, err := servicePorts(service)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %s/%s: %v", service.Namespace, service.Name, err))
+			continue
+		}
+		for _, port := range ports {
+			desired[port] = true
+		}
+	}
+
+	for i := range nodes.Items {
+		if err := c.syncNode(kube, &nodes.Items[i], desired); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", nodes.Items[i].Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// servicePorts returns the public ports to open for service. The ports of
+// LoadBalancer Services restricted to other sources than 0.0.0.0/0 are
+// skipped with a warning.
+func servicePorts(service *v1.Service) ([]publicPort, error) {
+	switch service.Spec.Type {
+	case v1.ServiceTypeNodePort:
+		var ports []publicPort
+		for _, port := range service.Spec.Ports {
+			if port.NodePort != 0 && (port.Protocol == v1.ProtocolTCP || port.Protocol == v1.ProtocolUDP) {
+				ports = append(ports, publicPort{protocol: strings.ToLower(string(port.Protocol)), port: int64(port.NodePort)})
+			}
+		}
+		return ports, nil
+	case v1.ServiceTypeLoadBalancer:
+		rules, err := cloud.NodePortRules(service)
+		if err != nil {
+			return nil, err
+		}
+		open := map[publicPort]bool{}
+		for _, rule := range rules {
+			port := publicPort{protocol: rule.Protocol, port: int64(rule.Port)}
+			open[port] = open[port] || rule.Source == allSources
+		}
+		var ports []publicPort
+		for port, public := range open {
+			if !public {
+				log.Warningf("%s: not opening NodePort %s of service %s/%s, public ports can not be restricted to its source ranges", ProviderName, port, service.Namespace, service.Name)
+				continue
+			}
+			ports = append(ports, port)
+		}
+		return ports, nil
+	}
+	return nil, nil
+}
+
+// syncNode opens the desired ports that are closed on the instance of node and
+// closes those it opened that are no longer desired. The ports it opened are
+// recorded in the publicPortsAnnotation of node.
+func (c *portController) syncNode(kube kubernetes.Interface, node *v1.Node, desired map[publicPort]bool) error {
+	name := node.Name
+	if node.Spec.ProviderID != "" {
+		id, err := instanceIDFromProviderID(node.Spec.ProviderID)
+		if err != nil {
+			return err
+		}
+		name = id
+	}
+	states, err := c.client.GetInstancePortStates(&lightsail.GetInstancePortStatesInput{InstanceName: StringP(name)})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	owned := parsePublicPorts(node.Annotations[publicPortsAnnotation])
+	for port := range owned {
+		if desired[port] {
+			continue
+		}
+		if !isOpen(states.PortStates, port, true) {
+			// closed by someone else
+			delete(owned, port)
+			continue
+		}
+		err := c.mutator.Destroy(node, "close instance public port", cloud.Params{"instance": name, "port": port.String()}, func() error {
+			_, err := c.client.CloseInstancePublicPorts(&lightsail.CloseInstancePublicPortsInput{
+				InstanceName: StringP(name),
+				PortInfo:     portInfo(port),
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(owned, port)
+		log.Infof("%s: closed public port %s of instance %s", ProviderName, port, name)
+	}
+
+	for _, port := range sortedPublicPorts(desired) {
+		if isOpen(states.PortStates, port, false) {
+			// open already, by the controller or by someone else
+			continue
+		}
+		err := c.mutator.Do(node, "open instance public port", cloud.Params{"instance": name, "port": port.String()}, func() error {
+			_, err := c.client.OpenInstancePublicPorts(&lightsail.OpenInstancePublicPortsInput{
+				InstanceName: StringP(name),
+				PortInfo:     portInfo(port),
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		owned[port] = true
+		log.Infof("%s: opened public port %s of instance %s", ProviderName, port, name)
+	}
+
+	if value := formatPublicPorts(owned); value != node.Annotations[publicPortsAnnotation] && !cloud.DryRun() {
+		if err := recordPublicPorts(kube, node.Name, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// isOpen returns true if port is open in states. If exact is set, only a state
+// of the single port counts, otherwise any range or protocol covering it.
+func isOpen(states []*lightsail.InstancePortState, port publicPort, exact bool) bool {
+	for _, state := range states {
+		if String(state.State) != lightsail.PortStateOpen {
+			continue
+		}
+		from, to, protocol := Int64(state.FromPort), Int64(state.ToPort), String(state.Protocol)
+		if exact && from == port.port && to == port.port && protocol == port.protocol {
+			return true
+		}
+		if !exact && from <= port.port && port.port <= to && (protocol == port.protocol || protocol == lightsail.NetworkProtocolAll) {
+			return true
+		}
+	}
+	return false
+}
+
+func portInfo(port publicPort) *lightsail.PortInfo {
+	return &lightsail.PortInfo{
+		FromPort: Int64P(port.port),
+		ToPort:   Int64P(port.port),
+		Protocol: StringP(port.protocol),
+	}
+}
+
+// recordPublicPorts sets the publicPortsAnnotation of the Node called name to
+// value, or removes it if value is empty.
+func recordPublicPorts(kube kubernetes.Interface, name, value string) error {
+	var annotation interface{}
+	if value != "" {
+		annotation = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{publicPortsAnnotation: annotation},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = kube.CoreV1().Nodes().Patch(name, types.MergePatchType, patch)
+	return err
+}
+
+// parsePublicPorts parses the value of a publicPortsAnnotation, skipping
+// malformed ports.
+func parsePublicPorts(value string) map[publicPort]bool {
+	ports := map[publicPort]bool{}
+	for _, s := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(s), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		port, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ports[publicPort{protocol: parts[0], port: port}] = true
+	}
+	return ports
+}
+
+func formatPublicPorts(ports map[publicPort]bool) string {
+	var values []string
+	for _, port := range sortedPublicPorts(ports) {
+		values = append(values, port.String())
+	}
+	return strings.Join(values, ",")
+}
+
+func sortedPublicPorts(ports map[publicPort]bool) []publicPort {
+	sorted := make([]publicPort, 0, len(ports))
+	for port := range ports {
+		sorted = append(sorted, port)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].port != sorted[j].port {
+			return sorted[i].port < sorted[j].port
+		}
+		return sorted[i].protocol < sorted[j].protocol
+	})
+	return sorted
+}
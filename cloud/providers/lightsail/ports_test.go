@@ -0,0 +1,153 @@
+package lightsail
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	_aws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func newPortTestCloud(t *testing.T, api *standin.Lightsail) (*Cloud, func()) {
+	metadata := standin.NewMetadata(map[string]string{
+		"/latest/meta-data/placement/availability-zone": "us-west-2a",
+	})
+	config := fmt.Sprintf("accessKeyID: id\nsecretAccessKey: secret\nendpoint: %s\nmetadataURL: %s\nclusterID: ls5\nmanagePublicPorts: true\n", api.Endpoint(), metadata.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		metadata.Close()
+		t.Fatal(err)
+	}
+	return c.(*Cloud), metadata.Close
+}
+
+func portState(protocol string, from, to int64) *lightsail.InstancePortState {
+	return &lightsail.InstancePortState{
+		Protocol: _aws.String(protocol),
+		FromPort: _aws.Int64(from),
+		ToPort:   _aws.Int64(to),
+		State:    _aws.String(lightsail.PortStateOpen),
+	}
+}
+
+// portStrings returns the open ports of instance as <protocol>/<from>-<to>.
+func portStrings(api *standin.Lightsail, instance string) []string {
+	var ports []string
+	for _, s := range api.PortStates[instance] {
+		ports = append(ports, fmt.Sprintf("%s/%d-%d", *s.Protocol, *s.FromPort, *s.ToPort))
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+func service(name string, typ v1.ServiceType, nodePort int32, sourceRanges ...string) v1.Service {
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1.ServiceSpec{
+			Type:                     typ,
+			Ports:                    []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80, NodePort: nodePort}},
+			LoadBalancerSourceRanges: sourceRanges,
+		},
+	}
+}
+
+func TestPortController(t *testing.T) {
+	api := standin.NewLightsail(
+		testInstance("ls5-master", "172.26.0.10", "34.210.0.10"),
+		testInstance("ls5-node", "172.26.0.11", "34.210.0.11"),
+	)
+	defer api.Close()
+	api.PortStates = map[string][]*lightsail.InstancePortState{
+		// opened by hand
+		"ls5-master": {portState("tcp", 22, 22), portState("tcp", 30443, 30443)},
+		"ls5-node":   {portState("tcp", 22, 22)},
+	}
+	c, closeMetadata := newPortTestCloud(t, api)
+	defer closeMetadata()
+
+	kube := standin.NewKubernetes(
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ls5-master"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}, Spec: v1.NodeSpec{ProviderID: "lightsail://ls5-node"}},
+	)
+	defer kube.Close()
+	kube.Services = []v1.Service{
+		service("web", v1.ServiceTypeLoadBalancer, 30080),
+		service("api", v1.ServiceTypeLoadBalancer, 30443, "0.0.0.0/0"),
+		service("admin", v1.ServiceTypeLoadBalancer, 30090, "203.0.113.0/24"),
+		service("metrics", v1.ServiceTypeNodePort, 30100),
+		service("internal", v1.ServiceTypeClusterIP, 0),
+	}
+
+	// syncing twice changes nothing
+	for i := 0; i < 2; i++ {
+		if err := c.ports.Sync(context.Background(), kube.Client()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"tcp/22-22", "tcp/30080-30080", "tcp/30100-30100", "tcp/30443-30443"}
+	for _, instance := range []string{"ls5-master", "ls5-node"} {
+		if ports := portStrings(api, instance); !reflect.DeepEqual(ports, expected) {
+			t.Errorf("expected ports %v of %s, got %v", expected, instance, ports)
+		}
+	}
+	// the port opened by hand is not recorded
+	for name, want := range map[string]string{"ls5-master": "tcp/30080,tcp/30100", "node": "tcp/30080,tcp/30100,tcp/30443"} {
+		if got := kube.Node(name).Annotations[publicPortsAnnotation]; got != want {
+			t.Errorf("expected node %s to record ports %q, got %q", name, want, got)
+		}
+	}
+
+	// only the ports opened by the controller are closed
+	kube.Services = []v1.Service{service("metrics", v1.ServiceTypeNodePort, 30100)}
+	if err := c.ports.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	for instance, want := range map[string][]string{
+		"ls5-master": {"tcp/22-22", "tcp/30100-30100", "tcp/30443-30443"},
+		"ls5-node":   {"tcp/22-22", "tcp/30100-30100"},
+	} {
+		if ports := portStrings(api, instance); !reflect.DeepEqual(ports, want) {
+			t.Errorf("expected ports %v of %s, got %v", want, instance, ports)
+		}
+	}
+	if got := kube.Node("node").Annotations[publicPortsAnnotation]; got != "tcp/30100" {
+		t.Errorf("expected node to record ports tcp/30100, got %q", got)
+	}
+
+	kube.Services = nil
+	if err := c.ports.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := kube.Node("node").Annotations[publicPortsAnnotation]; found {
+		t.Error("expected annotation to be removed")
+	}
+}
+
+func TestPortControllerDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	api := standin.NewLightsail(testInstance("ls5-master", "172.26.0.10", "34.210.0.10"))
+	defer api.Close()
+	c, closeMetadata := newPortTestCloud(t, api)
+	defer closeMetadata()
+
+	kube := standin.NewKubernetes(v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ls5-master"}})
+	defer kube.Close()
+	kube.Services = []v1.Service{service("web", v1.ServiceTypeLoadBalancer, 30080)}
+
+	if err := c.ports.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.PortStates["ls5-master"]) != 0 || len(kube.Node("ls5-master").Annotations) != 0 {
+		t.Errorf("expected no changes in dry-run mode, got ports %v", api.PortStates)
+	}
+}
@@ -0,0 +1,19 @@
+package lightsail
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestReplay(t *testing.T) {
+	// the AWS session can only load a CA bundle into an *http.Transport
+	os.Unsetenv("AWS_CA_BUNDLE")
+	conformance.Replay(t, ProviderName, "testdata/replay.yaml", func(config []byte) ([]string, error) {
+		tokenSource := &tokenSource{}
+		err := yaml.Unmarshal(config, tokenSource)
+		return []string{tokenSource.AccessKeyID, tokenSource.SecretAccessKey}, err
+	})
+}
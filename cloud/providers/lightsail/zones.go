@@ -11,23 +11,33 @@ import (
 )
 
 const (
-	metadataURL = "http://169.254.169.254/latest/meta-data/"
+	metadataURL  = "http://169.254.169.254/"
+	metadataPath = "latest/meta-data/"
 )
 
 type zones struct {
-	client *lightsail.Lightsail
+	client      *lightsail.Lightsail
+	metadataURL string
 }
 
-func newZones(client *lightsail.Lightsail) cloudprovider.Zones {
-	return zones{client}
+func newZones(client *lightsail.Lightsail, metadataURL string) cloudprovider.Zones {
+	return zones{client, metadataURL}
 }
 
 func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
-	return getZone()
+	return getZone(z.metadataURL)
 }
 
 func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudprovider.Zone, error) {
-	return getZone()
+	id, err := instanceIDFromProviderID(providerID)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	instance, err := instanceByID(z.client, id)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return instanceZone(instance), nil
 }
 
 func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
@@ -35,11 +45,15 @@ func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cl
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
-	return cloudprovider.Zone{Region: String(instance.Location.RegionName), FailureDomain: String(instance.Location.AvailabilityZone)}, nil
+	return instanceZone(instance), nil
+}
+
+func instanceZone(instance *lightsail.Instance) cloudprovider.Zone {
+	return cloudprovider.Zone{Region: String(instance.Location.RegionName), FailureDomain: String(instance.Location.AvailabilityZone)}
 }
 
-func getZone() (cloudprovider.Zone, error) {
-	zone, err := getAvailabilityZone()
+func getZone(metadataURL string) (cloudprovider.Zone, error) {
+	zone, err := getAvailabilityZone(metadataURL)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
@@ -61,7 +75,7 @@ func azToRegion(az string) (string, error) {
 	return region, nil
 }
 
-func getAvailabilityZone() (string, error) {
+func getAvailabilityZone(metadataURL string) (string, error) {
 	zone := "placement/availability-zone"
-	return GetMetadata(zone)
+	return GetMetadata(metadataURL, zone)
 }
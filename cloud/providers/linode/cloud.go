@@ -0,0 +1,113 @@
+package linode
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+)
+
+const (
+	ProviderName = "linode"
+)
+
+type tokenSource struct {
+	Token string `json:"token" yaml:"token"`
+	// Endpoint overrides the base URL of the Linode API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// ClusterID limits the controller to instances tagged with the cluster
+	// tag, see cloud.ClusterTag, and to the NodeBalancers it created for the
+	// cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	// Region is the region of the cluster, e.g. us-east. NodeBalancers are
+	// created in it, or in the region of their first node if it is not set.
+	// The zone of the cluster defaults to the region of the local instance.
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+type Cloud struct {
+	client        *linodeapi.Client
+	instances     cloudprovider.Instances
+	zones         cloudprovider.Zones
+	loadbalancers cloudprovider.LoadBalancer
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+}
+
+func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, linodeapi.DefaultEndpoint)
+	cloudprovider.RegisterCloudProvider(
+		ProviderName,
+		func(config io.Reader) (cloudprovider.Interface, error) {
+			return newCloud(config)
+		})
+}
+
+func newCloud(config io.Reader) (*Cloud, error) {
+	tokenSource := &tokenSource{}
+	contents, err := ioutil.ReadAll(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(contents, tokenSource)
+	if err != nil {
+		return nil, err
+	}
+
+	client := linodeapi.NewClient(tokenSource.Token, tokenSource.Endpoint, cloud.HTTPClient())
+	mutator := cloud.NewMutator(ProviderName, tokenSource.ClusterID)
+	collector := cloud.NewCollector(mutator)
+	lbs := newLoadbalancers(client, mutator, tokenSource.Region)
+	collector.Register(lbs.(cloud.ResourceSource))
+	return &Cloud{
+		client:        client,
+		instances:     newInstances(client, mutator),
+		zones:         newZones(client, tokenSource.Region, tokenSource.ClusterID),
+		loadbalancers: lbs,
+
+		mutator:   mutator,
+		collector: collector,
+	}, nil
+}
+
+func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+}
+
+func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
+	return c.loadbalancers, true
+}
+
+func (c *Cloud) Instances() (cloudprovider.Instances, bool) {
+	return c.instances, true
+}
+
+func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
+	return c.zones, true
+}
+
+func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
+	return nil, false
+}
+
+func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
+	return nil, false
+}
+
+func (c *Cloud) ProviderName() string {
+	return ProviderName
+}
+
+func (c *Cloud) ScrubDNS(nameservers, searches []string) (nsOut, srchOut []string) {
+	return nil, nil
+}
+
+func (c *Cloud) HasClusterID() bool {
+	return true
+}
@@ -0,0 +1,43 @@
+package linode
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\nregion: us-east\n")
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "master",
+				ProviderID: "linode://12345",
+				Type:       "g6-standard-2",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "master"},
+					{Type: v1.NodeInternalIP, Address: "192.168.130.2"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "us-east"},
+			},
+			{
+				Name:       "node-1",
+				ProviderID: "linode://12346",
+				Type:       "g6-standard-4",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "node-1"},
+					{Type: v1.NodeInternalIP, Address: "192.168.130.3"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.11"},
+				},
+				Zone: cloudprovider.Zone{Region: "us-central"},
+			},
+		},
+		MissingName:       "node-2",
+		MissingProviderID: "linode://12347",
+	})
+}
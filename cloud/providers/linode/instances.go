@@ -0,0 +1,167 @@
+package linode
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+)
+
+// privateNetwork is the network of the private IPv4 addresses of instances
+var _, privateNetwork, _ = net.ParseCIDR("192.168.128.0/17")
+
+type instances struct {
+	client  *linodeapi.Client
+	mutator *cloud.Mutator
+}
+
+func newInstances(client *linodeapi.Client, mutator *cloud.Mutator) cloudprovider.Instances {
+	return &instances{client: client, mutator: mutator}
+}
+
+func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
+	instance, err := instanceByName(i.client, i.mutator.ClusterID(), name)
+	if err != nil {
+		return nil, err
+	}
+	return nodeAddresses(instance), nil
+}
+
+func (i *instances) NodeAddressesByProviderID(_ context.Context, providerID string) ([]v1.NodeAddress, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := instanceByID(i.client, id)
+	if err != nil {
+		return nil, err
+	}
+	return nodeAddresses(instance), nil
+}
+
+// nodeAddresses returns the private IPv4 addresses of instance, then its
+// public IPv4 addresses and its public IPv6 address.
+func nodeAddresses(instance *linodeapi.Instance) []v1.NodeAddress {
+	addresses := []v1.NodeAddress{{Type: v1.NodeHostName, Address: instance.Label}}
+	var public []v1.NodeAddress
+	for _, ip := range instance.IPv4 {
+		if isPrivate(ip) {
+			addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: ip})
+		} else {
+			public = append(public, v1.NodeAddress{Type: v1.NodeExternalIP, Address: ip})
+		}
+	}
+	addresses = append(addresses, public...)
+	if instance.IPv6 != "" {
+		ipv6 := strings.SplitN(instance.IPv6, "/", 2)[0]
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: ipv6})
+	}
+	return cloud.NodeAddresses(addresses)
+}
+
+func isPrivate(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && privateNetwork.Contains(ip)
+}
+
+func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
+	return i.InstanceID(ctx, nodeName)
+}
+
+func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
+	instance, err := instanceByName(i.client, i.mutator.ClusterID(), nodeName)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(instance.ID), nil
+}
+
+func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
+	instance, err := instanceByName(i.client, i.mutator.ClusterID(), nodeName)
+	if err != nil {
+		return "", err
+	}
+	return instance.Type, nil
+}
+
+func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID string) (string, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return "", err
+	}
+	instance, err := instanceByID(i.client, id)
+	if err != nil {
+		return "", err
+	}
+	return instance.Type, nil
+}
+
+// AddSSHKeyToAllInstances is not supported, Linode only installs SSH keys
+// when an instance is deployed.
+func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
+	return cloud.ErrNotImplemented
+}
+
+func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
+	return types.NodeName(hostname), nil
+}
+
+func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID string) (bool, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return false, err
+	}
+	_, err = instanceByID(i.client, id)
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// InstanceShutdownByProviderID returns true if the instance is powered off or
+// shutting down.
+func (i *instances) InstanceShutdownByProviderID(_ context.Context, providerID string) (bool, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return false, err
+	}
+	instance, err := instanceByID(i.client, id)
+	if err != nil {
+		return false, err
+	}
+	return instance.Status == linodeapi.InstanceOffline || instance.Status == linodeapi.InstanceShuttingDown, nil
+}
+
+func instanceByID(client *linodeapi.Client, id int64) (*linodeapi.Instance, error) {
+	instance, err := client.GetInstance(int(id))
+	if linodeapi.IsNotFound(err) {
+		return nil, cloudprovider.InstanceNotFound
+	}
+	return instance, err
+}
+
+// instanceByName returns the instance labelled nodeName. If clusterID is not
+// empty, only instances tagged with its cluster tag are considered, so that
+// instances of other clusters with the same label do not collide.
+func instanceByName(client *linodeapi.Client, clusterID string, nodeName types.NodeName) (*linodeapi.Instance, error) {
+	instances, err := client.ListInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, instance := range instances {
+		if clusterID != "" && !cloud.HasClusterTag(instance.Tags, clusterID) {
+			continue
+		}
+		if instance.Label == string(nodeName) {
+			return &instances[i], nil
+		}
+	}
+	return nil, cloudprovider.InstanceNotFound
+}
@@ -0,0 +1,118 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func testInstance(id int, label string, ipv4 []string, tags ...string) linodeapi.Instance {
+	return linodeapi.Instance{
+		ID:     id,
+		Label:  label,
+		Status: linodeapi.InstanceRunning,
+		Type:   "g6-standard-2",
+		Region: "us-east",
+		IPv4:   ipv4,
+		Tags:   tags,
+	}
+}
+
+func testInstances() []linodeapi.Instance {
+	master := testInstance(12345, "master", []string{"203.0.113.10", "192.168.130.2"}, cloud.ClusterTag("prod"))
+	node := testInstance(12346, "node-1", []string{"203.0.113.11", "192.168.130.3"}, cloud.ClusterTag("prod"))
+	node.Type = "g6-standard-4"
+	node.Region = "us-central"
+	return []linodeapi.Instance{master, node}
+}
+
+func newTestCloud(t *testing.T, config string) (*Cloud, *standin.Linode) {
+	api := standin.NewLinode(testInstances()...)
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("token: secret\nendpoint: %s\n%s", api.Endpoint(), config)))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c, api
+}
+
+func TestInstanceByName(t *testing.T) {
+	api := standin.NewLinode(
+		testInstance(1, "master", nil, cloud.ClusterTag("dev")),
+		testInstance(2, "master", nil, cloud.ClusterTag("prod")),
+	)
+	defer api.Close()
+	client := linodeapi.NewClient("secret", api.Endpoint(), nil)
+
+	instance, err := instanceByName(client, "prod", "master")
+	if err != nil || instance.ID != 2 {
+		t.Errorf("expected instance 2 of cluster prod, got %v (%v)", instance, err)
+	}
+	if _, err := instanceByName(client, "staging", "master"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestNodeAddresses(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	api.Instances[0].IPv6 = "2001:db8::f03c:91ff:fe24:3a2f/128"
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "linode://12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "192.168.130.2"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8::f03c:91ff:fe24:3a2f"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+}
+
+func TestInstanceShutdownByProviderID(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+
+	if shutdown, err := c.instances.InstanceShutdownByProviderID(ctx, "linode://12345"); err != nil || shutdown {
+		t.Errorf("expected running instance, got shutdown %v (%v)", shutdown, err)
+	}
+	for _, status := range []string{linodeapi.InstanceShuttingDown, linodeapi.InstanceOffline} {
+		api.Instances[0].Status = status
+		if shutdown, err := c.instances.InstanceShutdownByProviderID(ctx, "linode://12345"); err != nil || !shutdown {
+			t.Errorf("expected %s instance to be shut down, got %v (%v)", status, shutdown, err)
+		}
+	}
+	if _, err := c.instances.InstanceShutdownByProviderID(ctx, "linode://1"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestListPages(t *testing.T) {
+	var instances []linodeapi.Instance
+	for i := 0; i < 1200; i++ {
+		instances = append(instances, testInstance(i+1, fmt.Sprintf("node-%d", i), nil))
+	}
+	api := standin.NewLinode(instances...)
+	defer api.Close()
+
+	listed, err := linodeapi.NewClient("secret", api.Endpoint(), nil).ListInstances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != len(instances) || listed[1199].Label != "node-1199" {
+		t.Errorf("expected %d instances, got %d", len(instances), len(listed))
+	}
+}
@@ -0,0 +1,135 @@
+// Package linodeapi is a minimal client of the Linode API v4, see
+// https://www.linode.com/docs/api. It covers the instances and NodeBalancers
+// used by the linode cloud provider and is shared with the stand-in of the API.
+package linodeapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultEndpoint is the base URL of the Linode API.
+const DefaultEndpoint = "https://api.linode.com/v4/"
+
+// pageSize is the page size of list calls, the maximum of the API
+const pageSize = 500
+
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client authenticating with token. An empty endpoint
+// selects DefaultEndpoint and a nil httpClient http.DefaultClient.
+func NewClient(token, endpoint string, httpClient *http.Client) *Client {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{endpoint: strings.TrimSuffix(endpoint, "/") + "/", token: token, httpClient: httpClient}
+}
+
+// Error is the error returned by the API for a failed call.
+type Error struct {
+	StatusCode int           `json:"-"`
+	Errors     []ErrorReason `json:"errors"`
+}
+
+type ErrorReason struct {
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e *Error) Error() string {
+	var reasons []string
+	for _, r := range e.Errors {
+		if r.Field != "" {
+			reasons = append(reasons, r.Field+": "+r.Reason)
+		} else {
+			reasons = append(reasons, r.Reason)
+		}
+	}
+	return fmt.Sprintf("linode: [%d] %s", e.StatusCode, strings.Join(reasons, "; "))
+}
+
+// IsNotFound returns true if err is the error of a missing resource.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// do sends a request to path, relative to the endpoint, with in encoded as
+// JSON body unless it is nil, and decodes the response into out unless it is
+// nil.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		e := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, e); err != nil || len(e.Errors) == 0 {
+			e.Errors = []ErrorReason{{Reason: resp.Status}}
+		}
+		return e
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// list calls fn with the data of every page of the resources at path.
+func (c *Client) list(path string, fn func(data json.RawMessage) error) error {
+	values := url.Values{}
+	values.Set("page_size", strconv.Itoa(pageSize))
+	for page := 1; ; page++ {
+		values.Set("page", strconv.Itoa(page))
+		var resp struct {
+			Data  json.RawMessage `json:"data"`
+			Page  int             `json:"page"`
+			Pages int             `json:"pages"`
+		}
+		if err := c.do(http.MethodGet, path+"?"+values.Encode(), nil, &resp); err != nil {
+			return err
+		}
+		if err := fn(resp.Data); err != nil {
+			return err
+		}
+		if resp.Page >= resp.Pages {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package linodeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	InstanceRunning      = "running"
+	InstanceOffline      = "offline"
+	InstanceShuttingDown = "shutting_down"
+)
+
+type Instance struct {
+	ID     int    `json:"id"`
+	Label  string `json:"label"`
+	Status string `json:"status"`
+	Type   string `json:"type"`
+	Region string `json:"region"`
+	// IPv4 are the public and private IPv4 addresses of the instance
+	IPv4 []string `json:"ipv4"`
+	// IPv6 is the SLAAC address of the instance with its prefix length, e.g.
+	// 2001:db8::f03c:91ff:fe24:3a2f/128
+	IPv6 string   `json:"ipv6"`
+	Tags []string `json:"tags"`
+}
+
+// ListInstances returns all instances of the account.
+func (c *Client) ListInstances() ([]Instance, error) {
+	var instances []Instance
+	err := c.list("linode/instances", func(data json.RawMessage) error {
+		var page []Instance
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		instances = append(instances, page...)
+		return nil
+	})
+	return instances, err
+}
+
+// GetInstance returns the instance with id. The error of a missing instance
+// satisfies IsNotFound.
+func (c *Client) GetInstance(id int) (*Instance, error) {
+	instance := &Instance{}
+	if err := c.do(http.MethodGet, fmt.Sprintf("linode/instances/%d", id), nil, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
@@ -0,0 +1,140 @@
+package linodeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const ProtocolTCP = "tcp"
+
+type NodeBalancer struct {
+	ID       int      `json:"id"`
+	Label    string   `json:"label"`
+	Region   string   `json:"region"`
+	Hostname string   `json:"hostname"`
+	IPv4     string   `json:"ipv4"`
+	IPv6     string   `json:"ipv6"`
+	Tags     []string `json:"tags"`
+}
+
+// NodeBalancerConfig balances a port of a NodeBalancer across its nodes.
+type NodeBalancerConfig struct {
+	ID             int    `json:"id"`
+	Port           int    `json:"port"`
+	Protocol       string `json:"protocol"`
+	Algorithm      string `json:"algorithm,omitempty"`
+	NodeBalancerID int    `json:"nodebalancer_id"`
+}
+
+// NodeBalancerNode is a backend of a NodeBalancerConfig.
+type NodeBalancerNode struct {
+	ID int `json:"id"`
+	// Address is the private IPv4 address and port of the backend, e.g.
+	// 192.168.128.10:30080
+	Address        string `json:"address"`
+	Label          string `json:"label"`
+	Status         string `json:"status,omitempty"`
+	Weight         int    `json:"weight,omitempty"`
+	Mode           string `json:"mode,omitempty"`
+	ConfigID       int    `json:"config_id"`
+	NodeBalancerID int    `json:"nodebalancer_id"`
+}
+
+type NodeBalancerCreateOptions struct {
+	Region  string                            `json:"region,omitempty"`
+	Label   string                            `json:"label,omitempty"`
+	Tags    []string                          `json:"tags,omitempty"`
+	Configs []NodeBalancerConfigCreateOptions `json:"configs,omitempty"`
+}
+
+// NodeBalancerConfigCreateOptions configure a port of a NodeBalancer. Nodes
+// are only accepted when a NodeBalancer is created or a config is rebuilt.
+type NodeBalancerConfigCreateOptions struct {
+	Port     int                             `json:"port"`
+	Protocol string                          `json:"protocol"`
+	Nodes    []NodeBalancerNodeCreateOptions `json:"nodes,omitempty"`
+}
+
+type NodeBalancerNodeCreateOptions struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+// ListNodeBalancers returns all NodeBalancers of the account.
+func (c *Client) ListNodeBalancers() ([]NodeBalancer, error) {
+	var nbs []NodeBalancer
+	err := c.list("nodebalancers", func(data json.RawMessage) error {
+		var page []NodeBalancer
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		nbs = append(nbs, page...)
+		return nil
+	})
+	return nbs, err
+}
+
+func (c *Client) CreateNodeBalancer(opts NodeBalancerCreateOptions) (*NodeBalancer, error) {
+	nb := &NodeBalancer{}
+	if err := c.do(http.MethodPost, "nodebalancers", opts, nb); err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// SetNodeBalancerTags replaces the tags of the NodeBalancer with id.
+func (c *Client) SetNodeBalancerTags(id int, tags []string) error {
+	return c.do(http.MethodPut, fmt.Sprintf("nodebalancers/%d", id), map[string][]string{"tags": tags}, nil)
+}
+
+func (c *Client) DeleteNodeBalancer(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("nodebalancers/%d", id), nil, nil)
+}
+
+// ListConfigs returns the configs of the NodeBalancer with id.
+func (c *Client) ListConfigs(id int) ([]NodeBalancerConfig, error) {
+	var configs []NodeBalancerConfig
+	err := c.list(fmt.Sprintf("nodebalancers/%d/configs", id), func(data json.RawMessage) error {
+		var page []NodeBalancerConfig
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		configs = append(configs, page...)
+		return nil
+	})
+	return configs, err
+}
+
+// CreateConfig creates a config without nodes, see RebuildConfig.
+func (c *Client) CreateConfig(id int, opts NodeBalancerConfigCreateOptions) (*NodeBalancerConfig, error) {
+	opts.Nodes = nil
+	config := &NodeBalancerConfig{}
+	if err := c.do(http.MethodPost, fmt.Sprintf("nodebalancers/%d/configs", id), opts, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// RebuildConfig updates a config and replaces its nodes with those of opts.
+func (c *Client) RebuildConfig(id, configID int, opts NodeBalancerConfigCreateOptions) error {
+	return c.do(http.MethodPost, fmt.Sprintf("nodebalancers/%d/configs/%d/rebuild", id, configID), opts, nil)
+}
+
+func (c *Client) DeleteConfig(id, configID int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("nodebalancers/%d/configs/%d", id, configID), nil, nil)
+}
+
+// ListNodes returns the nodes of a config of the NodeBalancer with id.
+func (c *Client) ListNodes(id, configID int) ([]NodeBalancerNode, error) {
+	var nodes []NodeBalancerNode
+	err := c.list(fmt.Sprintf("nodebalancers/%d/configs/%d/nodes", id, configID), func(data json.RawMessage) error {
+		var page []NodeBalancerNode
+		if err := json.Unmarshal(data, &page); err != nil {
+			return err
+		}
+		nodes = append(nodes, page...)
+		return nil
+	})
+	return nodes, err
+}
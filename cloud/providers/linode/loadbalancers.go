@@ -0,0 +1,366 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+)
+
+type loadbalancers struct {
+	client  *linodeapi.Client
+	mutator *cloud.Mutator
+	region  string
+}
+
+// newLoadbalancers returns a cloudprovider.LoadBalancer whose concrete type is a *loadbalancer.
+func newLoadbalancers(client *linodeapi.Client, mutator *cloud.Mutator, region string) cloudprovider.LoadBalancer {
+	return &loadbalancers{client: client, mutator: mutator, region: region}
+}
+
+// backend is an instance behind a NodeBalancer.
+type backend struct {
+	label     string
+	region    string
+	privateIP string
+}
+
+// GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
+// *v1.Service parameter as read-only and not modify it.
+func (l *loadbalancers) GetLoadBalancerName(_ context.Context, clusterName string, service *v1.Service) string {
+	return cloudprovider.DefaultLoadBalancerName(service)
+}
+
+// GetLoadBalancer returns the *v1.LoadBalancerStatus of service.
+//
+// GetLoadBalancer will not modify service.
+func (l *loadbalancers) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (*v1.LoadBalancerStatus, bool, error) {
+	nb, err := l.find(l.GetLoadBalancerName(ctx, clusterName, service))
+	if err != nil || nb == nil {
+		return nil, false, err
+	}
+	return lbStatus(nb), true, nil
+}
+
+// EnsureLoadBalancer ensures that the cluster is running a NodeBalancer for
+// service, balancing its ports across their NodePorts on the instances of
+// nodes.
+//
+// EnsureLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) EnsureLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	backends, err := l.backends(nodes)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := nbConfigs(service, backends)
+	if err != nil {
+		return nil, err
+	}
+
+	nb, err := l.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if nb != nil {
+		if err := l.syncConfigs(service, nb, configs); err != nil {
+			return nil, err
+		}
+		return lbStatus(nb), nil
+	}
+
+	opts := linodeapi.NodeBalancerCreateOptions{Region: l.region, Label: name, Configs: configs}
+	if opts.Region == "" && len(backends) > 0 {
+		opts.Region = backends[0].region
+	}
+	if l.mutator.ClusterID() != "" {
+		opts.Tags = []string{cloud.ClusterTag(l.mutator.ClusterID())}
+	}
+	err = l.mutator.Do(service, "create nodebalancer", cloud.Params{"label": name, "region": opts.Region, "cluster": l.mutator.ClusterID()}, func() error {
+		nb, err = l.client.CreateNodeBalancer(opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if nb == nil {
+		// dry-run mode
+		return &v1.LoadBalancerStatus{}, nil
+	}
+	log.Infof("%s: created nodebalancer %s", ProviderName, name)
+	return lbStatus(nb), nil
+}
+
+// UpdateLoadBalancer updates the NodeBalancer for service to balance across
+// the instances of nodes.
+//
+// UpdateLoadBalancer will not modify service or nodes.
+func (l *loadbalancers) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	backends, err := l.backends(nodes)
+	if err != nil {
+		return err
+	}
+	configs, err := nbConfigs(service, backends)
+	if err != nil {
+		return err
+	}
+	nb, err := l.find(name)
+	if err != nil {
+		return err
+	}
+	if nb == nil {
+		return fmt.Errorf("nodebalancer %s not found", name)
+	}
+	return l.syncConfigs(service, nb, configs)
+}
+
+// EnsureLoadBalancerDeleted deletes the specified loadbalancer if it exists.
+// nil is returned if the load balancer for service does not exist or is
+// successfully deleted.
+//
+// EnsureLoadBalancerDeleted will not modify service.
+func (l *loadbalancers) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) error {
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	nb, err := l.find(name)
+	if err != nil || nb == nil {
+		return err
+	}
+	err = l.mutator.Destroy(service, "delete nodebalancer", cloud.Params{"label": name, "id": strconv.Itoa(nb.ID), "cluster": l.mutator.ClusterID()}, func() error {
+		return l.client.DeleteNodeBalancer(nb.ID)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: deleted nodebalancer %s", ProviderName, name)
+	return nil
+}
+
+// ListOwned lists the NodeBalancers tagged with cluster clusterID, so that
+// the NodeBalancers of deleted Services are collected.
+func (l *loadbalancers) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	nbs, err := l.client.ListNodeBalancers()
+	if err != nil {
+		return nil, err
+	}
+	var owned []cloud.OwnedResource
+	for _, nb := range nbs {
+		if cloud.HasClusterTag(nb.Tags, clusterID) {
+			owned = append(owned, cloud.OwnedResource{Kind: "nodebalancer", ID: strconv.Itoa(nb.ID), LoadBalancerName: nb.Label})
+		}
+	}
+	return owned, nil
+}
+
+func (l *loadbalancers) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	id, err := strconv.Atoi(resource.ID)
+	if err != nil {
+		return err
+	}
+	err = l.client.DeleteNodeBalancer(id)
+	if linodeapi.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// find returns the NodeBalancer labelled name of the cluster, or nil if there
+// is none. A NodeBalancer without cluster tag, created before the cluster ID
+// was configured, is tagged with the cluster and returned.
+func (l *loadbalancers) find(name string) (*linodeapi.NodeBalancer, error) {
+	nbs, err := l.client.ListNodeBalancers()
+	if err != nil {
+		return nil, err
+	}
+	clusterID := l.mutator.ClusterID()
+	for i := range nbs {
+		nb := &nbs[i]
+		if nb.Label != name {
+			continue
+		}
+		if clusterID == "" || cloud.HasClusterTag(nb.Tags, clusterID) {
+			return nb, nil
+		}
+		if _, tagged := cloud.NewClusterMember(nb.Label, nb.Tags, ""); tagged {
+			// the NodeBalancer of another cluster
+			continue
+		}
+
+		tags := append([]string{cloud.ClusterTag(clusterID)}, nb.Tags...)
+		err := l.mutator.Do(nil, "tag nodebalancer", cloud.Params{"label": name, "id": strconv.Itoa(nb.ID), "cluster": clusterID}, func() error {
+			return l.client.SetNodeBalancerTags(nb.ID, tags)
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("%s: tagged nodebalancer %s with cluster %s", ProviderName, name, clusterID)
+		nb.Tags = tags
+		return nb, nil
+	}
+	return nil, nil
+}
+
+// backends returns the instances of nodes, found by provider ID or, if it is
+// not set, by name. NodeBalancers reach their backends by private IP, so every
+// instance needs one.
+func (l *loadbalancers) backends(nodes []*v1.Node) ([]backend, error) {
+	var backends []backend
+	for _, node := range nodes {
+		var instance *linodeapi.Instance
+		if node.Spec.ProviderID != "" {
+			id, err := cloud.NumericIDFromProviderID(ProviderName, node.Spec.ProviderID)
+			if err != nil {
+				return nil, err
+			}
+			if instance, err = instanceByID(l.client, id); err != nil {
+				return nil, fmt.Errorf("node %s: %v", node.Name, err)
+			}
+		} else {
+			var err error
+			if instance, err = instanceByName(l.client, l.mutator.ClusterID(), types.NodeName(node.Name)); err != nil {
+				return nil, fmt.Errorf("node %s: %v", node.Name, err)
+			}
+		}
+
+		b := backend{label: instance.Label, region: instance.Region}
+		for _, ip := range instance.IPv4 {
+			if isPrivate(ip) {
+				b.privateIP = ip
+				break
+			}
+		}
+		if b.privateIP == "" {
+			return nil, fmt.Errorf("node %s: instance %d has no private IP to be reached by nodebalancers", node.Name, instance.ID)
+		}
+		backends = append(backends, b)
+	}
+	return backends, nil
+}
+
+// syncConfigs makes the configs of nb balance the ports of desired across
+// their nodes. Configs are rebuilt if their protocol or nodes changed.
+func (l *loadbalancers) syncConfigs(service *v1.Service, nb *linodeapi.NodeBalancer, desired []linodeapi.NodeBalancerConfigCreateOptions) error {
+	configs, err := l.client.ListConfigs(nb.ID)
+	if err != nil {
+		return err
+	}
+	byPort := map[int]linodeapi.NodeBalancerConfig{}
+	for _, config := range configs {
+		byPort[config.Port] = config
+	}
+
+	ports := map[int]bool{}
+	for _, opts := range desired {
+		opts := opts
+		ports[opts.Port] = true
+		params := cloud.Params{"label": nb.Label, "port": strconv.Itoa(opts.Port), "nodes": backendAddresses(opts.Nodes)}
+		config, found := byPort[opts.Port]
+		if !found {
+			err := l.mutator.Do(service, "create nodebalancer config", params, func() error {
+				created, err := l.client.CreateConfig(nb.ID, opts)
+				if err != nil {
+					return err
+				}
+				return l.client.RebuildConfig(nb.ID, created.ID, opts)
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		nodes, err := l.client.ListNodes(nb.ID, config.ID)
+		if err != nil {
+			return err
+		}
+		current := make([]linodeapi.NodeBalancerNodeCreateOptions, 0, len(nodes))
+		for _, node := range nodes {
+			current = append(current, linodeapi.NodeBalancerNodeCreateOptions{Address: node.Address, Label: node.Label})
+		}
+		sortNodes(current)
+		if config.Protocol == opts.Protocol && reflect.DeepEqual(current, opts.Nodes) {
+			continue
+		}
+		err = l.mutator.Do(service, "rebuild nodebalancer config", params, func() error {
+			return l.client.RebuildConfig(nb.ID, config.ID, opts)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, config := range configs {
+		if ports[config.Port] {
+			continue
+		}
+		config := config
+		err := l.mutator.Destroy(service, "delete nodebalancer config", cloud.Params{"label": nb.Label, "port": strconv.Itoa(config.Port)}, func() error {
+			return l.client.DeleteConfig(nb.ID, config.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nbConfigs returns the NodeBalancer configs balancing the ports of service
+// across their NodePorts on backends. NodeBalancers are only used for TCP.
+func nbConfigs(service *v1.Service, backends []backend) ([]linodeapi.NodeBalancerConfigCreateOptions, error) {
+	var configs []linodeapi.NodeBalancerConfigCreateOptions
+	for _, port := range service.Spec.Ports {
+		if port.Protocol != v1.ProtocolTCP {
+			return nil, fmt.Errorf("port %d of service %s/%s: protocol %s is not supported, only TCP", port.Port, service.Namespace, service.Name, port.Protocol)
+		}
+		nodes := make([]linodeapi.NodeBalancerNodeCreateOptions, 0, len(backends))
+		for _, b := range backends {
+			nodes = append(nodes, linodeapi.NodeBalancerNodeCreateOptions{
+				Address: fmt.Sprintf("%s:%d", b.privateIP, port.NodePort),
+				Label:   b.label,
+			})
+		}
+		sortNodes(nodes)
+		configs = append(configs, linodeapi.NodeBalancerConfigCreateOptions{
+			Port:     int(port.Port),
+			Protocol: linodeapi.ProtocolTCP,
+			Nodes:    nodes,
+		})
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Port < configs[j].Port
+	})
+	return configs, nil
+}
+
+func sortNodes(nodes []linodeapi.NodeBalancerNodeCreateOptions) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Address < nodes[j].Address
+	})
+}
+
+func backendAddresses(nodes []linodeapi.NodeBalancerNodeCreateOptions) string {
+	var addresses []string
+	for _, node := range nodes {
+		addresses = append(addresses, node.Address)
+	}
+	return strings.Join(addresses, ",")
+}
+
+func lbStatus(nb *linodeapi.NodeBalancer) *v1.LoadBalancerStatus {
+	status := &v1.LoadBalancerStatus{}
+	for _, ip := range []string{nb.IPv4, nb.IPv6} {
+		if ip != "" {
+			status.Ingress = append(status.Ingress, v1.LoadBalancerIngress{IP: ip})
+		}
+	}
+	return status
+}
@@ -0,0 +1,209 @@
+package linode
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+// testLBName is the NodeBalancer label of the loadBalancerService, see
+// cloudprovider.DefaultLoadBalancerName
+const testLBName = "a9f2b7c1e0d3a4b5c8e6f7a8b9c0d1e2"
+
+func loadBalancerService(ports ...v1.ServicePort) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: metav1.NamespaceDefault, UID: types.UID("9f2b7c1e-0d3a-4b5c-8e6f-7a8b9c0d1e2f")},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer, Ports: ports},
+	}
+}
+
+func testNodes() []*v1.Node {
+	return []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}, Spec: v1.NodeSpec{ProviderID: "linode://12345"}},
+		// found by name
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	}
+}
+
+// nbConfigPorts returns the ports of the configs of the NodeBalancer nbID,
+// each with the addresses of its nodes.
+func nbConfigPorts(api *standin.Linode, nbID int) map[int][]string {
+	ports := map[int][]string{}
+	for _, config := range api.NodeBalancerConfigs[nbID] {
+		addresses := []string{}
+		for _, node := range api.NodeBalancerNodes[config.ID] {
+			addresses = append(addresses, node.Address)
+		}
+		ports[config.Port] = addresses
+	}
+	return ports
+}
+
+func TestEnsureLoadBalancer(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	ctx := context.Background()
+
+	service := loadBalancerService(
+		v1.ServicePort{Name: "https", Protocol: v1.ProtocolTCP, Port: 443, NodePort: 30443},
+		v1.ServicePort{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080},
+	)
+	status, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "198.51.100.1"}, {IP: "2001:db8:100::1"}}}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("expected status %v, got %v", expected, status)
+	}
+	if len(api.NodeBalancers) != 1 {
+		t.Fatalf("expected 1 nodebalancer, got %v", api.NodeBalancers)
+	}
+	nb := api.NodeBalancers[0]
+	if nb.Label != testLBName || nb.Region != "us-east" || !cloud.HasClusterTag(nb.Tags, "prod") {
+		t.Errorf("unexpected nodebalancer %+v", nb)
+	}
+	ports := map[int][]string{
+		80:  {"192.168.130.2:30080", "192.168.130.3:30080"},
+		443: {"192.168.130.2:30443", "192.168.130.3:30443"},
+	}
+	if actual := nbConfigPorts(api, nb.ID); !reflect.DeepEqual(actual, ports) {
+		t.Errorf("expected configs %v, got %v", ports, actual)
+	}
+
+	// changed ports and nodes are synced
+	service.Spec.Ports = []v1.ServicePort{
+		{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30081},
+		{Name: "ssh", Protocol: v1.ProtocolTCP, Port: 22, NodePort: 30022},
+	}
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()[1:]); err != nil {
+		t.Fatal(err)
+	}
+	ports = map[int][]string{
+		22: {"192.168.130.3:30022"},
+		80: {"192.168.130.3:30081"},
+	}
+	if actual := nbConfigPorts(api, nb.ID); !reflect.DeepEqual(actual, ports) {
+		t.Errorf("expected configs %v, got %v", ports, actual)
+	}
+
+	// unchanged configs are not rebuilt
+	nodeID := api.NodeBalancerNodes[api.NodeBalancerConfigs[nb.ID][0].ID][0].ID
+	if err := c.loadbalancers.UpdateLoadBalancer(ctx, "prod", service, testNodes()[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if id := api.NodeBalancerNodes[api.NodeBalancerConfigs[nb.ID][0].ID][0].ID; id != nodeID {
+		t.Errorf("expected node %d to be kept, got %d", nodeID, id)
+	}
+
+	service.Spec.Ports = append(service.Spec.Ports, v1.ServicePort{Name: "dns", Protocol: v1.ProtocolUDP, Port: 53, NodePort: 30053})
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err == nil {
+		t.Error("expected error for UDP port")
+	}
+}
+
+func TestEnsureLoadBalancerWithoutPrivateIP(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	api.Instances[1].IPv4 = []string{"203.0.113.11"}
+
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	if _, err := c.loadbalancers.EnsureLoadBalancer(context.Background(), "prod", service, testNodes()); err == nil {
+		t.Error("expected error for instance without private IP")
+	}
+	if len(api.NodeBalancers) != 0 {
+		t.Errorf("expected no nodebalancer, got %v", api.NodeBalancers)
+	}
+}
+
+func TestLoadBalancerOfOtherCluster(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\nregion: eu-west\n")
+	defer api.Close()
+	ctx := context.Background()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	api.NodeBalancers = []linodeapi.NodeBalancer{{ID: 1, Label: testLBName, Tags: []string{cloud.ClusterTag("staging")}}}
+
+	if _, exists, err := c.loadbalancers.GetLoadBalancer(ctx, "prod", service); err != nil || exists {
+		t.Errorf("expected nodebalancer of another cluster to be ignored, got %v (%v)", exists, err)
+	}
+	if err := c.loadbalancers.EnsureLoadBalancerDeleted(ctx, "prod", service); err != nil || len(api.NodeBalancers) != 1 {
+		t.Errorf("expected nodebalancer of another cluster to be kept, got %v (%v)", api.NodeBalancers, err)
+	}
+	// its label is taken
+	api.NodeBalancers[0].Label = "other"
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err != nil {
+		t.Fatal(err)
+	}
+	if nb := api.NodeBalancers[1]; nb.Region != "eu-west" {
+		t.Errorf("expected configured region, got %+v", nb)
+	}
+}
+
+func TestUntaggedLoadBalancer(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\nregion: us-east\n")
+	defer api.Close()
+	ctx := context.Background()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	// created before the cluster ID was configured
+	api.NodeBalancers = []linodeapi.NodeBalancer{{ID: 1, Label: testLBName, Region: "us-east", Tags: []string{"web"}}}
+
+	if _, err := c.loadbalancers.EnsureLoadBalancer(ctx, "prod", service, testNodes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.NodeBalancers) != 1 {
+		t.Fatalf("expected the nodebalancer to be reused, got %v", api.NodeBalancers)
+	}
+	if expected := []string{cloud.ClusterTag("prod"), "web"}; !reflect.DeepEqual(api.NodeBalancers[0].Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, api.NodeBalancers[0].Tags)
+	}
+}
+
+func TestCollectLoadBalancers(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	api.NodeBalancers = []linodeapi.NodeBalancer{
+		{ID: 1, Label: testLBName, Tags: []string{cloud.ClusterTag("prod")}},
+		{ID: 2, Label: "aorphaned", Tags: []string{cloud.ClusterTag("prod")}},
+		{ID: 3, Label: "astaging", Tags: []string{cloud.ClusterTag("staging")}},
+	}
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: cloud.DefaultGCOptions.Interval}); err != nil {
+		t.Fatal(err)
+	}
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c.loadbalancers.(cloud.ResourceSource))
+	if err := collector.Collect(context.Background(), []v1.Service{*loadBalancerService()}); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, nb := range api.NodeBalancers {
+		labels = append(labels, nb.Label)
+	}
+	if expected := []string{testLBName, "astaging"}; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected nodebalancers %v, got %v", expected, labels)
+	}
+}
+
+func TestEnsureLoadBalancerDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	service := loadBalancerService(v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080})
+	status, err := c.loadbalancers.EnsureLoadBalancer(context.Background(), "prod", service, testNodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Ingress) != 0 || len(api.NodeBalancers) != 0 {
+		t.Errorf("expected no nodebalancer in dry-run mode, got %v", api.NodeBalancers)
+	}
+}
@@ -0,0 +1,61 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+)
+
+type zones struct {
+	client    *linodeapi.Client
+	region    string
+	clusterID string
+	// hostname returns the name of the local instance
+	hostname func() (string, error)
+}
+
+func newZones(client *linodeapi.Client, region, clusterID string) cloudprovider.Zones {
+	return zones{client, region, clusterID, os.Hostname}
+}
+
+// GetZone returns the configured region of the cluster or, if it is not
+// configured, the region of the local instance, found by hostname.
+func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
+	if z.region != "" {
+		return cloudprovider.Zone{Region: z.region}, nil
+	}
+	hostname, err := z.hostname()
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	instance, err := instanceByName(z.client, z.clusterID, types.NodeName(hostname))
+	if err != nil {
+		return cloudprovider.Zone{}, fmt.Errorf("region is not configured and local instance %s not found: %v", hostname, err)
+	}
+	return cloudprovider.Zone{Region: instance.Region}, nil
+}
+
+func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudprovider.Zone, error) {
+	id, err := cloud.NumericIDFromProviderID(ProviderName, providerID)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	instance, err := instanceByID(z.client, id)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return cloudprovider.Zone{Region: instance.Region}, nil
+}
+
+func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
+	instance, err := instanceByName(z.client, z.clusterID, nodeName)
+	if err != nil {
+		return cloudprovider.Zone{}, err
+	}
+	return cloudprovider.Zone{Region: instance.Region}, nil
+}
@@ -0,0 +1,25 @@
+package linode
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetZone(t *testing.T) {
+	c, api := newTestCloud(t, "clusterID: prod\n")
+	defer api.Close()
+	z := c.zones.(zones)
+
+	z.hostname = func() (string, error) { return "node-1", nil }
+	if zone, err := z.GetZone(context.Background()); err != nil || zone.Region != "us-central" {
+		t.Errorf("expected region of the local instance, got %v (%v)", zone, err)
+	}
+	z.hostname = func() (string, error) { return "missing", nil }
+	if zone, err := z.GetZone(context.Background()); err == nil {
+		t.Errorf("expected error without region and local instance, got %v", zone)
+	}
+	z.region = "eu-west"
+	if zone, err := z.GetZone(context.Background()); err != nil || zone.Region != "eu-west" {
+		t.Errorf("expected configured region, got %v (%v)", zone, err)
+	}
+}
@@ -7,16 +7,26 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/packethost/packngo"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
 const (
 	ProviderName = "packet"
+	apiEndpoint  = "https://api.packet.net/"
 )
 
 type credential struct {
 	Project string `json:"project" yaml:"project"`
 	ApiKey  string `json:"apiKey" yaml:"apiKey"`
 	Zone    string `json:"zone" yaml:"zone"`
+	// Endpoint overrides the base URL of the Packet API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// ClusterID limits the controller to devices tagged with the cluster tag,
+	// see cloud.ClusterTag, and to the resources it created for the cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+
+	spotOptions
+	eventOptions
 }
 
 type Cloud struct {
@@ -24,9 +34,17 @@ type Cloud struct {
 	instances     cloudprovider.Instances
 	zones         cloudprovider.Zones
 	loadbalancers cloudprovider.LoadBalancer
+	clusters      cloudprovider.Clusters
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+	labeler   *cloud.NodeLabeler
+	spot      *spotController
+	events    *eventWatcher
 }
 
 func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, apiEndpoint)
 	cloudprovider.RegisterCloudProvider(
 		ProviderName,
 		func(config io.Reader) (cloudprovider.Interface, error) {
@@ -46,17 +64,38 @@ func newCloud(config io.Reader) (*Cloud, error) {
 		return nil, err
 	}
 
-	packetClient := packngo.NewClientWithAuth("", packet.ApiKey, nil)
+	endpoint := packet.Endpoint
+	if endpoint == "" {
+		endpoint = apiEndpoint
+	}
+	packetClient, err := packngo.NewClientWithBaseURL("", packet.ApiKey, cloud.HTTPClient(), endpoint)
+	if err != nil {
+		return nil, err
+	}
 
+	mutator := cloud.NewMutator(ProviderName, packet.ClusterID)
+	collector := cloud.NewCollector(mutator)
 	return &Cloud{
 		client:        packetClient,
-		instances:     newInstances(packetClient, packet.Project),
-		zones:         newZones(packetClient, packet.Project, packet.Zone),
+		instances:     newInstances(packetClient, packet.Project, mutator),
+		zones:         newZones(packetClient, packet.Project, packet.Zone, packet.ClusterID),
 		loadbalancers: newLoadbalancers(packetClient),
+		clusters:      cloud.NewClusters(listMembers(packetClient, packet.Project)),
+
+		mutator:   mutator,
+		collector: collector,
+		labeler:   cloud.NewNodeLabeler(ProviderName, nodeLabels(packetClient, packet.Project, packet.ClusterID)),
+		spot:      newSpotController(packetClient, packet.Project, packet.ClusterID, packet.spotOptions),
+		events:    newEventWatcher(packetClient, packet.Project, packet.ClusterID, packet.eventOptions),
 	}, nil
 }
 
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+	c.labeler.Start(clientBuilder, stop)
+	c.spot.Start(clientBuilder, stop)
+	c.events.Start(clientBuilder, stop)
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
@@ -72,7 +111,7 @@ func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
 }
 
 func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
-	return nil, false
+	return c.clusters, true
 }
 
 func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
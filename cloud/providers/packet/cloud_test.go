@@ -1,7 +1,322 @@
 package packet
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+const testProject = "93125c2a-8b78-4d4f-a3c4-7367d6b7cca8"
+
+func ipAddress(address string, family int, public bool) *packngo.IPAddressAssignment {
+	ip := &packngo.IPAddressAssignment{}
+	ip.Address = address
+	ip.AddressFamily = family
+	ip.Public = public
+	return ip
+}
+
+func testDevices() []packngo.Device {
+	project := &packngo.Project{ID: testProject}
+	return []packngo.Device{
+		{
+			ID:       "e123s",
+			Hostname: "master",
+			Plan:     &packngo.Plan{Slug: "baremetal_0"},
+			Facility: &packngo.Facility{ID: "ewr1", Code: "ewr1"},
+			Project:  project,
+			Network: []*packngo.IPAddressAssignment{
+				ipAddress("147.75.0.10", 4, true),
+				ipAddress("10.99.0.10", 4, false),
+			},
+		},
+		{
+			ID:       "other",
+			Hostname: "node-1",
+			Facility: &packngo.Facility{ID: "ams1", Code: "ams1"},
+			Project:  &packngo.Project{ID: "another-project"},
+		},
+	}
+}
+
+func newTestCloud(t *testing.T) (*Cloud, *standin.Packet) {
+	api := standin.NewPacket(testDevices()...)
+	config := fmt.Sprintf("project: %s\napiKey: secret\nzone: ewr1\nendpoint: %s\n", testProject, api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c, api
+}
 
 func TestCred(t *testing.T) {
-	//config := `p2-`
+	c, err := newCloud(strings.NewReader("project: p\napiKey: secret\nzone: ewr1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.client.BaseURL.String() != apiEndpoint {
+		t.Errorf("expected default endpoint %s, got %s", apiEndpoint, c.client.BaseURL)
+	}
+}
+
+func TestDeviceByName(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	device, err := deviceByName(c.client, testProject, "", "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.ID != "e123s" {
+		t.Errorf("expected device e123s, got %s", device.ID)
+	}
+
+	// devices of other projects are not visible
+	if _, err := deviceByName(c.client, testProject, "", "node-1"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	addresses, err := c.instances.NodeAddresses(ctx, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "147.75.0.10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	instanceType, err := c.instances.InstanceTypeByProviderID(ctx, "packet://e123s")
+	if err != nil || instanceType != "baremetal_0" {
+		t.Errorf("expected instance type baremetal_0, got %q (%v)", instanceType, err)
+	}
+
+	zone, err := c.zones.GetZoneByProviderID(ctx, "packet://e123s")
+	if err != nil || zone.Region != "ewr1" {
+		t.Errorf("expected region ewr1, got %q (%v)", zone.Region, err)
+	}
+
+	exists, err := c.instances.InstanceExistsByProviderID(ctx, "packet://missing")
+	if err != nil || exists {
+		t.Errorf("expected packet://missing to not exist, got %v (%v)", exists, err)
+	}
+}
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOyiAiEqGOnlV2yPeHsqMmGnUhpcNTROCwsmk0vxihM+ alice@example.com"
+
+func TestAddSSHKeyToAllInstances(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	// the same key in another project must not count
+	api.SSHKeys["other"] = []packngo.SSHKey{{ID: "key-0", Label: "alice", Key: testSSHKey}}
+
+	for i := 0; i < 2; i++ {
+		if err := c.instances.AddSSHKeyToAllInstances(ctx, "alice", []byte(testSSHKey)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := api.SSHKeys[testProject]
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 ssh key in project %s, got %v", testProject, keys)
+	}
+	if !strings.HasPrefix(keys[0].Label, "alice-") || !strings.HasPrefix(testSSHKey, keys[0].Key) {
+		t.Errorf("unexpected ssh key %v", keys[0])
+	}
+}
+
+func TestClusters(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	project := &packngo.Project{ID: testProject}
+	api.Devices = append(api.Devices,
+		packngo.Device{
+			ID: "p1", Hostname: "prod-a", Project: project,
+			Tags: []string{cloud.ClusterTag("prod"), cloud.MasterTag},
+			Network: []*packngo.IPAddressAssignment{
+				ipAddress("10.99.0.20", 4, false),
+				ipAddress("147.75.0.20", 4, true),
+			},
+		},
+		packngo.Device{
+			ID: "p2", Hostname: "prod-b", Project: project,
+			Tags: []string{cloud.ClusterTag("prod")},
+		},
+		// devices of other projects are not visible
+		packngo.Device{
+			ID: "d1", Hostname: "dev-master", Project: &packngo.Project{ID: "another-project"},
+			Tags: []string{cloud.ClusterTag("dev")},
+		},
+	)
+	clusters, ok := c.Clusters()
+	if !ok {
+		t.Fatal("expected clusters support")
+	}
+
+	names, err := clusters.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"prod"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, names)
+	}
+	master, err := clusters.Master(ctx, "prod")
+	if err != nil || master != "147.75.0.20" {
+		t.Errorf("expected master 147.75.0.20, got %q (%v)", master, err)
+	}
+	if _, err := clusters.Master(ctx, "dev"); err == nil {
+		t.Error("expected error for cluster of another project")
+	}
+}
+
+func TestClusterID(t *testing.T) {
+	project := &packngo.Project{ID: testProject}
+	api := standin.NewPacket(
+		packngo.Device{ID: "d1", Hostname: "master", Project: project, Tags: []string{cloud.ClusterTag("dev")}},
+		packngo.Device{ID: "p1", Hostname: "master", Project: project, Tags: []string{"db", cloud.ClusterTag("prod")}},
+	)
+	defer api.Close()
+	ctx := context.Background()
+
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("project: %s\napiKey: secret\nendpoint: %s\nclusterID: prod\n", testProject, api.Endpoint())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.mutator.ClusterID() != "prod" {
+		t.Error("expected cluster ID to be configured")
+	}
+	if id, err := c.instances.InstanceID(ctx, "master"); err != nil || id != "p1" {
+		t.Errorf("expected instance id p1, got %q (%v)", id, err)
+	}
+
+	c, api = newTestCloud(t)
+	defer api.Close()
+	if c.mutator.ClusterID() != "" {
+		t.Error("expected no cluster ID")
+	}
+}
+
+func TestNodeLabels(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+	labels := nodeLabels(c.client, testProject, "")
+
+	expected := map[string]string{
+		"packet.pharmer.dev/facility":             "ewr1",
+		"packet.pharmer.dev/spot-instance":        "false",
+		"packet.pharmer.dev/hardware-reservation": "false",
+	}
+	for _, node := range []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "renamed"}, Spec: v1.NodeSpec{ProviderID: "packet://e123s"}},
+	} {
+		got, err := labels(ctx, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("node %s: expected labels %v, got %v", node.Name, expected, got)
+		}
+	}
+
+	if _, err := labels(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestDualStackAddresses(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	api.Devices[0].Network = append(api.Devices[0].Network,
+		ipAddress("2604:1380::10", 6, true),
+		ipAddress("fd00::10", 6, false),
+	)
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "packet://e123s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "147.75.0.10"},
+		{Type: v1.NodeInternalIP, Address: "fd00::10"},
+		{Type: v1.NodeExternalIP, Address: "2604:1380::10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+}
+
+func TestMultiHomedAddresses(t *testing.T) {
+	defer cloud.SetAddressOptions(cloud.DefaultAddressOptions)
+
+	c, api := newTestCloud(t)
+	defer api.Close()
+	management := func(ip *packngo.IPAddressAssignment) *packngo.IPAddressAssignment {
+		ip.Management = true
+		return ip
+	}
+	api.Devices[0].Network = []*packngo.IPAddressAssignment{
+		ipAddress("147.75.100.20", 4, true),
+		ipAddress("10.99.1.10", 4, false),
+		management(ipAddress("147.75.0.10", 4, true)),
+		ipAddress("147.75.100.5", 4, true),
+		management(ipAddress("10.99.0.10", 4, false)),
+	}
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "packet://e123s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "147.75.0.10"},
+		{Type: v1.NodeInternalIP, Address: "10.99.1.10"},
+		{Type: v1.NodeExternalIP, Address: "147.75.100.20"},
+		{Type: v1.NodeExternalIP, Address: "147.75.100.5"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	// exclude the elastic IPs of load balancers
+	cidrs, err := cloud.ParseCIDRs([]string{"147.75.100.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cloud.SetAddressOptions(cloud.AddressOptions{Families: cloud.DefaultAddressOptions.Families, ExcludeCIDRs: cidrs}); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = c.instances.NodeAddressesByProviderID(context.Background(), "packet://e123s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(addresses, expected[:4]) {
+		t.Errorf("expected addresses %v, got %v", expected[:4], addresses)
+	}
 }
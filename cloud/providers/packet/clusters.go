@@ -0,0 +1,42 @@
+package packet
+
+import (
+	"context"
+
+	"github.com/packethost/packngo"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// listMembers lists the devices of the project that belong to a cluster.
+func listMembers(client *packngo.Client, projectID string) cloud.ListMembersFunc {
+	return func(_ context.Context, _ string) ([]cloud.ClusterMember, error) {
+		devices, _, err := client.Devices.List(projectID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var members []cloud.ClusterMember
+		for _, device := range devices {
+			if member, ok := cloud.NewClusterMember(device.Hostname, device.Tags, deviceAddress(&device)); ok {
+				members = append(members, member)
+			}
+		}
+		return members, nil
+	}
+}
+
+// deviceAddress returns the public IPv4 address of device, or its private one
+// if it has no public address.
+func deviceAddress(device *packngo.Device) string {
+	var address string
+	for _, addr := range device.Network {
+		if addr.AddressFamily != 4 {
+			continue
+		}
+		if addr.Public {
+			return addr.Address
+		}
+		address = addr.Address
+	}
+	return address
+}
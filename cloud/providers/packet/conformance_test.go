@@ -0,0 +1,33 @@
+package packet
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "master",
+				ProviderID: "packet://e123s",
+				Type:       "baremetal_0",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "master"},
+					{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+					{Type: v1.NodeExternalIP, Address: "147.75.0.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "ewr1"},
+			},
+		},
+		// devices of other projects are not part of the inventory
+		MissingName:       "node-1",
+		MissingProviderID: "packet://missing",
+	})
+}
@@ -0,0 +1,329 @@
+package packet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appscode/go/log"
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+const (
+	// eventInterval is the time between two polls of the device events
+	eventInterval = time.Minute
+	// eventWindow is the time a device event keeps its Node condition active
+	eventWindow = 24 * time.Hour
+	// maintenanceWindow is the time a scheduled maintenance keeps its Node
+	// condition active until it is completed or cancelled, as it may be
+	// scheduled days after its event
+	maintenanceWindow = 14 * 24 * time.Hour
+	// eventPageSize is the number of events polled per page, pages are polled
+	// until their events are older than eventWindow
+	eventPageSize = 100
+	// eventMaxPages bounds the pages polled at once
+	eventMaxPages = 50
+)
+
+// deviceEventRule maps the device events of the given types to a Node event
+// reason and, if condition is set, to a Node condition, which the events set
+// or, if clears is set, clear.
+type deviceEventRule struct {
+	types     []string
+	reason    string
+	condition v1.NodeConditionType
+	clears    bool
+	// window is the time the condition stays active after the event unless
+	// it is cleared, eventWindow if it is zero
+	window time.Duration
+}
+
+// deviceEventRules map the device event types, other events are ignored. A
+// maintenance condition is cleared once the maintenance is completed or
+// cancelled.
+var deviceEventRules = []deviceEventRule{
+	{types: []string{"maintenance.scheduled"}, reason: "MaintenanceScheduled", condition: "MaintenanceScheduled", window: maintenanceWindow},
+	{types: []string{"maintenance.completed"}, reason: "MaintenanceCompleted", condition: "MaintenanceScheduled", clears: true},
+	{types: []string{"maintenance.cancelled", "maintenance.canceled"}, reason: "MaintenanceCancelled", condition: "MaintenanceScheduled", clears: true},
+	{types: []string{"hardware.failure"}, reason: "HardwareFailure", condition: "HardwareFailure"},
+	{types: []string{"instance.rebooted"}, reason: "DeviceRebooted"},
+	{types: []string{"instance.powered_off"}, reason: "DevicePoweredOff"},
+}
+
+func deviceEventRuleFor(event packngo.Event) *deviceEventRule {
+	t := strings.ToLower(event.Type)
+	for i, rule := range deviceEventRules {
+		for _, typ := range rule.types {
+			if t == typ {
+				return &deviceEventRules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// eventOptions select what the event watcher does with device events.
+type eventOptions struct {
+	// WatchDeviceEvents sets Node conditions and records Node events for the
+	// maintenance, failure and reboot events of devices
+	WatchDeviceEvents bool `json:"watchDeviceEvents,omitempty" yaml:"watchDeviceEvents,omitempty"`
+	// TaintDeviceEvents also taints Nodes with an active condition, e.g.
+	// packet.pharmer.dev/maintenance-scheduled=true:NoSchedule
+	TaintDeviceEvents bool `json:"taintDeviceEvents,omitempty" yaml:"taintDeviceEvents,omitempty"`
+}
+
+// eventWatcher polls the device events of the account and surfaces those of
+// the devices of Nodes as Node conditions, taints and Kubernetes Events.
+type eventWatcher struct {
+	client    *packngo.Client
+	project   string
+	clusterID string
+	options   eventOptions
+	now       func() time.Time
+
+	// recorded are the creation times of the device events already recorded
+	// as Node events, by ID. They are forgotten after maintenanceWindow.
+	recorded map[string]time.Time
+}
+
+func newEventWatcher(client *packngo.Client, projectID, clusterID string, options eventOptions) *eventWatcher {
+	return &eventWatcher{
+		client:    client,
+		project:   projectID,
+		clusterID: clusterID,
+		options:   options,
+		now:       time.Now,
+		recorded:  map[string]time.Time{},
+	}
+}
+
+// Start runs the watcher until stop is closed, if it is enabled.
+func (w *eventWatcher) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !w.options.WatchDeviceEvents {
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-event-watcher")
+	go wait.Until(func() {
+		if err := w.Sync(context.Background(), client); err != nil {
+			log.Errorf("%s: failed to sync device events: %v", ProviderName, err)
+		}
+	}, eventInterval, stop)
+}
+
+// Sync polls the device events once and updates all Nodes.
+func (w *eventWatcher) Sync(_ context.Context, kube kubernetes.Interface) error {
+	devices, _, err := w.client.Devices.List(w.project, nil)
+	if err != nil {
+		return err
+	}
+	since := w.now().Add(-eventWindow)
+	events, err := w.listEvents(since)
+	if err != nil {
+		return err
+	}
+	// events older than the maintenance window are no longer polled
+	for id, created := range w.recorded {
+		if w.now().Sub(created) > maintenanceWindow {
+			delete(w.recorded, id)
+		}
+	}
+	nodes, err := kube.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		device := matchDevice(devices, w.clusterID, node)
+		if device == nil {
+			continue
+		}
+		if err := w.syncNode(kube, node, deviceEvents(events, device.ID, since)); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %v", node.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// listEvents lists the events of the account, newest first, back to since.
+// Older events may be included.
+func (w *eventWatcher) listEvents(since time.Time) ([]packngo.Event, error) {
+	var events []packngo.Event
+	for page := 1; page <= eventMaxPages; page++ {
+		list, _, err := w.client.Events.List(&packngo.ListOptions{Page: page, PerPage: eventPageSize})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, list...)
+		if len(list) < eventPageSize {
+			return events, nil
+		}
+		if last := list[len(list)-1]; last.CreatedAt != nil && last.CreatedAt.Before(since) {
+			return events, nil
+		}
+	}
+	log.Warningf("%s: polled %d pages of events, ignoring older ones", ProviderName, eventMaxPages)
+	return events, nil
+}
+
+// deviceEvents returns the events of the device with id created after since.
+func deviceEvents(events []packngo.Event, id string, since time.Time) []packngo.Event {
+	var result []packngo.Event
+	for _, event := range events {
+		if event.CreatedAt == nil || event.CreatedAt.Before(since) {
+			continue
+		}
+		for _, rel := range event.Relationships {
+			if strings.HasSuffix(rel.Href, "/devices/"+id) {
+				result = append(result, event)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// syncNode updates the conditions and taints of node from events, the recent
+// events of its device, newest first, and records the events not yet recorded.
+func (w *eventWatcher) syncNode(kube kubernetes.Interface, node *v1.Node, events []packngo.Event) error {
+	var errs []error
+	// latest are the latest events setting or clearing each condition
+	latest := map[v1.NodeConditionType]packngo.Event{}
+	cleared := map[v1.NodeConditionType]bool{}
+	for _, event := range events {
+		rule := deviceEventRuleFor(event)
+		if rule == nil {
+			continue
+		}
+		if _, found := latest[rule.condition]; !found && rule.condition != "" {
+			latest[rule.condition] = event
+			cleared[rule.condition] = rule.clears
+		}
+		if _, found := w.recorded[event.ID]; found {
+			continue
+		}
+		// the Event was recorded before a restart of the watcher
+		if err := recordEvent(kube, node, rule, event); err != nil && !errors.IsAlreadyExists(err) {
+			errs = append(errs, err)
+			continue
+		}
+		w.recorded[event.ID] = event.CreatedAt.Time
+	}
+
+	statusChanged, specChanged := false, false
+	for _, rule := range deviceEventRules {
+		if rule.condition == "" || rule.clears {
+			continue
+		}
+		event, found := latest[rule.condition]
+		active := found && !cleared[rule.condition]
+		condition := v1.NodeCondition{
+			Type:               rule.condition,
+			Status:             v1.ConditionFalse,
+			Reason:             "No" + rule.reason,
+			LastHeartbeatTime:  metav1.NewTime(w.now()),
+			LastTransitionTime: metav1.NewTime(w.now()),
+		}
+		if active {
+			condition.Status = v1.ConditionTrue
+			condition.Reason = rule.reason
+			condition.Message = eventMessage(event)
+			condition.LastTransitionTime = metav1.NewTime(event.CreatedAt.Time)
+		} else if current := cloud.NodeCondition(node, rule.condition); !found && current != nil && current.Status == v1.ConditionTrue &&
+			w.now().Sub(current.LastTransitionTime.Time) < rule.window {
+			// the event left the polled window, but its condition is not
+			// cleared yet
+			active = true
+			condition = *current
+		}
+		// inactive conditions are only reported once they have been active
+		if active || cloud.NodeCondition(node, rule.condition) != nil {
+			statusChanged = cloud.SetNodeCondition(node, condition) || statusChanged
+		}
+
+		key := conditionTaintKey(rule.condition)
+		if active && w.options.TaintDeviceEvents {
+			specChanged = cloud.AddTaint(node, v1.Taint{Key: key, Value: "true", Effect: v1.TaintEffectNoSchedule}) || specChanged
+		} else {
+			specChanged = cloud.RemoveTaint(node, key, v1.TaintEffectNoSchedule) || specChanged
+		}
+	}
+
+	if specChanged {
+		updated, err := kube.CoreV1().Nodes().Update(node)
+		if err != nil {
+			return err
+		}
+		updated.Status.Conditions = node.Status.Conditions
+		node = updated
+	}
+	if statusChanged {
+		if _, err := kube.CoreV1().Nodes().UpdateStatus(node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// conditionTaintKey returns the taint key of a condition, e.g.
+// packet.pharmer.dev/maintenance-scheduled for MaintenanceScheduled.
+func conditionTaintKey(condition v1.NodeConditionType) string {
+	var name []rune
+	for i, r := range string(condition) {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			name = append(name, '-')
+		}
+		name = append(name, r)
+	}
+	return cloud.LabelKey(ProviderName, strings.ToLower(string(name)))
+}
+
+func eventMessage(event packngo.Event) string {
+	if event.Interpolated != "" {
+		return event.Interpolated
+	}
+	if event.Body != "" {
+		return event.Body
+	}
+	return event.Type
+}
+
+// recordEvent records an Event about node for the device event, a warning
+// unless the event clears a condition.
+func recordEvent(kube kubernetes.Interface, node *v1.Node, rule *deviceEventRule, event packngo.Event) error {
+	eventType := v1.EventTypeWarning
+	if rule.clears {
+		eventType = v1.EventTypeNormal
+	}
+	timestamp := metav1.NewTime(event.CreatedAt.Time)
+	_, err := kube.CoreV1().Events(metav1.NamespaceDefault).Create(&v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%s", node.Name, event.ID),
+			Namespace: metav1.NamespaceDefault,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind: "Node",
+			Name: node.Name,
+			UID:  node.UID,
+		},
+		Reason:         rule.reason,
+		Message:        eventMessage(event),
+		Source:         v1.EventSource{Component: ProviderName + "-cloud-provider"},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+		Type:           eventType,
+	})
+	return err
+}
@@ -0,0 +1,177 @@
+package packet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func testEvent(id, eventType, device string, created time.Time) packngo.Event {
+	return packngo.Event{
+		ID:            id,
+		Type:          eventType,
+		Interpolated:  eventType + " of " + device,
+		Relationships: []packngo.Href{{Href: "/devices/" + device}},
+		CreatedAt:     &packngo.Timestamp{Time: created},
+	}
+}
+
+func TestEventWatcher(t *testing.T) {
+	now := time.Now()
+	project := &packngo.Project{ID: testProject}
+	api := standin.NewPacket(
+		packngo.Device{ID: "m1", Hostname: "maintained", Project: project},
+		packngo.Device{ID: "f1", Hostname: "failed", Project: project},
+	)
+	defer api.Close()
+	api.Events = []packngo.Event{
+		testEvent("e4", "instance.rebooted", "m1", now.Add(-30*time.Minute)),
+		testEvent("e3", "maintenance.scheduled", "m1", now.Add(-time.Hour)),
+		testEvent("e2", "user.login", "m1", now.Add(-time.Hour)),
+		testEvent("e1", "hardware.failure", "f1", now.Add(-48*time.Hour)),
+	}
+	// the events of other devices push those of the Nodes to later pages
+	for i := 0; i < eventPageSize; i++ {
+		api.Events = append([]packngo.Event{testEvent(fmt.Sprintf("o%d", i), "user.login", "other", now.Add(-time.Minute))}, api.Events...)
+	}
+	config := fmt.Sprintf("project: %s\napiKey: secret\nendpoint: %s\nwatchDeviceEvents: true\ntaintDeviceEvents: true\n",
+		testProject, api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (eventOptions{true, true}); c.events.options != expected {
+		t.Fatalf("expected options %+v, got %+v", expected, c.events.options)
+	}
+	c.events.now = func() time.Time { return now }
+
+	failed := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "failed"}}
+	failed.Status.Conditions = []v1.NodeCondition{{Type: "HardwareFailure", Status: v1.ConditionTrue, Reason: "HardwareFailure"}}
+	kube := standin.NewKubernetes(v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "maintained"}}, failed)
+	defer kube.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := c.events.Sync(context.Background(), kube.Client()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	node := kube.Node("maintained")
+	condition := cloud.NodeCondition(node, "MaintenanceScheduled")
+	if condition == nil || condition.Status != v1.ConditionTrue || condition.Message != "maintenance.scheduled of m1" {
+		t.Errorf("expected active maintenance condition, got %+v", condition)
+	}
+	if cloud.NodeCondition(node, "HardwareFailure") != nil {
+		t.Error("expected no hardware failure condition")
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Key != "packet.pharmer.dev/maintenance-scheduled" {
+		t.Errorf("expected maintenance taint, got %v", node.Spec.Taints)
+	}
+
+	// the failure is older than the event window
+	if condition := cloud.NodeCondition(kube.Node("failed"), "HardwareFailure"); condition == nil || condition.Status != v1.ConditionFalse {
+		t.Errorf("expected inactive hardware failure condition, got %+v", condition)
+	}
+
+	var reasons []string
+	for _, event := range kube.Events {
+		if event.InvolvedObject.Name != "maintained" || event.Type != v1.EventTypeWarning {
+			t.Errorf("unexpected event %+v", event)
+		}
+		reasons = append(reasons, event.Reason)
+	}
+	if strings.Join(reasons, ",") != "DeviceRebooted,MaintenanceScheduled" {
+		t.Errorf("expected each event to be recorded once, got %v", reasons)
+	}
+
+	// a restarted watcher does not record the events again
+	c.events.recorded = map[string]time.Time{}
+	if err := c.events.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if len(kube.Events) != 2 || len(c.events.recorded) != 2 {
+		t.Errorf("expected the recorded events to be skipped, got %v", c.events.recorded)
+	}
+
+	// the scheduled maintenance stays active after the event window until it
+	// is completed, or the maintenance window ends
+	now = now.Add(eventWindow)
+	if err := c.events.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	node = kube.Node("maintained")
+	if condition := cloud.NodeCondition(node, "MaintenanceScheduled"); condition == nil || condition.Status != v1.ConditionTrue {
+		t.Errorf("expected active maintenance condition, got %+v", condition)
+	}
+	if len(node.Spec.Taints) != 1 {
+		t.Errorf("expected maintenance taint to be kept, got %v", node.Spec.Taints)
+	}
+
+	now = now.Add(maintenanceWindow)
+	if err := c.events.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	node = kube.Node("maintained")
+	if condition := cloud.NodeCondition(node, "MaintenanceScheduled"); condition == nil || condition.Status != v1.ConditionFalse {
+		t.Errorf("expected inactive maintenance condition, got %+v", condition)
+	}
+	if len(node.Spec.Taints) != 0 {
+		t.Errorf("expected maintenance taint to be removed, got %v", node.Spec.Taints)
+	}
+	// the events are forgotten once older than the maintenance window
+	if len(c.events.recorded) != 0 {
+		t.Errorf("expected recorded events to be pruned, got %v", c.events.recorded)
+	}
+}
+
+func TestEventWatcherEventTypes(t *testing.T) {
+	now := time.Now()
+	project := &packngo.Project{ID: testProject}
+	api := standin.NewPacket(
+		packngo.Device{ID: "m1", Hostname: "maintained", Project: project},
+		packngo.Device{ID: "r1", Hostname: "reinstalled", Project: project},
+	)
+	defer api.Close()
+	api.Events = []packngo.Event{
+		testEvent("e4", "maintenance.completed", "m1", now.Add(-time.Hour)),
+		testEvent("e3", "maintenance.scheduled", "m1", now.Add(-2*time.Hour)),
+		testEvent("e2", "provisioning.failed", "r1", now.Add(-time.Hour)),
+		testEvent("e1", "instance.reinstall.failed", "r1", now.Add(-time.Hour)),
+	}
+	config := fmt.Sprintf("project: %s\napiKey: secret\nendpoint: %s\nwatchDeviceEvents: true\n", testProject, api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.events.now = func() time.Time { return now }
+	kube := standin.NewKubernetes(v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "maintained"}}, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "reinstalled"}})
+	defer kube.Close()
+
+	if err := c.events.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	// the completed maintenance clears the condition
+	if condition := cloud.NodeCondition(kube.Node("maintained"), "MaintenanceScheduled"); condition != nil {
+		t.Errorf("expected no maintenance condition, got %+v", condition)
+	}
+	// failures of provisioning are no hardware failures
+	if condition := cloud.NodeCondition(kube.Node("reinstalled"), "HardwareFailure"); condition != nil {
+		t.Errorf("expected no hardware failure condition, got %+v", condition)
+	}
+
+	var events []string
+	for _, event := range kube.Events {
+		events = append(events, event.Type+" "+event.Reason)
+	}
+	if expected := "Normal MaintenanceCompleted,Warning MaintenanceScheduled"; strings.Join(events, ",") != expected {
+		t.Errorf("expected events %s, got %v", expected, events)
+	}
+}
@@ -3,6 +3,8 @@ package packet
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/packethost/packngo"
@@ -16,14 +18,15 @@ import (
 type instances struct {
 	client  *packngo.Client
 	project string
+	mutator *cloud.Mutator
 }
 
-func newInstances(client *packngo.Client, projectID string) cloudprovider.Instances {
-	return &instances{client, projectID}
+func newInstances(client *packngo.Client, projectID string, mutator *cloud.Mutator) cloudprovider.Instances {
+	return &instances{client: client, project: projectID, mutator: mutator}
 }
 
 func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
-	device, err := deviceByName(i.client, i.project, name)
+	device, err := deviceByName(i.client, i.project, i.mutator.ClusterID(), name)
 	if err != nil {
 		return nil, err
 	}
@@ -51,28 +54,40 @@ func (i *instances) nodeAddresses(device *packngo.Device) ([]v1.NodeAddress, err
 	if err != nil {
 		return nil, err
 	}
-	var privateIP, publicIP string
-
-	for _, addr := range host.Network {
+	// the management addresses assigned at provisioning are the primary ones,
+	// elastic IPs follow; private addresses come first, then by address
+	network := append([]*packngo.IPAddressAssignment(nil), host.Network...)
+	sort.SliceStable(network, func(a, b int) bool {
+		x, y := network[a], network[b]
+		if x.Management != y.Management {
+			return x.Management
+		}
+		if x.Public != y.Public {
+			return !x.Public
+		}
+		return x.Address < y.Address
+	})
+
+	var hasPrivateIP, hasPublicIP bool
+	for _, addr := range network {
+		addressType := v1.NodeInternalIP
+		if addr.Public {
+			addressType = v1.NodeExternalIP
+		}
 		if addr.AddressFamily == 4 {
-			if addr.Public {
-				publicIP = addr.Address
-			} else {
-				privateIP = addr.Address
-			}
+			hasPrivateIP = hasPrivateIP || !addr.Public
+			hasPublicIP = hasPublicIP || addr.Public
 		}
+		addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: addr.Address})
 	}
-	if privateIP == "" {
+	if !hasPrivateIP {
 		return nil, fmt.Errorf("could not get private ip")
 	}
-	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: privateIP})
-
-	if publicIP == "" {
+	if !hasPublicIP {
 		return nil, fmt.Errorf("could not get public ip")
 	}
-	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: publicIP})
 
-	return addresses, nil
+	return cloud.NodeAddresses(addresses), nil
 }
 
 func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
@@ -80,7 +95,7 @@ func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (st
 }
 
 func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
-	device, err := deviceByName(i.client, i.project, nodeName)
+	device, err := deviceByName(i.client, i.project, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -88,7 +103,7 @@ func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (stri
 }
 
 func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
-	device, err := deviceByName(i.client, i.project, nodeName)
+	device, err := deviceByName(i.client, i.project, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -107,8 +122,31 @@ func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID strin
 	return device.Plan.Slug, nil
 }
 
+// AddSSHKeyToAllInstances adds the key to the project, unless the project
+// already has it. Project keys are installed on the devices of the project
+// when they are provisioned.
 func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
-	return cloud.ErrNotImplemented
+	key, err := cloud.ParseSSHKey(user, keyData)
+	if err != nil {
+		return err
+	}
+	keys, _, err := i.client.SSHKeys.ProjectList(i.project)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if key.Matches(k.Key) {
+			return nil
+		}
+	}
+	return i.mutator.Do(nil, "create ssh key", cloud.Params{"label": key.Name, "fingerprint": key.Fingerprint, "project": i.project}, func() error {
+		_, _, err := i.client.SSHKeys.Create(&packngo.SSHKeyCreateRequest{
+			Label:     key.Name,
+			Key:       key.PublicKey,
+			ProjectID: i.project,
+		})
+		return err
+	})
 }
 
 func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
@@ -121,10 +159,10 @@ func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID str
 		return false, err
 	}
 	_, err = deviceByID(i.client, id)
-	if err == nil {
-		return true, nil
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
 	}
-	return false, nil
+	return err == nil, err
 }
 
 func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID string) (bool, error) {
@@ -133,16 +171,26 @@ func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID
 
 func deviceByID(client *packngo.Client, id string) (*packngo.Device, error) {
 	device, _, err := client.Devices.Get(id)
+	if e, ok := err.(*packngo.ErrorResponse); ok && e.Response != nil && e.Response.StatusCode == http.StatusNotFound {
+		return nil, cloudprovider.InstanceNotFound
+	}
 	return device, err
 }
 
-func deviceByName(client *packngo.Client, projectID string, nodeName types.NodeName) (*packngo.Device, error) {
+// deviceByName returns the device of the project called nodeName. If
+// clusterID is not empty, only devices tagged with its cluster tag are
+// considered, so that devices of other clusters with the same name do not
+// collide.
+func deviceByName(client *packngo.Client, projectID, clusterID string, nodeName types.NodeName) (*packngo.Device, error) {
 	devices, _, err := client.Devices.List(projectID, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, device := range devices {
+		if clusterID != "" && !cloud.HasClusterTag(device.Tags, clusterID) {
+			continue
+		}
 		if device.Hostname == string(nodeName) {
 			return &device, nil
 		}
@@ -0,0 +1,68 @@
+package packet
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// nodeLabels labels Nodes with the facility of their device and whether it is
+// a spot device or runs on reserved hardware.
+func nodeLabels(client *packngo.Client, projectID, clusterID string) cloud.NodeLabelsFunc {
+	return func(_ context.Context, node *v1.Node) (map[string]string, error) {
+		device, err := nodeDevice(client, projectID, clusterID, node)
+		if err != nil {
+			return nil, err
+		}
+		labels := map[string]string{
+			spotInstanceKey:        strconv.FormatBool(device.SpotInstance),
+			hardwareReservationKey: strconv.FormatBool(device.HardwareReservation.Href != ""),
+		}
+		if device.Facility != nil {
+			labels[cloud.LabelKey(ProviderName, "facility")] = device.Facility.Code
+		}
+		return labels, nil
+	}
+}
+
+// nodeDevice returns the device of node, by its provider ID if it is set and
+// by its name otherwise.
+func nodeDevice(client *packngo.Client, projectID, clusterID string, node *v1.Node) (*packngo.Device, error) {
+	if node.Spec.ProviderID == "" {
+		return deviceByName(client, projectID, clusterID, types.NodeName(node.Name))
+	}
+	id, err := deviceIDFromProviderID(node.Spec.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	return deviceByID(client, id)
+}
+
+// matchDevice returns the device of node among devices, by its provider ID if
+// it is set and by its name otherwise. If clusterID is not empty, only devices
+// tagged with its cluster tag match by name.
+func matchDevice(devices []packngo.Device, clusterID string, node *v1.Node) *packngo.Device {
+	var id string
+	if node.Spec.ProviderID != "" {
+		var err error
+		if id, err = deviceIDFromProviderID(node.Spec.ProviderID); err != nil {
+			return nil
+		}
+	}
+	for i, device := range devices {
+		switch {
+		case id != "":
+			if device.ID == id {
+				return &devices[i]
+			}
+		case clusterID != "" && !cloud.HasClusterTag(device.Tags, clusterID):
+		case device.Hostname == node.Name:
+			return &devices[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestReplay(t *testing.T) {
+	conformance.Replay(t, ProviderName, "testdata/replay.yaml", func(config []byte) ([]string, error) {
+		cred := &credential{}
+		err := yaml.Unmarshal(config, cred)
+		return []string{cred.ApiKey}, err
+	})
+}
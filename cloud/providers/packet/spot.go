@@ -0,0 +1,143 @@
+package packet
+
+import (
+	"context"
+	"time"
+
+	"github.com/appscode/go/log"
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// spotInterval is the time between two checks of the spot devices. Packet
+// announces the termination of a spot device two minutes in advance.
+const spotInterval = 30 * time.Second
+
+// spotNotice is how long before its termination time a spot device is
+// drained: the two minutes of notice given by Packet, plus two syncs of slack.
+// Termination times further ahead are not final and are ignored.
+const spotNotice = 2*spotInterval + 2*time.Minute
+
+var (
+	// spotInstanceKey labels and taints the Nodes of spot devices
+	spotInstanceKey = cloud.LabelKey(ProviderName, "spot-instance")
+	// hardwareReservationKey labels and taints the Nodes of devices on
+	// reserved hardware
+	hardwareReservationKey = cloud.LabelKey(ProviderName, "hardware-reservation")
+)
+
+// spotOptions select what the spot controller does with the Nodes of spot and
+// reservation backed devices.
+type spotOptions struct {
+	// TaintSpotInstances taints the Nodes of spot devices with
+	// packet.pharmer.dev/spot-instance=true:NoSchedule
+	TaintSpotInstances bool `json:"taintSpotInstances,omitempty" yaml:"taintSpotInstances,omitempty"`
+	// TaintHardwareReservations taints the Nodes of devices on reserved
+	// hardware with packet.pharmer.dev/hardware-reservation=true:NoSchedule
+	TaintHardwareReservations bool `json:"taintHardwareReservations,omitempty" yaml:"taintHardwareReservations,omitempty"`
+	// DrainTerminatingSpotInstances cordons and drains the Nodes of spot
+	// devices as soon as Packet announces their termination
+	DrainTerminatingSpotInstances bool `json:"drainTerminatingSpotInstances,omitempty" yaml:"drainTerminatingSpotInstances,omitempty"`
+}
+
+func (o spotOptions) enabled() bool {
+	return o.TaintSpotInstances || o.TaintHardwareReservations || o.DrainTerminatingSpotInstances
+}
+
+// spotController taints the Nodes of spot and reservation backed devices and
+// drains the Nodes of spot devices that are about to be terminated.
+type spotController struct {
+	client    *packngo.Client
+	project   string
+	clusterID string
+	options   spotOptions
+	now       func() time.Time
+}
+
+func newSpotController(client *packngo.Client, projectID, clusterID string, options spotOptions) *spotController {
+	return &spotController{client: client, project: projectID, clusterID: clusterID, options: options, now: time.Now}
+}
+
+// Start runs the controller until stop is closed, if any of its options is
+// enabled.
+func (c *spotController) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !c.options.enabled() {
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-spot-controller")
+	go wait.Until(func() {
+		if err := c.Sync(context.Background(), client); err != nil {
+			log.Errorf("%s: failed to sync spot devices: %v", ProviderName, err)
+		}
+	}, spotInterval, stop)
+}
+
+// Sync checks the devices of all Nodes once.
+func (c *spotController) Sync(_ context.Context, kube kubernetes.Interface) error {
+	devices, _, err := c.client.Devices.List(c.project, nil)
+	if err != nil {
+		return err
+	}
+	nodes, err := kube.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		device := matchDevice(devices, c.clusterID, node)
+		if device == nil {
+			continue
+		}
+		if err := c.syncNode(kube, node, device); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c *spotController) syncNode(kube kubernetes.Interface, node *v1.Node, device *packngo.Device) error {
+	changed := false
+	if c.options.TaintSpotInstances && device.SpotInstance {
+		changed = cloud.AddTaint(node, v1.Taint{Key: spotInstanceKey, Value: "true", Effect: v1.TaintEffectNoSchedule}) || changed
+	}
+	if c.options.TaintHardwareReservations && device.HardwareReservation.Href != "" {
+		changed = cloud.AddTaint(node, v1.Taint{Key: hardwareReservationKey, Value: "true", Effect: v1.TaintEffectNoSchedule}) || changed
+	}
+
+	drain := c.options.DrainTerminatingSpotInstances && terminating(device, c.now())
+	if drain && !node.Spec.Unschedulable {
+		log.Warningf("%s: device %s of node %s terminates at %s, cordoning node", ProviderName, device.ID, node.Name, device.TerminationTime.Format(time.RFC3339))
+		node.Spec.Unschedulable = true
+		changed = true
+	}
+
+	if changed {
+		if _, err := kube.CoreV1().Nodes().Update(node); err != nil {
+			return err
+		}
+	}
+	if drain {
+		// repeated on every sync, as pods may have been scheduled before the
+		// node was cordoned
+		return cloud.DrainNode(kube, node.Name)
+	}
+	return nil
+}
+
+// terminating returns true if the spot device terminates within spotNotice of
+// now.
+func terminating(device *packngo.Device, now time.Time) bool {
+	if !device.SpotInstance || device.TerminationTime == nil || device.TerminationTime.IsZero() {
+		return false
+	}
+	return device.TerminationTime.Sub(now) <= spotNotice
+}
@@ -0,0 +1,79 @@
+package packet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func TestSpotController(t *testing.T) {
+	project := &packngo.Project{ID: testProject}
+	api := standin.NewPacket(
+		packngo.Device{ID: "s1", Hostname: "spot-1", Project: project, SpotInstance: true,
+			TerminationTime: &packngo.Timestamp{Time: time.Now().Add(2 * time.Minute)}},
+		packngo.Device{ID: "s2", Hostname: "spot-2", Project: project, SpotInstance: true},
+		packngo.Device{ID: "s3", Hostname: "spot-3", Project: project, SpotInstance: true,
+			TerminationTime: &packngo.Timestamp{Time: time.Now().Add(24 * time.Hour)}},
+		packngo.Device{ID: "r1", Hostname: "reserved", Project: project,
+			HardwareReservation: packngo.Href{Href: "/hardware-reservations/7c9e"}},
+		packngo.Device{ID: "p1", Hostname: "plain", Project: project},
+	)
+	defer api.Close()
+	config := fmt.Sprintf("project: %s\napiKey: secret\nendpoint: %s\ntaintSpotInstances: true\ntaintHardwareReservations: true\ndrainTerminatingSpotInstances: true\n",
+		testProject, api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (spotOptions{true, true, true}); c.spot.options != expected {
+		t.Fatalf("expected options %+v, got %+v", expected, c.spot.options)
+	}
+
+	kube := standin.NewKubernetes(
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "spot-1"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "spot-2"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "spot-3"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "reserved-node"}, Spec: v1.NodeSpec{ProviderID: "packet://r1"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+		v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "elsewhere"}},
+	)
+	defer kube.Close()
+	kube.Pods = []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "spot-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "spot-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "spot-3"}},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.spot.Sync(context.Background(), kube.Client()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spot := v1.Taint{Key: "packet.pharmer.dev/spot-instance", Value: "true", Effect: v1.TaintEffectNoSchedule}
+	reserved := v1.Taint{Key: "packet.pharmer.dev/hardware-reservation", Value: "true", Effect: v1.TaintEffectNoSchedule}
+	for name, expected := range map[string]v1.NodeSpec{
+		"spot-1": {Taints: []v1.Taint{spot}, Unschedulable: true},
+		"spot-2": {Taints: []v1.Taint{spot}},
+		// the termination time is a day away, too far to be final
+		"spot-3":        {Taints: []v1.Taint{spot}},
+		"reserved-node": {Taints: []v1.Taint{reserved}, ProviderID: "packet://r1"},
+		"plain":         {},
+		"elsewhere":     {},
+	} {
+		if node := kube.Node(name); !reflect.DeepEqual(node.Spec, expected) {
+			t.Errorf("node %s: expected spec %+v, got %+v", name, expected, node.Spec)
+		}
+	}
+	if expected := []string{"default/web"}; !reflect.DeepEqual(kube.Evictions, expected) {
+		t.Errorf("expected evictions %v, got %v", expected, kube.Evictions)
+	}
+}
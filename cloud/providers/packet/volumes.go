@@ -0,0 +1,32 @@
+package packet
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// GetLabelsForVolume returns the region label of the block storage volume of
+// pv, its facility. PersistentVolumes that are not backed by a volume are not
+// labeled.
+func (c *Cloud) GetLabelsForVolume(_ context.Context, pv *v1.PersistentVolume) (map[string]string, error) {
+	id := cloud.VolumeID(pv)
+	if id == "" {
+		return nil, nil
+	}
+	volume, _, err := c.client.Volumes.Get(id)
+	if e, ok := err.(*packngo.ErrorResponse); ok && e.Response != nil && e.Response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if volume.Facility == nil {
+		return nil, nil
+	}
+	return cloud.VolumeLabels(cloudprovider.Zone{Region: volume.Facility.ID}), nil
+}
@@ -0,0 +1,37 @@
+package packet
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/packethost/packngo"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGetLabelsForVolume(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	api.Volumes = []packngo.Volume{{ID: "f3d9b5a0", Facility: &packngo.Facility{ID: "ewr1", Code: "ewr1"}}}
+
+	pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{
+		PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "net.packet.csi", VolumeHandle: "f3d9b5a0"}},
+	}}
+	labels, err := c.GetLabelsForVolume(context.Background(), pv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone, err := c.zones.GetZoneByProviderID(context.Background(), "packet://e123s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"failure-domain.beta.kubernetes.io/region": zone.Region}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+
+	pv.Spec.CSI.VolumeHandle = "unknown"
+	if labels, err := c.GetLabelsForVolume(context.Background(), pv); err != nil || labels != nil {
+		t.Errorf("expected unknown volume not to be labeled, got %v (%v)", labels, err)
+	}
+}
@@ -9,13 +9,14 @@ import (
 )
 
 type zones struct {
-	client  *packngo.Client
-	project string
-	zone    string
+	client    *packngo.Client
+	project   string
+	zone      string
+	clusterID string
 }
 
-func newZones(client *packngo.Client, projectID, zone string) cloudprovider.Zones {
-	return zones{client, projectID, zone}
+func newZones(client *packngo.Client, projectID, zone, clusterID string) cloudprovider.Zones {
+	return zones{client, projectID, zone, clusterID}
 }
 
 func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
@@ -36,7 +37,7 @@ func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudp
 }
 
 func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
-	device, err := deviceByName(z.client, z.project, nodeName)
+	device, err := deviceByName(z.client, z.project, z.clusterID, nodeName)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
@@ -1,7 +1,9 @@
 package providers
 
 import (
+	_ "pharmer.dev/cloud-controller-manager/cloud/providers/hetzner"
 	_ "pharmer.dev/cloud-controller-manager/cloud/providers/lightsail"
+	_ "pharmer.dev/cloud-controller-manager/cloud/providers/linode"
 	_ "pharmer.dev/cloud-controller-manager/cloud/providers/packet"
 	_ "pharmer.dev/cloud-controller-manager/cloud/providers/scaleway"
 	_ "pharmer.dev/cloud-controller-manager/cloud/providers/softlayer"
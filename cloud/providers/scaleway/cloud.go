@@ -8,6 +8,7 @@ import (
 	"github.com/ghodss/yaml"
 	scw "github.com/scaleway/scaleway-cli/pkg/api"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
 const (
@@ -18,15 +19,54 @@ type Credential struct {
 	Organization string `json:"organization" yaml:"organization"`
 	Token        string `json:"token" yaml:"token"`
 	Region       string `json:"region" yaml:"region"`
+	// Endpoints override the base URLs of the Scaleway APIs
+	Endpoints Endpoints `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	// ClusterID limits the controller to servers tagged with the cluster tag,
+	// see cloud.ClusterTag, and to the resources it created for the cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	// ManageSecurityGroup opens the NodePorts of LoadBalancer Services to
+	// their loadBalancerSourceRanges in a security group of the cluster, which
+	// is attached to the servers of the cluster in the organization's default
+	// security group. It requires a ClusterID.
+	ManageSecurityGroup bool `json:"manageSecurityGroup,omitempty" yaml:"manageSecurityGroup,omitempty"`
 }
+
+type Endpoints struct {
+	Par1    string `json:"par1,omitempty" yaml:"par1,omitempty"`
+	Ams1    string `json:"ams1,omitempty" yaml:"ams1,omitempty"`
+	Account string `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+// apply overrides the package level endpoints of the Scaleway client, which
+// does not support per client endpoints.
+func (e Endpoints) apply() {
+	if e.Par1 != "" {
+		scw.ComputeAPIPar1 = e.Par1
+	}
+	if e.Ams1 != "" {
+		scw.ComputeAPIAms1 = e.Ams1
+	}
+	if e.Account != "" {
+		scw.AccountAPI = e.Account
+	}
+}
+
 type Cloud struct {
 	client        *scw.ScalewayAPI
 	instances     cloudprovider.Instances
 	zones         cloudprovider.Zones
 	loadbalancers cloudprovider.LoadBalancer
+	clusters      cloudprovider.Clusters
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+	labeler   *cloud.NodeLabeler
+	// securityGroup is nil if the security group is not managed
+	securityGroup *securityGroup
 }
 
 func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, scw.ComputeAPIPar1)
 	cloudprovider.RegisterCloudProvider(
 		ProviderName,
 		func(config io.Reader) (cloudprovider.Interface, error) {
@@ -46,20 +86,43 @@ func newCloud(config io.Reader) (*Cloud, error) {
 	if err != nil {
 		return nil, err
 	}
+	cred.Endpoints.apply()
 	client, err := scw.NewScalewayAPI(cred.Organization, cred.Token, "pharmer", cred.Region)
 	if err != nil {
 		return nil, err
 	}
 
+	mutator := cloud.NewMutator(ProviderName, cred.ClusterID)
+	collector := cloud.NewCollector(mutator)
+	// the rules of a security group created earlier are collected even if it
+	// is no longer managed
+	collector.Register(newSecurityGroup(client, mutator))
+	var group *securityGroup
+	if cred.ManageSecurityGroup {
+		group = newSecurityGroup(client, mutator)
+	}
 	return &Cloud{
 		client:        client,
-		instances:     newInstances(client),
-		zones:         newZones(client, cred.Region),
-		loadbalancers: newLoadbalancers(client),
+		instances:     newInstances(client, mutator),
+		zones:         newZones(client, cred.Region, cred.ClusterID),
+		loadbalancers: newLoadbalancers(client, group),
+		clusters:      cloud.NewClusters(listMembers(client)),
+
+		mutator:   mutator,
+		collector: collector,
+		labeler:   cloud.NewNodeLabeler(ProviderName, nodeLabels(client, cred.ClusterID)),
+
+		securityGroup: group,
 	}, nil
 }
 
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+	c.labeler.Start(clientBuilder, stop)
+	if c.securityGroup != nil {
+		c.securityGroup.Initialize(clientBuilder)
+	}
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
@@ -75,7 +138,7 @@ func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
 }
 
 func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
-	return nil, false
+	return c.clusters, true
 }
 
 func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
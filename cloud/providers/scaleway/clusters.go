@@ -0,0 +1,33 @@
+package scaleway
+
+import (
+	"context"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// listMembers lists the servers of the organization that belong to a cluster.
+func listMembers(client *scw.ScalewayAPI) cloud.ListMembersFunc {
+	return func(_ context.Context, _ string) ([]cloud.ClusterMember, error) {
+		servers, err := client.GetServers(true, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var members []cloud.ClusterMember
+		for _, server := range *servers {
+			if server.Organization != "" && server.Organization != client.Organization {
+				continue
+			}
+			address := server.PublicAddress.IP
+			if address == "" {
+				address = server.PrivateIP
+			}
+			if member, ok := cloud.NewClusterMember(server.Name, server.Tags, address); ok {
+				members = append(members, member)
+			}
+		}
+		return members, nil
+	}
+}
@@ -0,0 +1,77 @@
+package scaleway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+func TestClusters(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	master := testServer("1f2e3d4c", "prod-master", "par1", "10.1.0.20", "51.15.0.20")
+	master.Tags = []string{cloud.ClusterTag("prod")}
+	node := testServer("2f3e4d5c", "prod-node-1", "ams1", "10.2.0.21", "")
+	node.Tags = []string{cloud.ClusterTag("prod")}
+	dev := testServer("3f4e5d6c", "dev-1", "ams1", "10.2.0.30", "")
+	dev.Tags = []string{"db", cloud.ClusterTag("dev"), cloud.MasterTag}
+	// servers of other organizations are not members
+	other := testServer("4f5e6d7c", "qa-master", "par1", "10.1.0.40", "51.15.0.40")
+	other.Tags = []string{cloud.ClusterTag("qa")}
+	other.Organization = "another-org"
+	api.Servers = append(api.Servers, master, node, dev, other)
+
+	clusters, ok := c.Clusters()
+	if !ok {
+		t.Fatal("expected clusters support")
+	}
+
+	names, err := clusters.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"dev", "prod"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, names)
+	}
+
+	for cluster, expected := range map[string]string{"prod": "51.15.0.20", "dev": "10.2.0.30"} {
+		address, err := clusters.Master(ctx, cluster)
+		if err != nil || address != expected {
+			t.Errorf("expected master %s of cluster %s, got %q (%v)", expected, cluster, address, err)
+		}
+	}
+}
+
+func TestClusterID(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	if c.mutator.ClusterID() != "" {
+		t.Error("expected no cluster ID")
+	}
+
+	dev := testServer("5f6e7d8c", "master", "par1", "10.1.0.50", "51.15.0.50")
+	dev.Tags = []string{cloud.ClusterTag("dev")}
+	prod := testServer("6f7e8d9c", "master", "ams1", "10.2.0.51", "51.15.0.51")
+	prod.Tags = []string{cloud.ClusterTag("prod")}
+	api.Servers = append(api.Servers, dev, prod)
+
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("organization: org\ntoken: secret\nregion: par1\nclusterID: prod\nendpoints:\n  par1: %s\n  ams1: %s\n",
+		api.ComputeEndpoint("par1"), api.ComputeEndpoint("ams1"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.mutator.ClusterID() != "prod" {
+		t.Error("expected cluster ID to be configured")
+	}
+	if id, err := c.instances.InstanceID(ctx, "master"); err != nil || id != "6f7e8d9c" {
+		t.Errorf("expected instance id 6f7e8d9c, got %q (%v)", id, err)
+	}
+}
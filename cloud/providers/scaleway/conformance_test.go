@@ -0,0 +1,33 @@
+package scaleway
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				// node names are the lower cased server names
+				Name:       "master",
+				ProviderID: "scaleway://5e5a7b1b",
+				Type:       "START1-S",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "Master"},
+					{Type: v1.NodeInternalIP, Address: "10.1.0.10"},
+					{Type: v1.NodeExternalIP, Address: "51.15.0.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "par1"},
+			},
+		},
+		MissingName:       "node-2",
+		MissingProviderID: "scaleway://missing",
+	})
+}
@@ -3,6 +3,7 @@ package scaleway
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -14,15 +15,16 @@ import (
 )
 
 type instances struct {
-	client *scw.ScalewayAPI
+	client  *scw.ScalewayAPI
+	mutator *cloud.Mutator
 }
 
-func newInstances(client *scw.ScalewayAPI) cloudprovider.Instances {
-	return &instances{client}
+func newInstances(client *scw.ScalewayAPI, mutator *cloud.Mutator) cloudprovider.Instances {
+	return &instances{client: client, mutator: mutator}
 }
 
 func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
-	server, err := serverByName(i.client, name)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), name)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +58,11 @@ func nodeAddresses(server *scw.ScalewayServer) ([]v1.NodeAddress, error) {
 	}
 	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: server.PublicAddress.IP})
 
-	return addresses, nil
+	if server.IPV6 != nil {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: server.IPV6.Address})
+	}
+
+	return cloud.NodeAddresses(addresses), nil
 }
 
 func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
@@ -64,7 +70,7 @@ func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (st
 }
 
 func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
-	server, err := serverByName(i.client, nodeName)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -72,7 +78,7 @@ func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (stri
 }
 
 func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
-	server, err := serverByName(i.client, nodeName)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -91,8 +97,32 @@ func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID strin
 	return server.CommercialType, nil
 }
 
+// AddSSHKeyToAllInstances adds the key to the Scaleway user owning the token,
+// unless the user already has it. Scaleway installs the keys of a user on the
+// servers of the user's organizations when they boot.
 func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
-	return cloud.ErrNotImplemented
+	key, err := cloud.ParseSSHKey(user, keyData)
+	if err != nil {
+		return err
+	}
+	account, err := i.client.GetUser()
+	if err != nil {
+		return err
+	}
+
+	// the API replaces the keys of the user, so the existing keys are sent along
+	keys := make([]scw.ScalewayKeyDefinition, 0, len(account.SSHPublicKeys)+1)
+	for _, k := range account.SSHPublicKeys {
+		if key.Matches(k.Key) {
+			return nil
+		}
+		keys = append(keys, scw.ScalewayKeyDefinition{Key: k.Key})
+	}
+	keys = append(keys, scw.ScalewayKeyDefinition{Key: key.PublicKey + " " + key.Name})
+
+	return i.mutator.Do(nil, "add ssh key", cloud.Params{"name": key.Name, "fingerprint": key.Fingerprint, "user": account.ID}, func() error {
+		return i.client.PatchUserSSHKey(account.ID, scw.ScalewayUserPatchSSHKeyDefinition{SSHPublicKeys: keys})
+	})
 }
 
 func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
@@ -105,11 +135,10 @@ func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID str
 		return false, err
 	}
 	_, err = serverByID(i.client, id)
-	if err == nil {
-		return true, nil
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
 	}
-
-	return false, nil
+	return err == nil, err
 }
 
 func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID string) (bool, error) {
@@ -117,16 +146,26 @@ func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID
 }
 
 func serverByID(client *scw.ScalewayAPI, id string) (*scw.ScalewayServer, error) {
-	return client.GetServer(id)
+	server, err := client.GetServer(id)
+	if e, ok := err.(scw.ScalewayAPIError); ok && e.StatusCode == http.StatusNotFound {
+		return nil, cloudprovider.InstanceNotFound
+	}
+	return server, err
 }
 
-func serverByName(client *scw.ScalewayAPI, nodeName types.NodeName) (*scw.ScalewayServer, error) {
+// serverByName returns the server called nodeName. If clusterID is not empty,
+// only servers tagged with its cluster tag are considered, so that servers of
+// other clusters with the same name do not collide.
+func serverByName(client *scw.ScalewayAPI, clusterID string, nodeName types.NodeName) (*scw.ScalewayServer, error) {
 	servers, err := client.GetServers(true, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, server := range *servers {
+		if clusterID != "" && !cloud.HasClusterTag(server.Tags, clusterID) {
+			continue
+		}
 		if strings.ToLower(server.Name) == string(nodeName) {
 			return &server, nil
 		}
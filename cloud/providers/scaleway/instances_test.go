@@ -0,0 +1,183 @@
+package scaleway
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+// TestMain points $HOME to a temporary directory while the tests run, as the
+// Scaleway client keeps a cache in it.
+func TestMain(m *testing.M) {
+	home, err := ioutil.TempDir("", "scaleway")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	code := m.Run()
+	os.Setenv("HOME", oldHome)
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+func testServer(id, name, zone, privateIP, publicIP string) scw.ScalewayServer {
+	server := scw.ScalewayServer{
+		Identifier:     id,
+		Name:           name,
+		CommercialType: "START1-S",
+		PrivateIP:      privateIP,
+		PublicAddress:  scw.ScalewayIPAddress{IP: publicIP},
+	}
+	server.Location.ZoneID = zone
+	return server
+}
+
+func newTestCloud(t *testing.T) (*Cloud, *standin.Scaleway) {
+	api := standin.NewScaleway(
+		testServer("5e5a7b1b", "Master", "par1", "10.1.0.10", "51.15.0.10"),
+		testServer("9a7c4e2d", "node-1", "ams1", "10.2.0.11", "51.15.0.11"),
+	)
+	api.User = scw.ScalewayUserDefinition{ID: "b1e3f5a7", Email: "alice@example.com"}
+	config := fmt.Sprintf("organization: org\ntoken: secret\nregion: par1\nendpoints:\n  par1: %s\n  ams1: %s\n  account: %s\n",
+		api.ComputeEndpoint("par1"), api.ComputeEndpoint("ams1"), api.AccountEndpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c, api
+}
+
+func TestServerByName(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	// servers are looked up in all zones, by lower case name
+	for name, id := range map[string]string{"master": "5e5a7b1b", "node-1": "9a7c4e2d"} {
+		server, err := serverByName(c.client, "", types.NodeName(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if server.Identifier != id {
+			t.Errorf("expected server %s for %s, got %s", id, name, server.Identifier)
+		}
+	}
+
+	if _, err := serverByName(c.client, "", "node-2"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	addresses, err := c.instances.NodeAddressesByProviderID(ctx, "scaleway://5e5a7b1b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "Master"},
+		{Type: v1.NodeInternalIP, Address: "10.1.0.10"},
+		{Type: v1.NodeExternalIP, Address: "51.15.0.10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	zone, err := c.zones.GetZoneByNodeName(ctx, "node-1")
+	if err != nil || zone.Region != "ams1" {
+		t.Errorf("expected region ams1, got %q (%v)", zone.Region, err)
+	}
+
+	exists, err := c.instances.InstanceExistsByProviderID(ctx, "scaleway://missing")
+	if err != nil || exists {
+		t.Errorf("expected scaleway://missing to not exist, got %v (%v)", exists, err)
+	}
+}
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOyiAiEqGOnlV2yPeHsqMmGnUhpcNTROCwsmk0vxihM+ alice@example.com"
+
+func TestAddSSHKeyToAllInstances(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	existing := scw.ScalewayKeyDefinition{Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDcQy7wEfMQT8WOVxNKcscvxZ2vZJdhDeRuPzEr/g2eg bob"}
+	api.User.SSHPublicKeys = []scw.ScalewayKeyDefinition{existing}
+
+	for i := 0; i < 2; i++ {
+		if err := c.instances.AddSSHKeyToAllInstances(ctx, "alice", []byte(testSSHKey)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := api.User.SSHPublicKeys
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 ssh keys, got %v", keys)
+	}
+	if keys[0] != existing {
+		t.Errorf("expected existing key to be kept, got %v", keys[0])
+	}
+	if !strings.HasPrefix(keys[1].Key, strings.TrimSuffix(testSSHKey, "alice@example.com")+"alice-") {
+		t.Errorf("unexpected ssh key %v", keys[1])
+	}
+}
+
+func TestNodeLabels(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+	labels := nodeLabels(c.client, "")
+
+	api.Servers[0].Arch = "x86_64"
+	got, err := labels(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"scaleway.pharmer.dev/arch": "x86_64"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected labels %v, got %v", expected, got)
+	}
+
+	// older servers only report the architecture of their image
+	api.Servers[0].Arch = ""
+	api.Servers[0].Image.Arch = "arm"
+	got, err = labels(ctx, &v1.Node{Spec: v1.NodeSpec{ProviderID: "scaleway://5e5a7b1b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"scaleway.pharmer.dev/arch": "arm"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected labels %v, got %v", expected, got)
+	}
+}
+
+func TestDualStackAddresses(t *testing.T) {
+	server := testServer("5e5a7b1b", "master", "par1", "10.1.0.10", "51.15.0.10")
+	server.IPV6 = &scw.ScalewayIPV6Definition{Address: "2001:bc8:1::10"}
+	addresses, err := nodeAddresses(&server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.1.0.10"},
+		{Type: v1.NodeExternalIP, Address: "51.15.0.10"},
+		{Type: v1.NodeExternalIP, Address: "2001:bc8:1::10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+}
@@ -0,0 +1,41 @@
+package scaleway
+
+import (
+	"context"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// nodeLabels labels Nodes with the architecture of their server. Servers
+// created from older images only report the architecture of the image.
+func nodeLabels(client *scw.ScalewayAPI, clusterID string) cloud.NodeLabelsFunc {
+	return func(_ context.Context, node *v1.Node) (map[string]string, error) {
+		server, err := nodeServer(client, clusterID, node)
+		if err != nil {
+			return nil, err
+		}
+		arch := server.Arch
+		if arch == "" {
+			arch = server.Image.Arch
+		}
+		return map[string]string{
+			cloud.LabelKey(ProviderName, "arch"): arch,
+		}, nil
+	}
+}
+
+// nodeServer returns the server of node, by its provider ID if it is set and
+// by its name otherwise.
+func nodeServer(client *scw.ScalewayAPI, clusterID string, node *v1.Node) (*scw.ScalewayServer, error) {
+	if node.Spec.ProviderID == "" {
+		return serverByName(client, clusterID, types.NodeName(node.Name))
+	}
+	id, err := serverIDFromProviderID(node.Spec.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	return serverByID(client, id)
+}
@@ -11,11 +11,14 @@ import (
 
 type loadbalancers struct {
 	client *scw.ScalewayAPI
+	// securityGroup opens the NodePorts of the Services, nil if the security
+	// group is not managed
+	securityGroup *securityGroup
 }
 
 // newLoadbalancers returns a cloudprovider.LoadBalancer whose concrete type is a *loadbalancer.
-func newLoadbalancers(client *scw.ScalewayAPI) cloudprovider.LoadBalancer {
-	return &loadbalancers{client: client}
+func newLoadbalancers(client *scw.ScalewayAPI, securityGroup *securityGroup) cloudprovider.LoadBalancer {
+	return &loadbalancers{client: client, securityGroup: securityGroup}
 }
 
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
@@ -32,12 +35,19 @@ func (l *loadbalancers) GetLoadBalancer(_ context.Context, clusterName string, s
 }
 
 // EnsureLoadBalancer ensures that the cluster is running a load balancer for
-// service.
+// service. Scaleway has no load balancers, but if the security group is
+// managed, the NodePorts of service are opened to its source ranges and an
+// empty status is returned.
 //
 // EnsureLoadBalancer will not modify service or nodes.
 func (l *loadbalancers) EnsureLoadBalancer(_ context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
-	return nil, cloud.ErrLBUnsupported
-
+	if l.securityGroup == nil {
+		return nil, cloud.ErrLBUnsupported
+	}
+	if err := l.securityGroup.Sync(service, false); err != nil {
+		return nil, err
+	}
+	return &v1.LoadBalancerStatus{}, nil
 }
 
 // UpdateLoadBalancer updates the load balancer for service to balance across
@@ -45,7 +55,10 @@ func (l *loadbalancers) EnsureLoadBalancer(_ context.Context, clusterName string
 //
 // UpdateLoadBalancer will not modify service or nodes.
 func (l *loadbalancers) UpdateLoadBalancer(_ context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
-	return cloud.ErrLBUnsupported
+	if l.securityGroup == nil {
+		return cloud.ErrLBUnsupported
+	}
+	return l.securityGroup.Sync(service, false)
 }
 
 // EnsureLoadBalancerDeleted deletes the specified loadbalancer if it exists.
@@ -54,5 +67,8 @@ func (l *loadbalancers) UpdateLoadBalancer(_ context.Context, clusterName string
 //
 // EnsureLoadBalancerDeleted will not modify service.
 func (l *loadbalancers) EnsureLoadBalancerDeleted(_ context.Context, clusterName string, service *v1.Service) error {
-	return cloud.ErrLBUnsupported
+	if l.securityGroup == nil {
+		return cloud.ErrLBUnsupported
+	}
+	return l.securityGroup.Sync(service, true)
 }
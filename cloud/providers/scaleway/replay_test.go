@@ -0,0 +1,59 @@
+package scaleway
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud/cassette"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+// defaultEndpoints are the endpoints of the Scaleway client before tests
+// override them.
+var defaultEndpoints = Endpoints{Par1: scw.ComputeAPIPar1, Ams1: scw.ComputeAPIAms1}
+
+func TestReplay(t *testing.T) {
+	rec, config, node, err := cassette.Open("testdata/replay.yaml", func(config []byte) ([]string, error) {
+		cred := &Credential{}
+		err := yaml.Unmarshal(config, cred)
+		return []string{cred.Token}, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the Scaleway client does not accept an HTTP client, so its requests
+	// are routed through proxies to the configured endpoints
+	cred := &Credential{}
+	if err := yaml.Unmarshal(config, cred); err != nil {
+		t.Fatal(err)
+	}
+	upstream := defaultEndpoints
+	if cred.Endpoints.Par1 != "" {
+		upstream.Par1 = cred.Endpoints.Par1
+	}
+	if cred.Endpoints.Ams1 != "" {
+		upstream.Ams1 = cred.Endpoints.Ams1
+	}
+	par1, ams1 := rec.Proxy(upstream.Par1), rec.Proxy(upstream.Ams1)
+	defer par1.Close()
+	defer ams1.Close()
+	cred.Endpoints.Par1, cred.Endpoints.Ams1 = par1.URL, ams1.URL
+	config, err = yaml.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newCloud(bytes.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conformance.RunNode(t, c, types.NodeName(node))
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,367 @@
+package scaleway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/appscode/go/log"
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// allSources is the source of the rule that drops the traffic to a NodePort
+// that its accept rules do not match, as security groups accept inbound
+// traffic by default.
+const allSources = "0.0.0.0/0"
+
+// portKey identifies the NodePort of a security group rule.
+type portKey struct {
+	protocol string
+	port     int
+}
+
+// portRule is a rule of a NodePort: action is accept or drop.
+type portRule struct {
+	action string
+	source string
+}
+
+// securityGroup maintains the security group of the cluster in the zone of the
+// client. It opens the NodePorts of LoadBalancer Services to their
+// loadBalancerSourceRanges: each NodePort gets an accept rule per source range,
+// followed by a drop rule for all other sources. Only the rules for single
+// TCP and UDP ports in the NodePort range are owned, other rules of the group
+// are left alone.
+type securityGroup struct {
+	client    *scw.ScalewayAPI
+	mutator   *cloud.Mutator
+	nodePorts cloud.PortRange
+	// listServices lists the Services of the cluster, it is set on
+	// initialization
+	listServices func() ([]v1.Service, error)
+}
+
+func newSecurityGroup(client *scw.ScalewayAPI, mutator *cloud.Mutator) *securityGroup {
+	return &securityGroup{client: client, mutator: mutator, nodePorts: cloud.DefaultNodePortRange}
+}
+
+// Initialize sets up the client listing the Services of the cluster.
+func (g *securityGroup) Initialize(clientBuilder cloudprovider.ControllerClientBuilder) {
+	client := clientBuilder.ClientOrDie(ProviderName + "-security-group")
+	g.listServices = func() ([]v1.Service, error) {
+		services, err := client.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return services.Items, nil
+	}
+}
+
+// securityGroupName returns the name of the security group of the cluster,
+// which identifies it.
+func securityGroupName(clusterID string) string {
+	return cloud.ClusterTag(clusterID)
+}
+
+// Sync reconciles the NodePort rules of the security group with the
+// LoadBalancer Services of the cluster, taking service as it is passed by the
+// service controller, or as deleted. The security group is created if it does
+// not exist and attached to the servers of the cluster in the organization's
+// default security group.
+func (g *securityGroup) Sync(service *v1.Service, deleted bool) error {
+	if g.mutator.ClusterID() == "" {
+		return cloud.ErrNoClusterID
+	}
+	if g.listServices == nil {
+		return fmt.Errorf("security group is not initialized")
+	}
+	desired, errs, err := g.desiredRules(service, deleted)
+	if err != nil {
+		return err
+	}
+
+	groups, err := g.client.GetSecurityGroups()
+	if err != nil {
+		return err
+	}
+	groupID, err := g.ensureGroup(groups.SecurityGroups)
+	if err != nil || groupID == "" {
+		// the group was not created in dry-run mode
+		return err
+	}
+
+	rules, err := g.client.GetSecurityGroupRules(groupID)
+	if err != nil {
+		return err
+	}
+	current := g.ownedRules(rules.Rules)
+
+	ports := map[portKey]bool{}
+	for key := range current {
+		ports[key] = true
+	}
+	for key := range desired {
+		ports[key] = true
+	}
+	for _, key := range sortedPorts(ports) {
+		if err := g.syncPort(service, groupID, key, current[key], desired[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// attach the group once its rules are in place
+	if err := g.attach(service, groupID, groups.SecurityGroups); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// desiredRules returns the rules of the NodePorts of the LoadBalancer
+// Services, with service replacing the listed version of itself. Services
+// with invalid source ranges are skipped, their errors are returned along
+// with the rules of the other Services.
+func (g *securityGroup) desiredRules(service *v1.Service, deleted bool) (map[portKey][]portRule, []error, error) {
+	services, err := g.listServices()
+	if err != nil {
+		return nil, nil, err
+	}
+	var errs []error
+	var nodePortRules []cloud.NodePortRule
+	add := func(s *v1.Service) {
+		rules, err := cloud.NodePortRules(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %s/%s: %v", s.Namespace, s.Name, err))
+			return
+		}
+		nodePortRules = append(nodePortRules, rules...)
+	}
+	for i := range services {
+		if services[i].Namespace != service.Namespace || services[i].Name != service.Name {
+			add(&services[i])
+		}
+	}
+	if !deleted {
+		add(service)
+	}
+	cloud.SortNodePortRules(nodePortRules)
+
+	desired := map[portKey][]portRule{}
+	for _, r := range nodePortRules {
+		key := portKey{protocol: r.Protocol, port: r.Port}
+		desired[key] = append(desired[key], portRule{action: "accept", source: r.Source})
+	}
+	for key, rules := range desired {
+		if !acceptsAll(rules) {
+			desired[key] = append(rules, portRule{action: "drop", source: allSources})
+		}
+	}
+	return desired, errs, nil
+}
+
+func acceptsAll(rules []portRule) bool {
+	for _, r := range rules {
+		if r.source == allSources {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedRules returns the owned inbound rules of the group by NodePort, in the
+// order of their position.
+func (g *securityGroup) ownedRules(rules []scw.ScalewaySecurityGroupRule) map[portKey][]scw.ScalewaySecurityGroupRule {
+	rules = append([]scw.ScalewaySecurityGroupRule(nil), rules...)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Position < rules[j].Position })
+
+	owned := map[portKey][]scw.ScalewaySecurityGroupRule{}
+	for _, r := range rules {
+		protocol := strings.ToLower(r.Protocol)
+		if r.Direction != "inbound" || (protocol != "tcp" && protocol != "udp") || !g.nodePorts.Contains(r.DestPortFrom) {
+			continue
+		}
+		if r.DestPortTo != "" && r.DestPortTo != fmt.Sprint(r.DestPortFrom) {
+			continue
+		}
+		key := portKey{protocol: protocol, port: r.DestPortFrom}
+		owned[key] = append(owned[key], r)
+	}
+	return owned
+}
+
+// syncPort replaces the rules of a NodePort if they differ from the desired
+// ones. All of them are recreated, as new rules are appended to the group and
+// the drop rule must come last. The new rules are created before the current
+// ones are deleted, so that the NodePort is never left open to all sources.
+func (g *securityGroup) syncPort(service *v1.Service, groupID string, key portKey, current []scw.ScalewaySecurityGroupRule, desired []portRule) error {
+	if len(current) == len(desired) {
+		equal := true
+		for i := range current {
+			if current[i].Action != desired[i].action || current[i].IPRange != desired[i].source {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return nil
+		}
+	}
+
+	port := fmt.Sprintf("%s/%d", key.protocol, key.port)
+	for _, r := range desired {
+		err := g.mutator.Do(service, "create security group rule", cloud.Params{"group": groupID, "port": port, "action": r.action, "source": r.source}, func() error {
+			return g.client.PostSecurityGroupRule(groupID, scw.ScalewayNewSecurityGroupRule{
+				Action:       r.action,
+				Direction:    "inbound",
+				IPRange:      r.source,
+				Protocol:     strings.ToUpper(key.protocol),
+				DestPortFrom: key.port,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, r := range current {
+		err := g.mutator.Destroy(service, "delete security group rule", cloud.Params{"group": groupID, "port": port, "action": r.Action, "source": r.IPRange}, func() error {
+			return g.client.DeleteSecurityGroupRule(groupID, r.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Infof("%s: set rules of NodePort %s in security group %s to %v", ProviderName, port, groupID, desired)
+	return nil
+}
+
+// ListOwned lists the NodePort rules of the security group of clusterID in
+// the zone of the client. The group itself is kept, it may hold rules added by
+// hand.
+func (g *securityGroup) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	groups, err := g.client.GetSecurityGroups()
+	if err != nil {
+		return nil, err
+	}
+	var resources []cloud.OwnedResource
+	for _, group := range groups.SecurityGroups {
+		if group.Name != securityGroupName(clusterID) {
+			continue
+		}
+		rules, err := g.client.GetSecurityGroupRules(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		for key, owned := range g.ownedRules(rules.Rules) {
+			for _, r := range owned {
+				resources = append(resources, cloud.OwnedResource{Kind: "security group rule", ID: group.ID + "/" + r.ID, NodePort: key.port})
+			}
+		}
+	}
+	return resources, nil
+}
+
+// DeleteOwned deletes a NodePort rule listed by ListOwned.
+func (g *securityGroup) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	parts := strings.SplitN(resource.ID, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid security group rule %s", resource.ID)
+	}
+	return g.client.DeleteSecurityGroupRule(parts[0], parts[1])
+}
+
+// ensureGroup returns the ID of the security group of the cluster, creating
+// it if it does not exist. The ID is empty if the group would be created in
+// dry-run mode.
+func (g *securityGroup) ensureGroup(groups []scw.ScalewaySecurityGroups) (string, error) {
+	name := securityGroupName(g.mutator.ClusterID())
+	for _, group := range groups {
+		if group.Name == name {
+			return group.ID, nil
+		}
+	}
+
+	err := g.mutator.Do(nil, "create security group", cloud.Params{"name": name}, func() error {
+		return g.client.PostSecurityGroup(scw.ScalewayNewSecurityGroup{
+			Organization: g.client.Organization,
+			Name:         name,
+			Description:  "NodePorts of the LoadBalancer Services of Kubernetes cluster " + g.mutator.ClusterID(),
+		})
+	})
+	if err != nil || cloud.DryRun() {
+		return "", err
+	}
+
+	// the API does not return the created group
+	created, err := g.client.GetSecurityGroups()
+	if err != nil {
+		return "", err
+	}
+	for _, group := range created.SecurityGroups {
+		if group.Name == name {
+			log.Infof("%s: created security group %s", ProviderName, group.ID)
+			return group.ID, nil
+		}
+	}
+	return "", fmt.Errorf("security group %s not found after creating it", name)
+}
+
+// attach attaches the security group to the servers of the cluster in the
+// zone of the client that are in the organization's default security group.
+// Servers in other security groups are left alone.
+func (g *securityGroup) attach(service *v1.Service, groupID string, groups []scw.ScalewaySecurityGroups) error {
+	defaultGroup := ""
+	for _, group := range groups {
+		if group.OrganizationDefault {
+			defaultGroup = group.ID
+		}
+	}
+
+	servers, err := g.client.GetServers(true, 0)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, server := range *servers {
+		if !cloud.HasClusterTag(server.Tags, g.mutator.ClusterID()) || server.Location.ZoneID != g.client.Region {
+			continue
+		}
+		switch server.SecurityGroup.Identifier {
+		case groupID:
+			continue
+		case "", defaultGroup:
+		default:
+			log.Warningf("%s: server %s is in security group %s, not attaching security group %s", ProviderName, server.Identifier, server.SecurityGroup.Identifier, groupID)
+			continue
+		}
+		err := g.mutator.Do(service, "attach security group", cloud.Params{"group": groupID, "server": server.Identifier}, func() error {
+			return g.client.PatchServer(server.Identifier, scw.ScalewayServerPatchDefinition{
+				SecurityGroup: &scw.ScalewaySecurityGroup{Identifier: groupID},
+			})
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("%s: attached security group %s to server %s", ProviderName, groupID, server.Identifier)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func sortedPorts(ports map[portKey]bool) []portKey {
+	keys := make([]portKey, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].port != keys[j].port {
+			return keys[i].port < keys[j].port
+		}
+		return keys[i].protocol < keys[j].protocol
+	})
+	return keys
+}
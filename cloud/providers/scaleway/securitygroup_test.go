@@ -0,0 +1,222 @@
+package scaleway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func loadBalancerService(name string, nodePort int32, sourceRanges ...string) v1.Service {
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1.ServiceSpec{
+			Type:                     v1.ServiceTypeLoadBalancer,
+			Ports:                    []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80, NodePort: nodePort}},
+			LoadBalancerSourceRanges: sourceRanges,
+		},
+	}
+}
+
+// newSecurityGroupTestCloud returns a cloud of the cluster prod managing its
+// security group, whose Services are services.
+func newSecurityGroupTestCloud(t *testing.T, services *[]v1.Service, servers ...scw.ScalewayServer) (*Cloud, *standin.Scaleway) {
+	api := standin.NewScaleway(servers...)
+	api.SecurityGroups = map[string][]scw.ScalewaySecurityGroups{
+		"par1": {{ID: "default", Name: "Default security group", OrganizationDefault: true}, {ID: "custom", Name: "custom"}},
+	}
+	config := fmt.Sprintf("organization: org\ntoken: secret\nregion: par1\nclusterID: prod\nmanageSecurityGroup: true\nendpoints:\n  par1: %s\n  ams1: %s\n  account: %s\n",
+		api.ComputeEndpoint("par1"), api.ComputeEndpoint("ams1"), api.AccountEndpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	c.securityGroup.listServices = func() ([]v1.Service, error) {
+		return *services, nil
+	}
+	return c, api
+}
+
+func clusterServer(id, zone, cluster, securityGroup string) scw.ScalewayServer {
+	server := testServer(id, id, zone, "10.1.0.10", "51.15.0.10")
+	server.Tags = []string{cloud.ClusterTag(cluster)}
+	server.SecurityGroup.Identifier = securityGroup
+	return server
+}
+
+// ruleStrings returns the rules of group in the order of their position as
+// <action> <protocol>/<port> from <source>.
+func ruleStrings(api *standin.Scaleway, group string) []string {
+	var rules []string
+	for _, r := range api.SecurityGroupRules[group] {
+		rules = append(rules, fmt.Sprintf("%s %s/%d from %s", r.Action, strings.ToLower(r.Protocol), r.DestPortFrom, r.IPRange))
+	}
+	return rules
+}
+
+// clusterGroup returns the ID of the security group of the cluster prod.
+func clusterGroup(t *testing.T, api *standin.Scaleway) string {
+	for _, group := range api.SecurityGroups["par1"] {
+		if group.Name == cloud.ClusterTag("prod") {
+			return group.ID
+		}
+	}
+	t.Fatalf("expected security group of the cluster, got %v", api.SecurityGroups)
+	return ""
+}
+
+func TestSecurityGroup(t *testing.T) {
+	services := []v1.Service{
+		loadBalancerService("api", 30443),
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "internal"}, Spec: v1.ServiceSpec{Type: v1.ServiceTypeClusterIP}},
+	}
+	c, api := newSecurityGroupTestCloud(t, &services,
+		clusterServer("master", "par1", "prod", "default"),
+		clusterServer("node-1", "par1", "prod", "custom"),
+		clusterServer("node-2", "ams1", "prod", ""),
+		clusterServer("other", "par1", "staging", "default"),
+	)
+	defer api.Close()
+	lb, _ := c.LoadBalancer()
+
+	web := loadBalancerService("web", 30080, "203.0.113.0/24", "198.51.100.0/24")
+	status, err := lb.EnsureLoadBalancer(context.Background(), "kubernetes", &web, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil || len(status.Ingress) != 0 {
+		t.Errorf("expected empty status, got %v", status)
+	}
+	group := clusterGroup(t, api)
+	expected := []string{
+		"accept tcp/30080 from 198.51.100.0/24",
+		"accept tcp/30080 from 203.0.113.0/24",
+		"drop tcp/30080 from 0.0.0.0/0",
+		"accept tcp/30443 from 0.0.0.0/0",
+	}
+	if rules := ruleStrings(api, group); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	// servers in another group, zone or cluster are left alone
+	for i, want := range []string{group, "custom", "", "default"} {
+		if got := api.Servers[i].SecurityGroup.Identifier; got != want {
+			t.Errorf("expected server %s in security group %q, got %q", api.Servers[i].Identifier, want, got)
+		}
+	}
+
+	// rules outside of the NodePort range are kept, the rules of a changed
+	// port are replaced and those of deleted Services removed
+	api.SecurityGroupRules[group] = append(api.SecurityGroupRules[group], scw.ScalewaySecurityGroupRule{
+		ID: "ssh", Action: "accept", Direction: "inbound", Protocol: "TCP", DestPortFrom: 22, IPRange: "0.0.0.0/0", Position: 100,
+	})
+	web = loadBalancerService("web", 30080, "203.0.113.0/24")
+	services = append(services, web)
+	if err := lb.UpdateLoadBalancer(context.Background(), "kubernetes", &web, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := lb.EnsureLoadBalancerDeleted(context.Background(), "kubernetes", &services[0]); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		"accept tcp/22 from 0.0.0.0/0",
+		"accept tcp/30080 from 203.0.113.0/24",
+		"drop tcp/30080 from 0.0.0.0/0",
+	}
+	if rules := ruleStrings(api, group); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	if len(api.SecurityGroups["par1"]) != 3 {
+		t.Errorf("expected the security group to be reused, got %v", api.SecurityGroups)
+	}
+}
+
+func TestSecurityGroupInvalidSourceRange(t *testing.T) {
+	services := []v1.Service{loadBalancerService("broken", 30081, "203.0.113.0"), loadBalancerService("api", 30443)}
+	c, api := newSecurityGroupTestCloud(t, &services)
+	defer api.Close()
+
+	// the Service with the invalid source range is skipped, the others are
+	// still synced
+	web := loadBalancerService("web", 30080, "203.0.113.0/24")
+	err := c.securityGroup.Sync(&web, false)
+	if err == nil || !strings.Contains(err.Error(), "default/broken") {
+		t.Errorf("expected error for service default/broken, got %v", err)
+	}
+	expected := []string{
+		"accept tcp/30080 from 203.0.113.0/24",
+		"drop tcp/30080 from 0.0.0.0/0",
+		"accept tcp/30443 from 0.0.0.0/0",
+	}
+	if rules := ruleStrings(api, clusterGroup(t, api)); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestCollectOrphanedSecurityGroupRules(t *testing.T) {
+	services := []v1.Service{loadBalancerService("web", 30080, "203.0.113.0/24"), loadBalancerService("api", 30443)}
+	c, api := newSecurityGroupTestCloud(t, &services)
+	defer api.Close()
+	if err := c.securityGroup.Sync(&services[0], false); err != nil {
+		t.Fatal(err)
+	}
+	group := clusterGroup(t, api)
+	api.SecurityGroupRules[group] = append(api.SecurityGroupRules[group], scw.ScalewaySecurityGroupRule{
+		ID: "ssh", Action: "accept", Direction: "inbound", Protocol: "TCP", DestPortFrom: 22, IPRange: "0.0.0.0/0", Position: 100,
+	})
+
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c.securityGroup)
+	if err := collector.Collect(context.Background(), services[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"accept tcp/30080 from 203.0.113.0/24",
+		"drop tcp/30080 from 0.0.0.0/0",
+		"accept tcp/22 from 0.0.0.0/0",
+	}
+	if rules := ruleStrings(api, group); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestSecurityGroupDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	services := []v1.Service{}
+	c, api := newSecurityGroupTestCloud(t, &services, clusterServer("master", "par1", "prod", "default"))
+	defer api.Close()
+
+	web := loadBalancerService("web", 30080)
+	if err := c.securityGroup.Sync(&web, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.SecurityGroups["par1"]) != 2 || api.Servers[0].SecurityGroup.Identifier != "default" {
+		t.Errorf("expected no changes in dry-run mode, got groups %v", api.SecurityGroups)
+	}
+}
+
+func TestLoadBalancerUnsupported(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	lb, _ := c.LoadBalancer()
+
+	web := loadBalancerService("web", 30080)
+	if _, err := lb.EnsureLoadBalancer(context.Background(), "kubernetes", &web, nil); err != cloud.ErrLBUnsupported {
+		t.Errorf("expected %v without a managed security group, got %v", cloud.ErrLBUnsupported, err)
+	}
+}
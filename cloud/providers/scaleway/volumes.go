@@ -0,0 +1,29 @@
+package scaleway
+
+import (
+	"context"
+	"net/http"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// GetLabelsForVolume returns the region label of the volume of pv. Volumes
+// are looked up in the zone of the client, which is also their region.
+// PersistentVolumes that are not backed by a volume are not labeled.
+func (c *Cloud) GetLabelsForVolume(_ context.Context, pv *v1.PersistentVolume) (map[string]string, error) {
+	id := cloud.VolumeID(pv)
+	if id == "" {
+		return nil, nil
+	}
+	_, err := c.client.GetVolume(id)
+	if e, ok := err.(scw.ScalewayAPIError); ok && e.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cloud.VolumeLabels(cloudprovider.Zone{Region: c.client.Region}), nil
+}
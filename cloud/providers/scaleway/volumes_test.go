@@ -0,0 +1,37 @@
+package scaleway
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGetLabelsForVolume(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	api.Volumes = map[string][]scw.ScalewayVolume{
+		"par1": {{Identifier: "7d4a8b5e", Name: "pvc-1"}},
+		"ams1": {{Identifier: "0c1e2f3a", Name: "pvc-2"}},
+	}
+
+	pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{
+		PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "par1/7d4a8b5e"}},
+	}}
+	labels, err := c.GetLabelsForVolume(context.Background(), pv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"failure-domain.beta.kubernetes.io/region": "par1"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+
+	// volumes of other zones are not found by the client
+	pv.Spec.CSI.VolumeHandle = "ams1/0c1e2f3a"
+	if labels, err := c.GetLabelsForVolume(context.Background(), pv); err != nil || labels != nil {
+		t.Errorf("expected volume of another zone not to be labeled, got %v (%v)", labels, err)
+	}
+}
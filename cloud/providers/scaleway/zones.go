@@ -10,12 +10,13 @@ import (
 )
 
 type zones struct {
-	client *scw.ScalewayAPI
-	region string
+	client    *scw.ScalewayAPI
+	region    string
+	clusterID string
 }
 
-func newZones(client *scw.ScalewayAPI, region string) cloudprovider.Zones {
-	return &zones{client, region}
+func newZones(client *scw.ScalewayAPI, region, clusterID string) cloudprovider.Zones {
+	return &zones{client, region, clusterID}
 }
 
 func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
@@ -36,7 +37,7 @@ func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudp
 }
 
 func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
-	server, err := serverByName(z.client, nodeName)
+	server, err := serverByName(z.client, z.clusterID, nodeName)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
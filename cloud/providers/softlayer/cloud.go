@@ -9,6 +9,7 @@ import (
 	"github.com/softlayer/softlayer-go/services"
 	"github.com/softlayer/softlayer-go/session"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
 const (
@@ -19,6 +20,11 @@ type Credential struct {
 	UserName string `json:"username" yaml:"username"`
 	ApiKey   string `json:"apiKey" yaml:"apiKey"`
 	Zone     string `json:"zone" yaml:"zone"`
+	// Endpoint overrides the REST endpoint of the SoftLayer API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// ClusterID limits the controller to guests tagged with the cluster tag,
+	// see cloud.ClusterTag, and to the resources it created for the cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
 }
 
 type Cloud struct {
@@ -28,9 +34,14 @@ type Cloud struct {
 	instances     cloudprovider.Instances
 	zones         cloudprovider.Zones
 	loadbalancers cloudprovider.LoadBalancer
+
+	mutator   *cloud.Mutator
+	collector *cloud.Collector
+	labeler   *cloud.NodeLabeler
 }
 
 func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, session.DefaultEndpoint)
 	cloudprovider.RegisterCloudProvider(
 		ProviderName,
 		func(config io.Reader) (cloudprovider.Interface, error) {
@@ -51,20 +62,30 @@ func newCloud(config io.Reader) (*Cloud, error) {
 		return nil, err
 	}
 
-	sess := session.New(cred.UserName, cred.ApiKey)
+	sess := session.New(cred.UserName, cred.ApiKey, cred.Endpoint)
+	sess.HTTPClient = cloud.HTTPClient()
 	virtualServiceClient := services.GetVirtualGuestService(sess)
 	accountServiceClient := services.GetAccountService(sess)
 
+	mutator := cloud.NewMutator(ProviderName, cred.ClusterID)
+	collector := cloud.NewCollector(mutator)
 	return &Cloud{
 		virtualServiceClient: virtualServiceClient,
 		accountServiceClient: accountServiceClient,
 
-		instances:     newInstances(virtualServiceClient, accountServiceClient),
-		zones:         newZones(virtualServiceClient, accountServiceClient, cred.Zone),
+		instances:     newInstances(virtualServiceClient, accountServiceClient, cred.ClusterID),
+		zones:         newZones(virtualServiceClient, accountServiceClient, cred.Zone, cred.ClusterID),
 		loadbalancers: newLoadbalancers(virtualServiceClient, accountServiceClient),
+
+		mutator:   mutator,
+		collector: collector,
+		labeler:   cloud.NewNodeLabeler(ProviderName, nodeLabels(virtualServiceClient, accountServiceClient, cred.ClusterID)),
 	}, nil
 }
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+	c.labeler.Start(clientBuilder, stop)
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
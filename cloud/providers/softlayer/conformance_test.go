@@ -0,0 +1,43 @@
+package softlayer
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "master",
+				ProviderID: "softlayer://1001",
+				Type:       "2c4m",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "master"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+					{Type: v1.NodeExternalIP, Address: "169.45.0.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "dal10"},
+			},
+			{
+				Name:       "node-1",
+				ProviderID: "softlayer://1002",
+				Type:       "2c4m",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "node-1"},
+					{Type: v1.NodeInternalIP, Address: "10.0.0.11"},
+					{Type: v1.NodeExternalIP, Address: "169.45.0.11"},
+				},
+				Zone: cloudprovider.Zone{Region: "dal12"},
+			},
+		},
+		MissingName:       "node-2",
+		MissingProviderID: "softlayer://9999",
+	})
+}
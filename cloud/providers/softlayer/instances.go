@@ -3,12 +3,14 @@ package softlayer
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/softlayer/softlayer-go/datatypes"
 	"github.com/softlayer/softlayer-go/services"
+	"github.com/softlayer/softlayer-go/sl"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	cloudprovider "k8s.io/cloud-provider"
@@ -18,16 +20,18 @@ import (
 type instances struct {
 	virtualServiceClient services.Virtual_Guest
 	accountServiceClient services.Account
+
+	clusterID string
 }
 
 func newInstances(virtualServiceClient services.Virtual_Guest,
-	accountServiceClient services.Account) cloudprovider.Instances {
+	accountServiceClient services.Account, clusterID string) cloudprovider.Instances {
 	return &instances{virtualServiceClient: virtualServiceClient,
-		accountServiceClient: accountServiceClient}
+		accountServiceClient: accountServiceClient, clusterID: clusterID}
 }
 
 func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
-	vGuest, err := guestByName(i.accountServiceClient, name)
+	vGuest, err := guestByName(i.accountServiceClient, i.clusterID, name)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +70,61 @@ func nodeAddresses(virtualServiceClient services.Virtual_Guest, vGuest datatypes
 
 	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: publicIP})
 
-	return addresses, nil
+	// relational properties are only returned if they are in the mask
+	components, err := bluemix.Mask("port;primaryIpAddress;primaryVersion6IpAddressRecord.ipAddress;ipAddressBindings.ipAddress.ipAddress").GetNetworkComponents()
+	if err != nil {
+		return nil, fmt.Errorf("could not get network components: %v", err)
+	}
+	addresses = append(addresses, componentAddresses(components)...)
+
+	return cloud.NodeAddresses(addresses), nil
+}
+
+// publicPort is the port of the public network component of a guest, the
+// private one is port 0.
+const publicPort = 1
+
+func componentPort(component datatypes.Virtual_Guest_Network_Component) int {
+	if component.Port == nil {
+		return 0
+	}
+	return *component.Port
+}
+
+// componentAddresses returns the addresses of the network components, private
+// components first. The primary IPv4 address of a component is followed by its
+// IPv6 address and its other bound addresses, ordered by address.
+func componentAddresses(components []datatypes.Virtual_Guest_Network_Component) []v1.NodeAddress {
+	components = append([]datatypes.Virtual_Guest_Network_Component(nil), components...)
+	sort.SliceStable(components, func(a, b int) bool {
+		return componentPort(components[a]) < componentPort(components[b])
+	})
+
+	var addresses []v1.NodeAddress
+	for _, component := range components {
+		addressType := v1.NodeInternalIP
+		if componentPort(component) == publicPort {
+			addressType = v1.NodeExternalIP
+		}
+		if component.PrimaryIpAddress != nil {
+			addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: *component.PrimaryIpAddress})
+		}
+		if record := component.PrimaryVersion6IpAddressRecord; record != nil && record.IpAddress != nil {
+			addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: *record.IpAddress})
+		}
+
+		var bound []string
+		for _, binding := range component.IpAddressBindings {
+			if binding.IpAddress != nil && binding.IpAddress.IpAddress != nil {
+				bound = append(bound, *binding.IpAddress.IpAddress)
+			}
+		}
+		sort.Strings(bound)
+		for _, address := range bound {
+			addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: address})
+		}
+	}
+	return addresses
 }
 
 func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
@@ -74,7 +132,7 @@ func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (st
 }
 
 func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
-	vGuest, err := guestByName(i.accountServiceClient, nodeName)
+	vGuest, err := guestByName(i.accountServiceClient, i.clusterID, nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -82,7 +140,7 @@ func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (stri
 }
 
 func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
-	vGuest, err := guestByName(i.accountServiceClient, nodeName)
+	vGuest, err := guestByName(i.accountServiceClient, i.clusterID, nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -130,10 +188,10 @@ func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID str
 	}
 
 	_, err = guestByID(i.virtualServiceClient, id)
-	if err == nil {
-		return true, nil
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
 	}
-	return false, nil
+	return err == nil, err
 }
 
 func guestByID(virtualServiceClient services.Virtual_Guest, id string) (datatypes.Virtual_Guest, error) {
@@ -144,17 +202,36 @@ func guestByID(virtualServiceClient services.Virtual_Guest, id string) (datatype
 
 	vGuest, err := virtualServiceClient.Id(guestID).GetObject()
 	if err != nil {
-		return datatypes.Virtual_Guest{}, err
+		return datatypes.Virtual_Guest{}, notFoundError(err)
 	}
 	return vGuest, nil
 }
 
-func guestByName(accountServiceClient services.Account, nodeName types.NodeName) (datatypes.Virtual_Guest, error) {
+// notFoundError translates the SoftLayer error for unknown objects into
+// cloudprovider.InstanceNotFound.
+func notFoundError(err error) error {
+	if e, ok := err.(sl.Error); ok && e.Exception == "SoftLayer_Exception_ObjectNotFound" {
+		return cloudprovider.InstanceNotFound
+	}
+	return err
+}
+
+// guestByName returns the guest called nodeName. If clusterID is not empty,
+// only guests tagged with its cluster tag are considered, so that guests of
+// other clusters with the same name do not collide.
+func guestByName(accountServiceClient services.Account, clusterID string, nodeName types.NodeName) (datatypes.Virtual_Guest, error) {
+	if clusterID != "" {
+		// relational properties are only returned if they are in the mask
+		accountServiceClient = accountServiceClient.Mask("tagReferences.tag.name")
+	}
 	guests, err := accountServiceClient.GetVirtualGuests()
 	if err != nil {
 		return datatypes.Virtual_Guest{}, err
 	}
 	for _, guest := range guests {
+		if clusterID != "" && !cloud.HasClusterTag(guestTags(guest), clusterID) {
+			continue
+		}
 		if *guest.Hostname == string(nodeName) {
 			return guest, err
 		}
@@ -184,3 +261,13 @@ func guestIDFromProviderID(providerID string) (string, error) {
 
 	return split[2], nil
 }
+
+func guestTags(guest datatypes.Virtual_Guest) []string {
+	var tags []string
+	for _, ref := range guest.TagReferences {
+		if ref.Tag != nil && ref.Tag.Name != nil {
+			tags = append(tags, *ref.Tag.Name)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,221 @@
+package softlayer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/softlayer/softlayer-go/datatypes"
+	"github.com/softlayer/softlayer-go/sl"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func testGuest(id int, hostname, datacenter, privateIP, publicIP string) datatypes.Virtual_Guest {
+	return datatypes.Virtual_Guest{
+		Id:                      sl.Int(id),
+		Hostname:                sl.String(hostname),
+		StartCpus:               sl.Int(2),
+		MaxMemory:               sl.Int(4096),
+		PrimaryBackendIpAddress: sl.String(privateIP),
+		PrimaryIpAddress:        sl.String(publicIP),
+		Datacenter:              &datatypes.Location{Name: sl.String(datacenter)},
+	}
+}
+
+func newTestCloud(t *testing.T) (*Cloud, *standin.SoftLayer) {
+	api := standin.NewSoftLayer(
+		testGuest(1001, "master", "dal10", "10.0.0.10", "169.45.0.10"),
+		testGuest(1002, "node-1", "dal12", "10.0.0.11", "169.45.0.11"),
+	)
+	config := fmt.Sprintf("username: user\napiKey: secret\nzone: dal10\nendpoint: %s\n", api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c, api
+}
+
+func TestGuestByName(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+
+	guest, err := guestByName(c.accountServiceClient, "", "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *guest.Id != 1002 {
+		t.Errorf("expected guest 1002, got %d", *guest.Id)
+	}
+
+	if _, err := guestByName(c.accountServiceClient, "", "node-2"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+
+	addresses, err := c.instances.NodeAddressesByProviderID(ctx, "softlayer://1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+		{Type: v1.NodeExternalIP, Address: "169.45.0.10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	instanceType, err := c.instances.InstanceType(ctx, "node-1")
+	if err != nil || instanceType != "2c4m" {
+		t.Errorf("expected instance type 2c4m, got %q (%v)", instanceType, err)
+	}
+
+	zone, err := c.zones.GetZoneByNodeName(ctx, "node-1")
+	if err != nil || zone.Region != "dal12" {
+		t.Errorf("expected region dal12, got %q (%v)", zone.Region, err)
+	}
+
+	exists, err := c.instances.InstanceExistsByProviderID(ctx, "softlayer://9999")
+	if err != nil || exists {
+		t.Errorf("expected softlayer://9999 to not exist, got %v (%v)", exists, err)
+	}
+}
+
+func TestClusterID(t *testing.T) {
+	tagged := func(guest datatypes.Virtual_Guest, tag string) datatypes.Virtual_Guest {
+		guest.TagReferences = []datatypes.Tag_Reference{{Tag: &datatypes.Tag{Name: sl.String(tag)}}}
+		return guest
+	}
+	api := standin.NewSoftLayer(
+		tagged(testGuest(2001, "master", "dal10", "10.0.0.20", "169.45.0.20"), cloud.ClusterTag("dev")),
+		tagged(testGuest(2002, "master", "dal10", "10.0.0.21", "169.45.0.21"), cloud.ClusterTag("prod")),
+	)
+	defer api.Close()
+	ctx := context.Background()
+
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("username: user\napiKey: secret\nendpoint: %s\nclusterID: prod\n", api.Endpoint())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.mutator.ClusterID() != "prod" {
+		t.Error("expected cluster ID to be configured")
+	}
+	if id, err := c.instances.InstanceID(ctx, "master"); err != nil || id != "2002" {
+		t.Errorf("expected instance id 2002, got %q (%v)", id, err)
+	}
+}
+
+func TestNodeLabels(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	ctx := context.Background()
+	labels := nodeLabels(c.virtualServiceClient, c.accountServiceClient, "")
+
+	for node, datacenter := range map[*v1.Node]string{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}}:                                                  "dal10",
+		{ObjectMeta: metav1.ObjectMeta{Name: "node"}, Spec: v1.NodeSpec{ProviderID: "softlayer://1002"}}: "dal12",
+	} {
+		got, err := labels(ctx, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := map[string]string{"softlayer.pharmer.dev/datacenter": datacenter}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("node %s: expected labels %v, got %v", node.Name, expected, got)
+		}
+	}
+
+	if _, err := labels(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestDualStackAddresses(t *testing.T) {
+	c, api := newTestCloud(t)
+	defer api.Close()
+	api.Guests[0].NetworkComponents = []datatypes.Virtual_Guest_Network_Component{{
+		Port:                           sl.Int(1),
+		PrimaryIpAddress:               sl.String("169.45.0.10"),
+		PrimaryVersion6IpAddressRecord: &datatypes.Network_Subnet_IpAddress{IpAddress: sl.String("2607:f0d0::10")},
+	}}
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "softlayer://1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+		{Type: v1.NodeExternalIP, Address: "169.45.0.10"},
+		{Type: v1.NodeExternalIP, Address: "2607:f0d0::10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+}
+
+func TestMultiHomedAddresses(t *testing.T) {
+	defer cloud.SetAddressOptions(cloud.DefaultAddressOptions)
+
+	c, api := newTestCloud(t)
+	defer api.Close()
+	binding := func(address string) datatypes.Virtual_Guest_Network_Component_IpAddress {
+		return datatypes.Virtual_Guest_Network_Component_IpAddress{
+			IpAddress: &datatypes.Network_Subnet_IpAddress{IpAddress: sl.String(address)},
+		}
+	}
+	api.Guests[0].NetworkComponents = []datatypes.Virtual_Guest_Network_Component{
+		{
+			Port:              sl.Int(1),
+			PrimaryIpAddress:  sl.String("169.45.0.10"),
+			IpAddressBindings: []datatypes.Virtual_Guest_Network_Component_IpAddress{binding("169.45.9.7"), binding("169.45.0.20")},
+		},
+		{
+			Port:              sl.Int(0),
+			PrimaryIpAddress:  sl.String("10.0.0.10"),
+			IpAddressBindings: []datatypes.Virtual_Guest_Network_Component_IpAddress{binding("10.0.1.10")},
+		},
+	}
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "softlayer://1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+		{Type: v1.NodeExternalIP, Address: "169.45.0.10"},
+		{Type: v1.NodeInternalIP, Address: "10.0.1.10"},
+		{Type: v1.NodeExternalIP, Address: "169.45.0.20"},
+		{Type: v1.NodeExternalIP, Address: "169.45.9.7"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	cidrs, err := cloud.ParseCIDRs([]string{"169.45.9.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cloud.SetAddressOptions(cloud.AddressOptions{Families: cloud.DefaultAddressOptions.Families, ExcludeCIDRs: cidrs}); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = c.instances.NodeAddressesByProviderID(context.Background(), "softlayer://1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(addresses, expected[:5]) {
+		t.Errorf("expected addresses %v, got %v", expected[:5], addresses)
+	}
+}
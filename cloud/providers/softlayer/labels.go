@@ -0,0 +1,41 @@
+package softlayer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/softlayer/softlayer-go/services"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// nodeLabels labels Nodes with the datacenter of their guest.
+func nodeLabels(virtualServiceClient services.Virtual_Guest, accountServiceClient services.Account, clusterID string) cloud.NodeLabelsFunc {
+	return func(_ context.Context, node *v1.Node) (map[string]string, error) {
+		id, err := nodeGuestID(accountServiceClient, clusterID, node)
+		if err != nil {
+			return nil, err
+		}
+		datacenter, err := fetchDatacenterLocation(virtualServiceClient, id)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			cloud.LabelKey(ProviderName, "datacenter"): datacenter,
+		}, nil
+	}
+}
+
+// nodeGuestID returns the ID of the guest of node, from its provider ID if it
+// is set and by its name otherwise.
+func nodeGuestID(accountServiceClient services.Account, clusterID string, node *v1.Node) (string, error) {
+	if node.Spec.ProviderID != "" {
+		return guestIDFromProviderID(node.Spec.ProviderID)
+	}
+	guest, err := guestByName(accountServiceClient, clusterID, types.NodeName(node.Name))
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(*guest.Id), nil
+}
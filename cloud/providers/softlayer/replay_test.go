@@ -0,0 +1,16 @@
+package softlayer
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestReplay(t *testing.T) {
+	conformance.Replay(t, ProviderName, "testdata/replay.yaml", func(config []byte) ([]string, error) {
+		cred := &Credential{}
+		err := yaml.Unmarshal(config, cred)
+		return []string{cred.ApiKey, cred.UserName}, err
+	})
+}
@@ -13,13 +13,14 @@ type zones struct {
 	virtualServiceClient services.Virtual_Guest
 	accountServiceClient services.Account
 
-	zone string
+	zone      string
+	clusterID string
 }
 
 func newZones(virtualServiceClient services.Virtual_Guest,
-	accountServiceClient services.Account, region string) cloudprovider.Zones {
+	accountServiceClient services.Account, region, clusterID string) cloudprovider.Zones {
 	return &zones{virtualServiceClient: virtualServiceClient,
-		accountServiceClient: accountServiceClient, zone: region}
+		accountServiceClient: accountServiceClient, zone: region, clusterID: clusterID}
 }
 
 func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
@@ -42,7 +43,7 @@ func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudp
 }
 
 func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
-	vGuest, err := guestByName(z.accountServiceClient, nodeName)
+	vGuest, err := guestByName(z.accountServiceClient, z.clusterID, nodeName)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
@@ -61,7 +62,7 @@ func fetchDatacenterLocation(virtualServiceClient services.Virtual_Guest, id str
 
 	datacenter, err := virtualServiceClient.Id(guestID).GetDatacenter()
 	if err != nil {
-		return "", err
+		return "", notFoundError(err)
 	}
 	return *datacenter.Name, nil
 }
@@ -3,18 +3,38 @@ package vultr
 import (
 	"io"
 	"io/ioutil"
+	"time"
 
 	gv "github.com/JamesClonk/vultr/lib"
 	"github.com/ghodss/yaml"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
 const (
 	ProviderName = "vultr"
 )
 
+// rateLimit is the minimum interval between calls to the Vultr API, zero
+// keeps the default of the client. Tests lower it to run at full speed.
+var rateLimit time.Duration
+
 type tokenSource struct {
 	Token string `json:"token" yaml:"token"`
+	// Endpoint overrides the base URL of the Vultr API
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// MetadataURL overrides the base URL of the instance metadata service
+	MetadataURL string `json:"metadataURL,omitempty" yaml:"metadataURL,omitempty"`
+	// ClusterID limits the controller to servers tagged with the cluster tag,
+	// see cloud.ClusterTag, and to the resources it created for the cluster
+	ClusterID string `json:"clusterID,omitempty" yaml:"clusterID,omitempty"`
+	// NumericInstanceType reports the numeric plan ID, e.g. 201, as instance
+	// type instead of the plan name, e.g. vc2-1c-1gb
+	NumericInstanceType bool `json:"numericInstanceType,omitempty" yaml:"numericInstanceType,omitempty"`
+
+	firewallOptions
+	dnsOptions
+	reverseDNSOptions
 }
 
 type Cloud struct {
@@ -22,9 +42,18 @@ type Cloud struct {
 	instances     cloudprovider.Instances
 	zones         cloudprovider.Zones
 	loadbalancers cloudprovider.LoadBalancer
+	clusters      cloudprovider.Clusters
+
+	mutator    *cloud.Mutator
+	collector  *cloud.Collector
+	labeler    *cloud.NodeLabeler
+	firewall   *firewallController
+	dns        *dnsController
+	reverseDNS *reverseDNSController
 }
 
 func init() {
+	cloud.RegisterAPIEndpoint(ProviderName, gv.DefaultEndpoint)
 	cloudprovider.RegisterCloudProvider(
 		ProviderName,
 		func(config io.Reader) (cloudprovider.Interface, error) {
@@ -44,16 +73,45 @@ func newCloud(config io.Reader) (cloudprovider.Interface, error) {
 		return nil, err
 	}
 
-	vultrClient := gv.NewClient(tokenSource.Token, &gv.Options{})
+	if tokenSource.MetadataURL == "" {
+		tokenSource.MetadataURL = metadataURL
+	}
+
+	vultrClient := gv.NewClient(tokenSource.Token, &gv.Options{
+		Endpoint:       tokenSource.Endpoint,
+		HTTPClient:     cloud.HTTPClient(),
+		RateLimitation: rateLimit,
+	})
+	plans := newPlanCatalogue(vultrClient)
+	mutator := cloud.NewMutator(ProviderName, tokenSource.ClusterID)
+	collector := cloud.NewCollector(mutator)
+	firewall := newFirewallController(vultrClient, mutator, tokenSource.firewallOptions)
+	collector.Register(firewall)
+	dns := newDNSController(vultrClient, mutator, tokenSource.dnsOptions)
+	collector.Register(dns)
 	return &Cloud{
 		client:        vultrClient,
-		instances:     newInstances(vultrClient),
-		zones:         newZones(vultrClient),
+		instances:     newInstances(vultrClient, plans, mutator, tokenSource.NumericInstanceType),
+		zones:         newZones(vultrClient, tokenSource.MetadataURL, tokenSource.ClusterID),
 		loadbalancers: newLoadbalancers(vultrClient),
+		clusters:      cloud.NewClusters(listMembers(vultrClient)),
+
+		mutator:    mutator,
+		collector:  collector,
+		labeler:    cloud.NewNodeLabeler(ProviderName, nodeLabels(vultrClient, plans, tokenSource.ClusterID)),
+		firewall:   firewall,
+		dns:        dns,
+		reverseDNS: newReverseDNSController(vultrClient, mutator, tokenSource.reverseDNSOptions),
 	}, nil
 }
 
 func (c *Cloud) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	c.mutator.Initialize(clientBuilder)
+	c.collector.Start(clientBuilder, stop)
+	c.labeler.Start(clientBuilder, stop)
+	c.firewall.Start(clientBuilder, stop)
+	c.dns.Start(clientBuilder, stop)
+	c.reverseDNS.Start(clientBuilder, stop)
 }
 
 func (c *Cloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
@@ -69,7 +127,7 @@ func (c *Cloud) Zones() (cloudprovider.Zones, bool) {
 }
 
 func (c *Cloud) Clusters() (cloudprovider.Clusters, bool) {
-	return nil, false
+	return c.clusters, true
 }
 
 func (c *Cloud) Routes() (cloudprovider.Routes, bool) {
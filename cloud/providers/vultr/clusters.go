@@ -0,0 +1,32 @@
+package vultr
+
+import (
+	"context"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// listMembers lists the servers that belong to a cluster. Vultr servers have
+// a single tag, so masters are recognized by their name, see
+// cloud.ClusterTagPrefix.
+func listMembers(client *gv.Client) cloud.ListMembersFunc {
+	return func(_ context.Context, cluster string) ([]cloud.ClusterMember, error) {
+		servers, err := clusterServers(client, cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		var members []cloud.ClusterMember
+		for _, server := range servers {
+			address := server.MainIP
+			if address == "" {
+				address = server.InternalIP
+			}
+			if member, ok := cloud.NewClusterMember(server.Name, []string{server.Tag}, address); ok {
+				members = append(members, member)
+			}
+		}
+		return members, nil
+	}
+}
@@ -0,0 +1,44 @@
+package vultr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+func TestClusters(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+
+	api.Servers = append(api.Servers,
+		gv.Server{ID: "576970", Name: "prod-master", MainIP: "203.0.113.20", InternalIP: "10.99.0.20", Tag: cloud.ClusterTag("prod")},
+		gv.Server{ID: "576971", Name: "prod-node-1", MainIP: "203.0.113.21", InternalIP: "10.99.0.21", Tag: cloud.ClusterTag("prod")},
+		gv.Server{ID: "576972", Name: "dev-master", InternalIP: "10.99.0.30", Tag: cloud.ClusterTag("dev")},
+	)
+	clusters, ok := c.Clusters()
+	if !ok {
+		t.Fatal("expected clusters support")
+	}
+
+	names, err := clusters.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"dev", "prod"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, names)
+	}
+
+	for cluster, expected := range map[string]string{"prod": "203.0.113.20", "dev": "10.99.0.30"} {
+		master, err := clusters.Master(ctx, cluster)
+		if err != nil || master != expected {
+			t.Errorf("expected master %s of cluster %s, got %q (%v)", expected, cluster, master, err)
+		}
+	}
+	if _, err := clusters.Master(ctx, "k8s"); err == nil {
+		t.Error("expected error for cluster without master")
+	}
+}
@@ -0,0 +1,43 @@
+package vultr
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+
+	conformance.Run(t, c, conformance.Fixture{
+		Instances: []conformance.Instance{
+			{
+				Name:       "master",
+				ProviderID: "vultr://576965",
+				Type:       "vc2-1c-1gb",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "master"},
+					{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+				},
+				Zone: cloudprovider.Zone{Region: "1"},
+			},
+			{
+				Name:       "node-1",
+				ProviderID: "vultr://576966",
+				Type:       "vhf-2c-4gb",
+				Addresses: []v1.NodeAddress{
+					{Type: v1.NodeHostName, Address: "node-1"},
+					{Type: v1.NodeInternalIP, Address: "10.99.0.11"},
+					{Type: v1.NodeExternalIP, Address: "203.0.113.11"},
+				},
+				Zone: cloudprovider.Zone{Region: "1"},
+			},
+		},
+		MissingName:       "node-2",
+		MissingProviderID: "vultr://576967",
+	})
+}
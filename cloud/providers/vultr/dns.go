@@ -0,0 +1,426 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+const (
+	// dnsInterval is the time between two syncs of the DNS records
+	dnsInterval = time.Minute
+	// dnsTTL is the TTL of the created records, in seconds
+	dnsTTL = 300
+)
+
+// hostnameAnnotation is the annotation of LoadBalancer Services naming the
+// hostname to point at their ingress IPs, e.g. web.example.com.
+var hostnameAnnotation = cloud.LabelKey(ProviderName, "hostname")
+
+// dnsOptions configure the DNS records of LoadBalancer Services.
+type dnsOptions struct {
+	// ManageDNS creates A and AAAA records for the ingress IPs of LoadBalancer
+	// Services annotated with vultr.pharmer.dev/hostname, in the Vultr DNS
+	// domain of the hostname. A TXT record at the hostname marks it as owned
+	// by the Service, hostnames with records not owned by the cluster are left
+	// alone.
+	ManageDNS bool `json:"manageDNS,omitempty" yaml:"manageDNS,omitempty"`
+}
+
+// dnsController keeps the DNS records of the annotated LoadBalancer Services
+// in sync with their ingress IPs.
+type dnsController struct {
+	client  *gv.Client
+	mutator *cloud.Mutator
+	options dnsOptions
+}
+
+func newDNSController(client *gv.Client, mutator *cloud.Mutator, options dnsOptions) *dnsController {
+	return &dnsController{client: client, mutator: mutator, options: options}
+}
+
+// dnsTarget is a hostname of a Service and the IPs it points at.
+type dnsTarget struct {
+	service *v1.Service
+	// owner identifies the Service as <namespace>/<name>
+	owner string
+	ips   []string
+}
+
+// ownerRecord returns the data of the TXT record marking a hostname as owned
+// by the Service owner of the cluster, whose load balancer is loadBalancer.
+func ownerRecord(clusterID, owner, loadBalancer string) string {
+	return fmt.Sprintf(`"heritage=pharmer,cluster=%s,service=%s,loadbalancer=%s"`, clusterID, owner, loadBalancer)
+}
+
+// dnsOwner is the owner of a hostname recorded in its TXT record. The load
+// balancer is empty in records created by earlier versions.
+type dnsOwner struct {
+	cluster      string
+	service      string
+	loadBalancer string
+}
+
+// parseOwnerRecord returns the owner of the data of a TXT record, and false
+// if the record is not an ownership record.
+func parseOwnerRecord(data string) (dnsOwner, bool) {
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.Trim(data, `"`), ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	if fields["heritage"] != "pharmer" || fields["cluster"] == "" {
+		return dnsOwner{}, false
+	}
+	return dnsOwner{cluster: fields["cluster"], service: fields["service"], loadBalancer: fields["loadbalancer"]}, true
+}
+
+// Start runs the controller until stop is closed, if it is enabled. It needs a
+// cluster ID to tell its records apart from those of other clusters.
+func (c *dnsController) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !c.options.ManageDNS {
+		return
+	}
+	if c.mutator.ClusterID() == "" {
+		log.Warningf("%s: not managing DNS records, %v", ProviderName, cloud.ErrNoClusterID)
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-dns-controller")
+	go wait.Until(func() {
+		services, err := client.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("%s: failed to list services: %v", ProviderName, err)
+			return
+		}
+		if err := c.Sync(context.Background(), services.Items); err != nil {
+			log.Errorf("%s: failed to sync DNS records: %v", ProviderName, err)
+		}
+	}, dnsInterval, stop)
+}
+
+// Sync makes the records of the DNS domains match the hostnames of services,
+// the existing Services of the cluster. The records of hostnames owned by the
+// cluster that are no longer used are deleted.
+func (c *dnsController) Sync(_ context.Context, services []v1.Service) error {
+	domains, err := c.client.GetDNSDomains()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	// desired are the targets by domain and record name
+	desired := map[string]map[string]*dnsTarget{}
+	for i := range services {
+		service := &services[i]
+		hostname := strings.ToLower(strings.TrimSuffix(service.Annotations[hostnameAnnotation], "."))
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer || hostname == "" {
+			continue
+		}
+		owner := service.Namespace + "/" + service.Name
+		domain, name, found := splitHostname(domains, hostname)
+		if !found {
+			errs = append(errs, fmt.Errorf("service %s: no DNS domain of hostname %s", owner, hostname))
+			continue
+		}
+		if desired[domain] == nil {
+			desired[domain] = map[string]*dnsTarget{}
+		}
+		if t, found := desired[domain][name]; found {
+			errs = append(errs, fmt.Errorf("service %s: hostname %s is used by service %s", owner, hostname, t.owner))
+			continue
+		}
+		target := &dnsTarget{service: service, owner: owner}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if net.ParseIP(ingress.IP) != nil {
+				target.ips = append(target.ips, ingress.IP)
+			}
+		}
+		desired[domain][name] = target
+	}
+
+	for _, domain := range domains {
+		if err := c.syncDomain(domain.Domain, desired[domain.Domain]); err != nil {
+			errs = append(errs, fmt.Errorf("domain %s: %v", domain.Domain, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// splitHostname returns the domain of hostname, the longest one it is in, and
+// the name of its records in the domain.
+func splitHostname(domains []gv.DNSDomain, hostname string) (string, string, bool) {
+	domain := ""
+	for _, d := range domains {
+		name := strings.ToLower(d.Domain)
+		if (hostname == name || strings.HasSuffix(hostname, "."+name)) && len(name) > len(domain) {
+			domain = d.Domain
+		}
+	}
+	if domain == "" {
+		return "", "", false
+	}
+	return domain, strings.TrimSuffix(strings.TrimSuffix(hostname, strings.ToLower(domain)), "."), true
+}
+
+// syncDomain syncs the records of the names owned by the cluster and of the
+// desired targets in domain.
+func (c *dnsController) syncDomain(domain string, desired map[string]*dnsTarget) error {
+	records, err := c.client.GetDNSRecords(domain)
+	if err != nil {
+		return err
+	}
+	byName := map[string][]gv.DNSRecord{}
+	names := map[string]bool{}
+	for _, r := range records {
+		name := strings.ToLower(r.Name)
+		byName[name] = append(byName[name], r)
+		if o, ok := parseOwnerRecord(r.Data); r.Type == "TXT" && ok && o.cluster == c.mutator.ClusterID() {
+			names[name] = true
+		}
+	}
+	for name := range desired {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var errs []error
+	for _, name := range sorted {
+		if err := c.syncName(domain, name, byName[name], desired[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncName makes the A and AAAA records of name point at the IPs of target,
+// or deletes them and the ownership record if target is nil.
+func (c *dnsController) syncName(domain, name string, records []gv.DNSRecord, target *dnsTarget) error {
+	hostname := strings.TrimPrefix(name+"."+domain, ".")
+	var owner *gv.DNSRecord
+	for i, r := range records {
+		if o, ok := parseOwnerRecord(r.Data); r.Type == "TXT" && ok && o.cluster == c.mutator.ClusterID() {
+			owner = &records[i]
+		}
+	}
+
+	var current []gv.DNSRecord
+	for _, r := range records {
+		switch r.Type {
+		case "A", "AAAA":
+			current = append(current, r)
+		case "CNAME":
+			if owner == nil {
+				return fmt.Errorf("hostname %s has a CNAME record not owned by the cluster", hostname)
+			}
+		}
+	}
+
+	if target == nil {
+		// the hostname is no longer used
+		for _, r := range current {
+			if err := c.deleteRecord(nil, domain, hostname, r); err != nil {
+				return err
+			}
+		}
+		return c.deleteRecord(nil, domain, hostname, *owner)
+	}
+
+	data := ownerRecord(c.mutator.ClusterID(), target.owner, cloudprovider.DefaultLoadBalancerName(target.service))
+	switch {
+	case owner == nil && len(current) > 0:
+		return fmt.Errorf("hostname %s of service %s has records not owned by the cluster", hostname, target.owner)
+	case owner == nil:
+		err := c.mutator.Do(target.service, "create DNS record", cloud.Params{"hostname": hostname, "type": "TXT", "data": data}, func() error {
+			return c.client.CreateDNSRecord(domain, name, "TXT", data, 0, dnsTTL)
+		})
+		if err != nil {
+			return err
+		}
+	case owner.Data != data:
+		// the hostname moved to another Service of the cluster
+		if err := c.updateRecord(target.service, domain, hostname, *owner, data); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, typ := range []string{"A", "AAAA"} {
+		if err := c.syncRecords(domain, name, hostname, typ, current, target); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncRecords syncs the records of type typ of a name with the IPs of target
+// of the same family. Records of IPs that are no longer used are updated to
+// point at new IPs, remaining ones are deleted.
+func (c *dnsController) syncRecords(domain, name, hostname, typ string, current []gv.DNSRecord, target *dnsTarget) error {
+	missing := map[string]bool{}
+	for _, ip := range target.ips {
+		if recordType(ip) == typ {
+			missing[ip] = true
+		}
+	}
+	var stale []gv.DNSRecord
+	for _, r := range current {
+		if r.Type != typ {
+			continue
+		}
+		if missing[r.Data] {
+			delete(missing, r.Data)
+			continue
+		}
+		stale = append(stale, r)
+	}
+
+	ips := make([]string, 0, len(missing))
+	for ip := range missing {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	for len(stale) > 0 && len(ips) > 0 {
+		if err := c.updateRecord(target.service, domain, hostname, stale[0], ips[0]); err != nil {
+			return err
+		}
+		stale, ips = stale[1:], ips[1:]
+	}
+	for _, r := range stale {
+		if err := c.deleteRecord(target.service, domain, hostname, r); err != nil {
+			return err
+		}
+	}
+	for _, ip := range ips {
+		err := c.mutator.Do(target.service, "create DNS record", cloud.Params{"hostname": hostname, "type": typ, "data": ip}, func() error {
+			return c.client.CreateDNSRecord(domain, name, typ, ip, 0, dnsTTL)
+		})
+		if err != nil {
+			return err
+		}
+		log.Infof("%s: created %s record of %s pointing at %s", ProviderName, typ, hostname, ip)
+	}
+	return nil
+}
+
+// ListOwned lists the hostnames owned by the Services of clusterID, by the
+// hostname. Hostnames whose ownership record has no load balancer are left to
+// Sync, which adds it.
+func (c *dnsController) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	domains, err := c.client.GetDNSDomains()
+	if err != nil {
+		return nil, err
+	}
+	var resources []cloud.OwnedResource
+	for _, domain := range domains {
+		records, err := c.client.GetDNSRecords(domain.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			o, ok := parseOwnerRecord(r.Data)
+			if r.Type != "TXT" || !ok || o.cluster != clusterID || o.loadBalancer == "" {
+				continue
+			}
+			resources = append(resources, cloud.OwnedResource{
+				Kind:             "DNS records",
+				ID:               strings.ToLower(strings.TrimPrefix(r.Name+"."+domain.Domain, ".")),
+				LoadBalancerName: o.loadBalancer,
+			})
+		}
+	}
+	return resources, nil
+}
+
+// DeleteOwned deletes the A and AAAA records and the ownership record of a
+// hostname listed by ListOwned, unless it moved to another load balancer.
+func (c *dnsController) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	domains, err := c.client.GetDNSDomains()
+	if err != nil {
+		return err
+	}
+	domain, name, found := splitHostname(domains, resource.ID)
+	if !found {
+		return nil
+	}
+	records, err := c.client.GetDNSRecords(domain)
+	if err != nil {
+		return err
+	}
+
+	var owner *gv.DNSRecord
+	var current []gv.DNSRecord
+	for i, r := range records {
+		if strings.ToLower(r.Name) != name {
+			continue
+		}
+		switch r.Type {
+		case "A", "AAAA":
+			current = append(current, r)
+		case "TXT":
+			if o, ok := parseOwnerRecord(r.Data); ok && o.cluster == c.mutator.ClusterID() && o.loadBalancer == resource.LoadBalancerName {
+				owner = &records[i]
+			}
+		}
+	}
+	if owner == nil {
+		return nil
+	}
+	for _, r := range append(current, *owner) {
+		if err := c.client.DeleteDNSRecord(domain, r.RecordID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *dnsController) updateRecord(service *v1.Service, domain, hostname string, r gv.DNSRecord, data string) error {
+	err := c.mutator.Do(service, "update DNS record", cloud.Params{"hostname": hostname, "type": r.Type, "data": data}, func() error {
+		r.Data = data
+		return c.client.UpdateDNSRecord(domain, r)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: updated %s record of %s to %s", ProviderName, r.Type, hostname, data)
+	return nil
+}
+
+func (c *dnsController) deleteRecord(service *v1.Service, domain, hostname string, r gv.DNSRecord) error {
+	err := c.mutator.Destroy(service, "delete DNS record", cloud.Params{"hostname": hostname, "type": r.Type, "data": r.Data}, func() error {
+		return c.client.DeleteDNSRecord(domain, r.RecordID)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: deleted %s record of %s", ProviderName, r.Type, hostname)
+	return nil
+}
+
+// recordType returns the type of the records of ip, A or AAAA.
+func recordType(ip string) string {
+	if net.ParseIP(ip).To4() != nil {
+		return "A"
+	}
+	return "AAAA"
+}
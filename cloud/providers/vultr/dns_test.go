@@ -0,0 +1,164 @@
+package vultr
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func hostnameService(name, hostname string, ips ...string) v1.Service {
+	service := loadBalancerService(name, 30080)
+	service.UID = types.UID(name)
+	service.Annotations = map[string]string{hostnameAnnotation: hostname}
+	for _, ip := range ips {
+		service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ip})
+	}
+	return service
+}
+
+// recordStrings returns the records of domain as <type> <name> <data>.
+func recordStrings(api *standin.Vultr, domain string) []string {
+	var records []string
+	for _, r := range api.DNSRecords[domain] {
+		records = append(records, strings.Join([]string{r.Type, r.Name, r.Data}, " "))
+	}
+	sort.Strings(records)
+	return records
+}
+
+func newTestDNSController(api *standin.Vultr) *dnsController {
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	return newDNSController(client, cloud.NewMutator(ProviderName, "prod"), dnsOptions{ManageDNS: true})
+}
+
+func TestDNSController(t *testing.T) {
+	api := standin.NewVultr()
+	defer api.Close()
+	api.DNSDomains = []gv.DNSDomain{{Domain: "example.com"}, {Domain: "dev.example.com"}}
+	api.DNSRecords["example.com"] = []gv.DNSRecord{
+		{RecordID: 100, Type: "A", Name: "www", Data: "192.0.2.1"},
+		{RecordID: 101, Type: "A", Name: "old", Data: "192.0.2.9"},
+		{RecordID: 102, Type: "TXT", Name: "old", Data: ownerRecord("prod", "default/old", "aold")},
+	}
+	c := newTestDNSController(api)
+
+	services := []v1.Service{
+		hostnameService("web", "web.example.com.", "203.0.113.10", "2001:db8::10"),
+		hostnameService("api", "api.dev.example.com", "203.0.113.11"),
+		hostnameService("www", "www.example.com", "203.0.113.12"),
+	}
+	err := c.Sync(context.Background(), services)
+	if err == nil || !strings.Contains(err.Error(), "not owned") {
+		t.Errorf("expected error for the record not owned by the cluster, got %v", err)
+	}
+	expected := []string{
+		"A web 203.0.113.10",
+		"A www 192.0.2.1",
+		"AAAA web 2001:db8::10",
+		"TXT web " + ownerRecord("prod", "default/web", "aweb"),
+	}
+	if records := recordStrings(api, "example.com"); !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %v, got %v", expected, records)
+	}
+	expected = []string{"A api 203.0.113.11", "TXT api " + ownerRecord("prod", "default/api", "aapi")}
+	if records := recordStrings(api, "dev.example.com"); !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %v, got %v", expected, records)
+	}
+
+	// records are updated when the IP moves and deleted with the Service
+	services = []v1.Service{hostnameService("web", "web.example.com", "203.0.113.20")}
+	if err := c.Sync(context.Background(), services); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"A web 203.0.113.20", "A www 192.0.2.1", "TXT web " + ownerRecord("prod", "default/web", "aweb")}
+	if records := recordStrings(api, "example.com"); !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %v, got %v", expected, records)
+	}
+	if records := recordStrings(api, "dev.example.com"); len(records) != 0 {
+		t.Errorf("expected records to be deleted, got %v", records)
+	}
+}
+
+func TestDNSControllerOtherCluster(t *testing.T) {
+	api := standin.NewVultr()
+	defer api.Close()
+	api.DNSDomains = []gv.DNSDomain{{Domain: "example.com"}}
+	api.DNSRecords["example.com"] = []gv.DNSRecord{
+		{RecordID: 100, Type: "A", Name: "web", Data: "192.0.2.1"},
+		{RecordID: 101, Type: "TXT", Name: "web", Data: ownerRecord("staging", "default/web", "aweb")},
+	}
+	c := newTestDNSController(api)
+
+	if err := c.Sync(context.Background(), []v1.Service{hostnameService("web", "web.example.com", "203.0.113.10")}); err == nil {
+		t.Error("expected error for the record of another cluster")
+	}
+	// records of other clusters are not deleted either
+	if err := c.Sync(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if records := recordStrings(api, "example.com"); len(records) != 2 || records[0] != "A web 192.0.2.1" {
+		t.Errorf("expected records to be kept, got %v", records)
+	}
+}
+
+func TestCollectOrphanedDNSRecords(t *testing.T) {
+	api := standin.NewVultr()
+	defer api.Close()
+	api.DNSDomains = []gv.DNSDomain{{Domain: "example.com"}}
+	api.DNSRecords["example.com"] = []gv.DNSRecord{
+		{RecordID: 100, Type: "A", Name: "web", Data: "203.0.113.10"},
+		{RecordID: 101, Type: "TXT", Name: "web", Data: ownerRecord("prod", "default/web", "aweb")},
+		{RecordID: 102, Type: "A", Name: "old", Data: "203.0.113.11"},
+		{RecordID: 103, Type: "AAAA", Name: "old", Data: "2001:db8::11"},
+		{RecordID: 104, Type: "TXT", Name: "old", Data: ownerRecord("prod", "default/old", "aold")},
+		{RecordID: 105, Type: "A", Name: "legacy", Data: "203.0.113.12"},
+		{RecordID: 106, Type: "TXT", Name: "legacy", Data: `"heritage=pharmer,cluster=prod,service=default/legacy"`},
+		{RecordID: 107, Type: "A", Name: "staging", Data: "203.0.113.13"},
+		{RecordID: 108, Type: "TXT", Name: "staging", Data: ownerRecord("staging", "default/old", "aold")},
+	}
+	c := newTestDNSController(api)
+
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c)
+	if err := collector.Collect(context.Background(), []v1.Service{hostnameService("web", "web.example.com")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, r := range api.DNSRecords["example.com"] {
+		ids = append(ids, r.RecordID)
+	}
+	if expected := []int{100, 101, 105, 106, 107, 108}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected records %v to be kept, got %v", expected, ids)
+	}
+}
+
+func TestDNSControllerDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	api := standin.NewVultr()
+	defer api.Close()
+	api.DNSDomains = []gv.DNSDomain{{Domain: "example.com"}}
+	c := newTestDNSController(api)
+
+	if err := c.Sync(context.Background(), []v1.Service{hostnameService("web", "web.example.com", "203.0.113.10")}); err != nil {
+		t.Fatal(err)
+	}
+	if records := recordStrings(api, "example.com"); len(records) != 0 {
+		t.Errorf("expected no changes in dry-run mode, got %v", records)
+	}
+}
@@ -0,0 +1,351 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// firewallInterval is the time between two syncs of the firewall group
+const firewallInterval = time.Minute
+
+const (
+	allIPv4 = "0.0.0.0/0"
+	allIPv6 = "::/0"
+)
+
+// sshPort is kept open to all addresses in the firewall group, unless
+// FirewallBlockSSH is set.
+const sshPort = "22"
+
+// masterRoleLabel is the label of master Nodes set by kubeadm.
+const masterRoleLabel = "node-role.kubernetes.io/master"
+
+// firewallOptions configure the firewall group of the cluster.
+type firewallOptions struct {
+	// ManageFirewall creates a firewall group for the cluster and opens the
+	// NodePorts of LoadBalancer Services to their loadBalancerSourceRanges, or
+	// to all IPv4 and IPv6 addresses if they have none. A firewall group drops
+	// all other traffic, so it is only attached to the servers of the worker
+	// Nodes: masters are never attached, to keep the API server reachable,
+	// and neither are servers in another firewall group. SSH is opened to all
+	// addresses, see FirewallBlockSSH. Other rules for
+	// ports outside of the NodePort range can be added to the group by hand
+	// and are kept.
+	ManageFirewall bool `json:"manageFirewall,omitempty" yaml:"manageFirewall,omitempty"`
+	// FirewallBlockSSH does not open SSH in the firewall group, leaving the
+	// worker Nodes unreachable by SSH unless a rule is added by hand.
+	FirewallBlockSSH bool `json:"firewallBlockSSH,omitempty" yaml:"firewallBlockSSH,omitempty"`
+}
+
+// firewallController keeps the rules of the firewall group of the cluster in
+// sync with the LoadBalancer Services.
+type firewallController struct {
+	client    *gv.Client
+	mutator   *cloud.Mutator
+	options   firewallOptions
+	nodePorts cloud.PortRange
+}
+
+func newFirewallController(client *gv.Client, mutator *cloud.Mutator, options firewallOptions) *firewallController {
+	return &firewallController{client: client, mutator: mutator, options: options, nodePorts: cloud.DefaultNodePortRange}
+}
+
+// firewallGroupDescription returns the description of the firewall group of
+// the cluster, which identifies it.
+func firewallGroupDescription(clusterID string) string {
+	return cloud.ClusterTag(clusterID)
+}
+
+// Start runs the controller until stop is closed, if it is enabled. It needs a
+// cluster ID to tell its firewall group and servers apart from those of other
+// clusters.
+func (c *firewallController) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !c.options.ManageFirewall {
+		return
+	}
+	if c.mutator.ClusterID() == "" {
+		log.Warningf("%s: not managing the firewall group, %v", ProviderName, cloud.ErrNoClusterID)
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-firewall-controller")
+	go wait.Until(func() {
+		services, err := client.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("%s: failed to list services: %v", ProviderName, err)
+			return
+		}
+		nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("%s: failed to list nodes: %v", ProviderName, err)
+			return
+		}
+		if err := c.Sync(context.Background(), services.Items, nodes.Items); err != nil {
+			log.Errorf("%s: failed to sync firewall group: %v", ProviderName, err)
+		}
+	}, firewallInterval, stop)
+}
+
+// Sync makes the NodePort rules of the firewall group match services, the
+// existing Services of the cluster, and attaches the group to the servers of
+// nodes without one, other than masters. The group is created if it does not
+// exist.
+func (c *firewallController) Sync(_ context.Context, services []v1.Service, nodes []v1.Node) error {
+	var errs []error
+	desired := map[cloud.NodePortRule]bool{}
+	for i := range services {
+		rules, err := cloud.NodePortRules(&services[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %s/%s: %v", services[i].Namespace, services[i].Name, err))
+			continue
+		}
+		for _, rule := range rules {
+			desired[rule] = true
+			// the default source range of all IPv4 addresses also opens the
+			// NodePort to all IPv6 addresses
+			if rule.Source == allIPv4 {
+				rule.Source = allIPv6
+				desired[rule] = true
+			}
+		}
+	}
+
+	groupID, err := c.ensureGroup()
+	if err != nil {
+		return err
+	}
+	if groupID == "" {
+		// the group was not created in dry-run mode
+		return utilerrors.NewAggregate(errs)
+	}
+
+	rules, err := c.client.GetFirewallRules(groupID)
+	if err != nil {
+		return err
+	}
+	if err := c.ensureSSH(groupID, rules); err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range rules {
+		rule, owned := c.nodePortRule(r)
+		if !owned {
+			continue
+		}
+		if desired[rule] {
+			delete(desired, rule)
+			continue
+		}
+		err := c.mutator.Destroy(nil, "delete firewall rule", cloud.Params{"group": groupID, "rule": rule.String()}, func() error {
+			return c.client.DeleteFirewallRule(r.RuleNumber, groupID)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("%s: closed NodePort %s in firewall group %s", ProviderName, rule, groupID)
+	}
+
+	missing := make([]cloud.NodePortRule, 0, len(desired))
+	for rule := range desired {
+		missing = append(missing, rule)
+	}
+	cloud.SortNodePortRules(missing)
+	for _, rule := range missing {
+		_, network, err := net.ParseCIDR(rule.Source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = c.mutator.Do(nil, "create firewall rule", cloud.Params{"group": groupID, "rule": rule.String()}, func() error {
+			_, err := c.client.CreateFirewallRule(groupID, rule.Protocol, strconv.Itoa(rule.Port), network)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("%s: opened NodePort %s in firewall group %s", ProviderName, rule, groupID)
+	}
+
+	// attach the group only once all of its rules are in place, as it drops
+	// the traffic to NodePorts that are not opened
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	return c.attachGroup(groupID, nodes)
+}
+
+// ensureGroup returns the ID of the firewall group of the cluster, creating it
+// if it does not exist. The ID is empty if the group would be created in
+// dry-run mode.
+func (c *firewallController) ensureGroup() (string, error) {
+	id, err := c.findGroup(c.mutator.ClusterID())
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	description := firewallGroupDescription(c.mutator.ClusterID())
+	err = c.mutator.Do(nil, "create firewall group", cloud.Params{"description": description}, func() error {
+		id, err = c.client.CreateFirewallGroup(description)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if id != "" {
+		log.Infof("%s: created firewall group %s", ProviderName, id)
+	}
+	return id, nil
+}
+
+// ensureSSH opens SSH to all IPv4 and IPv6 addresses in the group, unless
+// FirewallBlockSSH is set, so that attaching the group does not lock out the
+// worker Nodes.
+func (c *firewallController) ensureSSH(groupID string, rules []gv.FirewallRule) error {
+	if c.options.FirewallBlockSSH {
+		return nil
+	}
+	open := map[string]bool{}
+	for _, r := range rules {
+		if r.Protocol == "tcp" && r.Port == sshPort && r.Network != nil {
+			open[r.Network.String()] = true
+		}
+	}
+	for _, source := range []string{allIPv4, allIPv6} {
+		if open[source] {
+			continue
+		}
+		_, network, _ := net.ParseCIDR(source)
+		err := c.mutator.Do(nil, "create firewall rule", cloud.Params{"group": groupID, "rule": "tcp/" + sshPort + " from " + source}, func() error {
+			_, err := c.client.CreateFirewallRule(groupID, "tcp", sshPort, network)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		log.Infof("%s: opened SSH from %s in firewall group %s", ProviderName, source, groupID)
+	}
+	return nil
+}
+
+// ListOwned lists the NodePort rules of the firewall group of clusterID. The
+// group itself is kept, it may hold rules added by hand.
+func (c *firewallController) ListOwned(_ context.Context, clusterID string) ([]cloud.OwnedResource, error) {
+	groupID, err := c.findGroup(clusterID)
+	if err != nil || groupID == "" {
+		return nil, err
+	}
+	rules, err := c.client.GetFirewallRules(groupID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []cloud.OwnedResource
+	for _, r := range rules {
+		if rule, owned := c.nodePortRule(r); owned {
+			resources = append(resources, cloud.OwnedResource{Kind: "firewall rule", ID: groupID + ":" + rule.String(), NodePort: rule.Port})
+		}
+	}
+	return resources, nil
+}
+
+// DeleteOwned deletes a NodePort rule listed by ListOwned. Rules are looked up
+// again as their numbers change when other rules are deleted.
+func (c *firewallController) DeleteOwned(_ context.Context, resource cloud.OwnedResource) error {
+	groupID := strings.SplitN(resource.ID, ":", 2)[0]
+	rules, err := c.client.GetFirewallRules(groupID)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if rule, owned := c.nodePortRule(r); owned && groupID+":"+rule.String() == resource.ID {
+			return c.client.DeleteFirewallRule(r.RuleNumber, groupID)
+		}
+	}
+	return nil
+}
+
+// findGroup returns the ID of the firewall group of clusterID, or an empty ID
+// if it does not exist.
+func (c *firewallController) findGroup(clusterID string) (string, error) {
+	groups, err := c.client.GetFirewallGroups()
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups {
+		if group.Description == firewallGroupDescription(clusterID) {
+			return group.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// nodePortRule returns the NodePort rule of r and whether the controller owns
+// it: only rules for a single TCP or UDP port in the NodePort range are owned.
+func (c *firewallController) nodePortRule(r gv.FirewallRule) (cloud.NodePortRule, bool) {
+	if (r.Protocol != "tcp" && r.Protocol != "udp") || r.Network == nil {
+		return cloud.NodePortRule{}, false
+	}
+	port, err := strconv.Atoi(r.Port)
+	if err != nil || !c.nodePorts.Contains(port) {
+		return cloud.NodePortRule{}, false
+	}
+	return cloud.NodePortRule{Protocol: r.Protocol, Port: port, Source: r.Network.String()}, true
+}
+
+// attachGroup attaches the firewall group to the servers of nodes that are in
+// no firewall group. Masters are skipped, as the group does not open the API
+// server.
+func (c *firewallController) attachGroup(groupID string, nodes []v1.Node) error {
+	servers, err := clusterServers(c.client, c.mutator.ClusterID())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range nodes {
+		server := matchServer(servers, &nodes[i])
+		if server == nil || isMaster(server, &nodes[i]) {
+			continue
+		}
+		switch server.FirewallGroupID {
+		case groupID:
+			continue
+		case "", "0":
+		default:
+			log.Warningf("%s: server %s is in firewall group %s, not attaching firewall group %s", ProviderName, server.ID, server.FirewallGroupID, groupID)
+			continue
+		}
+		err := c.mutator.Do(nil, "attach firewall group", cloud.Params{"group": groupID, "server": server.ID}, func() error {
+			return c.client.SetFirewallGroup(server.ID, groupID)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Infof("%s: attached firewall group %s to server %s", ProviderName, groupID, server.ID)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// isMaster returns whether the server of node is a master, by its name, see
+// cloud.NewClusterMember, or by the role label of node.
+func isMaster(server *gv.Server, node *v1.Node) bool {
+	if member, ok := cloud.NewClusterMember(server.Name, []string{server.Tag}, ""); ok && member.Master {
+		return true
+	}
+	_, found := node.Labels[masterRoleLabel]
+	return found
+}
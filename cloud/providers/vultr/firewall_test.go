@@ -0,0 +1,190 @@
+package vultr
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+func loadBalancerService(name string, nodePort int32, sourceRanges ...string) v1.Service {
+	return v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1.ServiceSpec{
+			Type:                     v1.ServiceTypeLoadBalancer,
+			Ports:                    []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80, NodePort: nodePort}},
+			LoadBalancerSourceRanges: sourceRanges,
+		},
+	}
+}
+
+func firewallRule(number int, protocol, port, network string) gv.FirewallRule {
+	_, ipnet, _ := net.ParseCIDR(network)
+	return gv.FirewallRule{RuleNumber: number, Action: "accept", Protocol: protocol, Port: port, Network: ipnet}
+}
+
+// ruleStrings returns the rules of group as <protocol>/<port> from <network>.
+func ruleStrings(api *standin.Vultr, group string) []string {
+	var rules []string
+	for _, r := range api.FirewallRules[group] {
+		rules = append(rules, r.Protocol+"/"+r.Port+" from "+r.Network.String())
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func TestFirewallController(t *testing.T) {
+	api := standin.NewVultr(
+		gv.Server{ID: "576965", Name: "prod-master", Tag: cloud.ClusterTag("prod")},
+		gv.Server{ID: "576966", Name: "node-1", Tag: cloud.ClusterTag("prod"), FirewallGroupID: "custom"},
+		gv.Server{ID: "576967", Name: "other", Tag: cloud.ClusterTag("staging")},
+		gv.Server{ID: "576968", Name: "node-2", Tag: cloud.ClusterTag("prod")},
+		gv.Server{ID: "576969", Name: "control-plane", Tag: cloud.ClusterTag("prod")},
+		gv.Server{ID: "576970", Name: "spare", Tag: cloud.ClusterTag("prod")},
+	)
+	defer api.Close()
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newFirewallController(client, cloud.NewMutator(ProviderName, "prod"), firewallOptions{ManageFirewall: true})
+
+	services := []v1.Service{
+		loadBalancerService("web", 30080, "203.0.113.0/24"),
+		loadBalancerService("api", 30443),
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "internal"}, Spec: v1.ServiceSpec{Type: v1.ServiceTypeClusterIP}},
+	}
+	nodes := []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prod-master"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "control-plane", Labels: map[string]string{masterRoleLabel: ""}}},
+	}
+	if err := c.Sync(context.Background(), services, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.FirewallGroups) != 1 || api.FirewallGroups[0].Description != cloud.ClusterTag("prod") {
+		t.Fatalf("expected firewall group of the cluster, got %v", api.FirewallGroups)
+	}
+	group := api.FirewallGroups[0].ID
+	// SSH is opened along with the NodePorts
+	expected := []string{"tcp/22 from 0.0.0.0/0", "tcp/22 from ::/0", "tcp/30080 from 203.0.113.0/24", "tcp/30443 from 0.0.0.0/0", "tcp/30443 from ::/0"}
+	if rules := ruleStrings(api, group); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	// masters, servers in another group, of another cluster or without Node
+	// are left alone
+	for id, want := range map[int]string{0: "", 1: "custom", 2: "", 3: group, 4: "", 5: ""} {
+		if got := api.Servers[id].FirewallGroupID; got != want {
+			t.Errorf("expected server %s in firewall group %q, got %q", api.Servers[id].ID, want, got)
+		}
+	}
+
+	// rules outside of the NodePort range are kept, the rules of deleted
+	// Services and changed source ranges are removed
+	api.FirewallRules[group] = append(api.FirewallRules[group], firewallRule(10, "tcp", "9100", "10.0.0.0/8"))
+	if err := c.Sync(context.Background(), []v1.Service{loadBalancerService("web", 30080, "198.51.100.0/24")}, nodes); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"tcp/22 from 0.0.0.0/0", "tcp/22 from ::/0", "tcp/30080 from 198.51.100.0/24", "tcp/9100 from 10.0.0.0/8"}
+	if rules := ruleStrings(api, group); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	if len(api.FirewallGroups) != 1 {
+		t.Errorf("expected the firewall group to be reused, got %v", api.FirewallGroups)
+	}
+}
+
+func TestFirewallControllerBlockSSH(t *testing.T) {
+	api := standin.NewVultr(gv.Server{ID: "576965", Name: "node-1", Tag: cloud.ClusterTag("prod")})
+	defer api.Close()
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newFirewallController(client, cloud.NewMutator(ProviderName, "prod"), firewallOptions{ManageFirewall: true, FirewallBlockSSH: true})
+
+	nodes := []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	if err := c.Sync(context.Background(), []v1.Service{loadBalancerService("web", 30080, "203.0.113.0/24")}, nodes); err != nil {
+		t.Fatal(err)
+	}
+	group := api.FirewallGroups[0].ID
+	if rules, expected := ruleStrings(api, group), []string{"tcp/30080 from 203.0.113.0/24"}; !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	if api.Servers[0].FirewallGroupID != group {
+		t.Errorf("expected server in firewall group %s, got %q", group, api.Servers[0].FirewallGroupID)
+	}
+}
+
+func TestFirewallControllerErrors(t *testing.T) {
+	api := standin.NewVultr(gv.Server{ID: "576965", Name: "node-1", Tag: cloud.ClusterTag("prod")})
+	defer api.Close()
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newFirewallController(client, cloud.NewMutator(ProviderName, "prod"), firewallOptions{ManageFirewall: true})
+
+	services := []v1.Service{loadBalancerService("web", 30080), loadBalancerService("api", 30443, "invalid")}
+	nodes := []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	if err := c.Sync(context.Background(), services, nodes); err == nil {
+		t.Error("expected error for the invalid source range")
+	}
+	// the group is not attached with rules missing
+	if len(api.FirewallGroups) != 1 || api.Servers[0].FirewallGroupID != "" {
+		t.Errorf("expected firewall group not to be attached, got server in %q", api.Servers[0].FirewallGroupID)
+	}
+}
+
+func TestCollectOrphanedFirewallRules(t *testing.T) {
+	api := standin.NewVultr()
+	defer api.Close()
+	api.FirewallGroups = []gv.FirewallGroup{{ID: "fw1", Description: cloud.ClusterTag("prod")}, {ID: "fw2", Description: cloud.ClusterTag("staging")}}
+	api.FirewallRules["fw1"] = []gv.FirewallRule{
+		firewallRule(1, "tcp", "22", "0.0.0.0/0"),
+		firewallRule(2, "tcp", "30080", "0.0.0.0/0"),
+		firewallRule(3, "tcp", "30443", "0.0.0.0/0"),
+		firewallRule(4, "tcp", "30443", "203.0.113.0/24"),
+	}
+	api.FirewallRules["fw2"] = []gv.FirewallRule{firewallRule(1, "tcp", "30443", "0.0.0.0/0")}
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newFirewallController(client, cloud.NewMutator(ProviderName, "prod"), firewallOptions{})
+
+	defer cloud.SetGCOptions(cloud.DefaultGCOptions)
+	if err := cloud.SetGCOptions(cloud.GCOptions{Mode: cloud.GCModeDelete, Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	collector := cloud.NewCollector(c.mutator)
+	collector.Register(c)
+	if err := collector.Collect(context.Background(), []v1.Service{loadBalancerService("web", 30080)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// rules outside of the NodePort range and of other clusters are kept
+	expected := []string{"tcp/22 from 0.0.0.0/0", "tcp/30080 from 0.0.0.0/0"}
+	if rules := ruleStrings(api, "fw1"); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+	if rules := ruleStrings(api, "fw2"); len(rules) != 1 {
+		t.Errorf("expected the rule of another cluster to be kept, got %v", rules)
+	}
+}
+
+func TestFirewallControllerDryRun(t *testing.T) {
+	cloud.SetDryRun(true)
+	defer cloud.SetDryRun(false)
+
+	api := standin.NewVultr(gv.Server{ID: "576965", Name: "node-1", Tag: cloud.ClusterTag("prod")})
+	defer api.Close()
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newFirewallController(client, cloud.NewMutator(ProviderName, "prod"), firewallOptions{ManageFirewall: true})
+
+	nodes := []v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}
+	if err := c.Sync(context.Background(), []v1.Service{loadBalancerService("web", 30080)}, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.FirewallGroups) != 0 || api.Servers[0].FirewallGroupID != "" {
+		t.Errorf("expected no changes in dry-run mode, got groups %v", api.FirewallGroups)
+	}
+}
@@ -15,19 +15,23 @@ import (
 )
 
 type instances struct {
-	client *gv.Client
+	client  *gv.Client
+	plans   *planCatalogue
+	mutator *cloud.Mutator
+	// numericInstanceType reports the numeric plan ID as instance type
+	numericInstanceType bool
 }
 
-func newInstances(client *gv.Client) cloudprovider.Instances {
-	return &instances{client}
+func newInstances(client *gv.Client, plans *planCatalogue, mutator *cloud.Mutator, numericInstanceType bool) cloudprovider.Instances {
+	return &instances{client: client, plans: plans, mutator: mutator, numericInstanceType: numericInstanceType}
 }
 
 func (i *instances) NodeAddresses(_ context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
-	server, err := serverByName(i.client, name)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), name)
 	if err != nil {
 		return nil, err
 	}
-	return nodeAddresses(server)
+	return nodeAddresses(i.client, server)
 }
 
 func (i *instances) NodeAddressesByProviderID(_ context.Context, providerID string) ([]v1.NodeAddress, error) {
@@ -40,10 +44,12 @@ func (i *instances) NodeAddressesByProviderID(_ context.Context, providerID stri
 		return nil, err
 	}
 
-	return nodeAddresses(&server)
+	return nodeAddresses(i.client, &server)
 }
 
-func nodeAddresses(server *gv.Server) ([]v1.NodeAddress, error) {
+// nodeAddresses returns the main addresses of server first, followed by its
+// additional IPv4 addresses, sorted by type and address.
+func nodeAddresses(client *gv.Client, server *gv.Server) ([]v1.NodeAddress, error) {
 	var addresses []v1.NodeAddress
 	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeHostName, Address: server.Name})
 
@@ -57,7 +63,23 @@ func nodeAddresses(server *gv.Server) ([]v1.NodeAddress, error) {
 	}
 	addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: server.MainIP})
 
-	return addresses, nil
+	ips, err := client.ListIPv4(server.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not list ipv4 addresses of server %s: %v", server.ID, err)
+	}
+	for _, ip := range ips {
+		addressType := v1.NodeExternalIP
+		if ip.Type == "private" {
+			addressType = v1.NodeInternalIP
+		}
+		addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: ip.IP})
+	}
+
+	for _, network := range server.V6Networks {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeExternalIP, Address: network.MainIP})
+	}
+
+	return cloud.NodeAddresses(addresses), nil
 }
 
 func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (string, error) {
@@ -65,7 +87,7 @@ func (i *instances) ExternalID(ctx context.Context, nodeName types.NodeName) (st
 }
 
 func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (string, error) {
-	server, err := serverByName(i.client, nodeName)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
@@ -73,11 +95,11 @@ func (i *instances) InstanceID(_ context.Context, nodeName types.NodeName) (stri
 }
 
 func (i *instances) InstanceType(_ context.Context, nodeName types.NodeName) (string, error) {
-	server, err := serverByName(i.client, nodeName)
+	server, err := serverByName(i.client, i.mutator.ClusterID(), nodeName)
 	if err != nil {
 		return "", err
 	}
-	return strconv.Itoa(server.PlanID), nil
+	return i.instanceType(server)
 }
 
 func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID string) (string, error) {
@@ -89,11 +111,39 @@ func (i *instances) InstanceTypeByProviderID(_ context.Context, providerID strin
 	if err != nil {
 		return "", err
 	}
-	return strconv.Itoa(server.PlanID), nil
+	return i.instanceType(&server)
+}
+
+// instanceType returns the name of the plan of server, e.g. vc2-1c-1gb, or its
+// numeric ID if numericInstanceType is set.
+func (i *instances) instanceType(server *gv.Server) (string, error) {
+	if i.numericInstanceType {
+		return strconv.Itoa(server.PlanID), nil
+	}
+	return i.plans.name(server.PlanID)
 }
 
+// AddSSHKeyToAllInstances registers the key with the Vultr account, unless it
+// is already registered. Vultr has no projects, so the key is shared by all
+// servers of the account and installed when they are deployed.
 func (i *instances) AddSSHKeyToAllInstances(_ context.Context, user string, keyData []byte) error {
-	return cloud.ErrNotImplemented
+	key, err := cloud.ParseSSHKey(user, keyData)
+	if err != nil {
+		return err
+	}
+	keys, err := i.client.GetSSHKeys()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if key.Matches(k.Key) {
+			return nil
+		}
+	}
+	return i.mutator.Do(nil, "create ssh key", cloud.Params{"name": key.Name, "fingerprint": key.Fingerprint}, func() error {
+		_, err := i.client.CreateSSHKey(key.Name, key.PublicKey)
+		return err
+	})
 }
 
 func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.NodeName, error) {
@@ -101,17 +151,15 @@ func (i *instances) CurrentNodeName(_ context.Context, hostname string) (types.N
 }
 
 func (i *instances) InstanceExistsByProviderID(_ context.Context, providerID string) (bool, error) {
-	//TODO(sanjid): check provider id here
 	id, err := serverIDFromProviderID(providerID)
 	if err != nil {
 		return false, err
 	}
 	_, err = serverByID(i.client, id)
-	if err == nil {
-		return true, nil
+	if err == cloudprovider.InstanceNotFound {
+		return false, nil
 	}
-
-	return false, nil
+	return err == nil, err
 }
 
 func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID string) (bool, error) {
@@ -119,11 +167,25 @@ func (i *instances) InstanceShutdownByProviderID(ctx context.Context, providerID
 }
 
 func serverByID(client *gv.Client, id string) (gv.Server, error) {
-	return client.GetServer(id)
+	server, err := client.GetServer(id)
+	if err != nil {
+		// the Vultr API reports unknown servers with a plain text error
+		if strings.HasPrefix(err.Error(), "Invalid server.") {
+			return gv.Server{}, cloudprovider.InstanceNotFound
+		}
+		return gv.Server{}, err
+	}
+	if server.ID == "" {
+		return gv.Server{}, cloudprovider.InstanceNotFound
+	}
+	return server, nil
 }
 
-func serverByName(client *gv.Client, nodeName types.NodeName) (*gv.Server, error) {
-	servers, err := client.GetServers()
+// serverByName returns the server called nodeName. If clusterID is not empty,
+// only servers tagged with its cluster tag are considered, so that servers of
+// other clusters with the same name do not collide.
+func serverByName(client *gv.Client, clusterID string, nodeName types.NodeName) (*gv.Server, error) {
+	servers, err := clusterServers(client, clusterID)
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +198,15 @@ func serverByName(client *gv.Client, nodeName types.NodeName) (*gv.Server, error
 	return nil, cloudprovider.InstanceNotFound
 }
 
+// clusterServers returns the servers tagged with the cluster tag of
+// clusterID, or all servers if clusterID is empty.
+func clusterServers(client *gv.Client, clusterID string) ([]gv.Server, error) {
+	if clusterID == "" {
+		return client.GetServers()
+	}
+	return client.GetServersByTag(cloud.ClusterTag(clusterID))
+}
+
 // serverIDFromProviderID returns a server's ID from providerID.
 //
 // The providerID spec should be retrievable from the Kubernetes
@@ -0,0 +1,219 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+var testServers = []gv.Server{
+	{ID: "576965", Name: "master", MainIP: "203.0.113.10", InternalIP: "10.99.0.10", RegionID: 1, PlanID: 201, Tag: "k8s"},
+	{ID: "576966", Name: "node-1", MainIP: "203.0.113.11", InternalIP: "10.99.0.11", RegionID: 1, PlanID: 202, Tag: "k8s"},
+}
+
+func init() {
+	rateLimit = time.Millisecond
+}
+
+func newTestCloud(t *testing.T, metadataURL string) (*Cloud, *standin.Vultr) {
+	api := standin.NewVultr(testServers...)
+	api.Plans = testPlans
+	config := fmt.Sprintf("token: secret\nendpoint: %s\nmetadataURL: %s\n", api.Endpoint(), metadataURL)
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		api.Close()
+		t.Fatal(err)
+	}
+	return c.(*Cloud), api
+}
+
+func TestServerByName(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+
+	server, err := serverByName(c.client, "", "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.ID != "576966" {
+		t.Errorf("expected server 576966, got %s", server.ID)
+	}
+
+	if _, err := serverByName(c.client, "", "node-2"); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+
+	addresses, err := c.instances.NodeAddressesByProviderID(ctx, "vultr://576965")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	id, err := c.instances.InstanceID(ctx, "node-1")
+	if err != nil || id != "576966" {
+		t.Errorf("expected instance id 576966, got %q (%v)", id, err)
+	}
+
+	instanceType, err := c.instances.InstanceTypeByProviderID(ctx, "vultr://576966")
+	if err != nil || instanceType != "vhf-2c-4gb" {
+		t.Errorf("expected instance type vhf-2c-4gb, got %q (%v)", instanceType, err)
+	}
+
+	exists, err := c.instances.InstanceExistsByProviderID(ctx, "vultr://1")
+	if err != nil || exists {
+		t.Errorf("expected instance vultr://1 to not exist, got %v (%v)", exists, err)
+	}
+}
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOyiAiEqGOnlV2yPeHsqMmGnUhpcNTROCwsmk0vxihM+ alice@example.com"
+
+func TestAddSSHKeyToAllInstances(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := c.instances.AddSSHKeyToAllInstances(ctx, "alice", []byte(testSSHKey)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(api.SSHKeys) != 1 {
+		t.Fatalf("expected 1 ssh key, got %v", api.SSHKeys)
+	}
+	if key := api.SSHKeys[0]; !strings.HasPrefix(key.Name, "alice-") || !strings.HasPrefix(testSSHKey, key.Key) {
+		t.Errorf("unexpected ssh key %v", key)
+	}
+
+	if err := c.instances.AddSSHKeyToAllInstances(ctx, "alice", []byte("invalid")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestClusterID(t *testing.T) {
+	api := standin.NewVultr(
+		gv.Server{ID: "576980", Name: "master", MainIP: "203.0.113.30", InternalIP: "10.99.0.30", Tag: cloud.ClusterTag("dev")},
+		gv.Server{ID: "576981", Name: "master", MainIP: "203.0.113.31", InternalIP: "10.99.0.31", Tag: cloud.ClusterTag("prod")},
+	)
+	defer api.Close()
+	ctx := context.Background()
+
+	c, err := newCloud(strings.NewReader(fmt.Sprintf("token: secret\nendpoint: %s\nclusterID: prod\n", api.Endpoint())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.(*Cloud).mutator.ClusterID() != "prod" {
+		t.Error("expected cluster ID to be configured")
+	}
+	instances, _ := c.Instances()
+	if id, err := instances.InstanceID(ctx, "master"); err != nil || id != "576981" {
+		t.Errorf("expected instance id 576981, got %q (%v)", id, err)
+	}
+
+	c, err = newCloud(strings.NewReader(fmt.Sprintf("token: secret\nendpoint: %s\n", api.Endpoint())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.(*Cloud).mutator.ClusterID() != "" {
+		t.Error("expected no cluster ID")
+	}
+}
+
+func TestDualStackAddresses(t *testing.T) {
+	defer cloud.SetAddressOptions(cloud.DefaultAddressOptions)
+
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	server := testServers[0]
+	server.V6Networks = []gv.V6Network{{Network: "2001:db8:1::", MainIP: "2001:db8:1::10", NetworkSize: "64"}}
+	addresses, err := nodeAddresses(c.client, &server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "2001:db8:1::10"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	if err := cloud.SetAddressOptions(cloud.AddressOptions{Families: []cloud.AddressFamily{cloud.IPv6, cloud.IPv4}}); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = nodeAddresses(c.client, &server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addresses[1].Address != "2001:db8:1::10" {
+		t.Errorf("expected IPv6 address first, got %v", addresses)
+	}
+}
+
+func TestMultiHomedAddresses(t *testing.T) {
+	defer cloud.SetAddressOptions(cloud.DefaultAddressOptions)
+
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	api.IPv4 = map[string][]gv.IPv4{
+		"576965": {
+			{IP: "203.0.113.10", Type: "main_ip"},
+			{IP: "203.0.113.99", Type: "secondary_ip"},
+			{IP: "10.99.0.10", Type: "private"},
+			{IP: "203.0.113.50", Type: "secondary_ip"},
+		},
+	}
+
+	addresses, err := c.instances.NodeAddressesByProviderID(context.Background(), "vultr://576965")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "master"},
+		{Type: v1.NodeInternalIP, Address: "10.99.0.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.50"},
+		{Type: v1.NodeExternalIP, Address: "203.0.113.99"},
+	}
+	if !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("expected addresses %v, got %v", expected, addresses)
+	}
+
+	cidrs, err := cloud.ParseCIDRs([]string{"203.0.113.96/27"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cloud.SetAddressOptions(cloud.AddressOptions{Families: cloud.DefaultAddressOptions.Families, ExcludeCIDRs: cidrs}); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = c.instances.NodeAddressesByProviderID(context.Background(), "vultr://576965")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(addresses, expected[:4]) {
+		t.Errorf("expected addresses %v, got %v", expected[:4], addresses)
+	}
+}
@@ -0,0 +1,45 @@
+package vultr
+
+import (
+	"context"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// nodeLabels labels Nodes with the name of the plan of their server, see
+// planName.
+func nodeLabels(client *gv.Client, plans *planCatalogue, clusterID string) cloud.NodeLabelsFunc {
+	return func(_ context.Context, node *v1.Node) (map[string]string, error) {
+		server, err := nodeServer(client, clusterID, node)
+		if err != nil {
+			return nil, err
+		}
+		name, err := plans.name(server.PlanID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			cloud.LabelKey(ProviderName, "plan-name"): name,
+		}, nil
+	}
+}
+
+// nodeServer returns the server of node, by its provider ID if it is set and
+// by its name otherwise.
+func nodeServer(client *gv.Client, clusterID string, node *v1.Node) (*gv.Server, error) {
+	if node.Spec.ProviderID == "" {
+		return serverByName(client, clusterID, types.NodeName(node.Name))
+	}
+	id, err := serverIDFromProviderID(node.Spec.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	server, err := serverByID(client, id)
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
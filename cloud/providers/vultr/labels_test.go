@@ -0,0 +1,35 @@
+package vultr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+func TestNodeLabels(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	ctx := context.Background()
+	labels := nodeLabels(c.client, newPlanCatalogue(c.client), "")
+
+	for node, plan := range map[*v1.Node]string{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}}:                                                   "vc2-1c-1gb",
+		{ObjectMeta: metav1.ObjectMeta{Name: "renamed"}, Spec: v1.NodeSpec{ProviderID: "vultr://576966"}}: "vhf-2c-4gb",
+	} {
+		got, err := labels(ctx, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := map[string]string{"vultr.pharmer.dev/plan-name": plan}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("node %s: expected labels %v, got %v", node.Name, expected, got)
+		}
+	}
+
+	if _, err := labels(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}); err != cloudprovider.InstanceNotFound {
+		t.Errorf("expected InstanceNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package vultr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+)
+
+const (
+	// planCacheTTL is the time after which the plan catalogue is fetched again.
+	planCacheTTL = time.Hour
+	// planMissTTL is the time a plan that is not in the catalogue is not
+	// fetched again for.
+	planMissTTL = 5 * time.Minute
+)
+
+// planCatalogue caches the plans offered by Vultr. The catalogue rarely
+// changes, so it is fetched once per planCacheTTL, or earlier when a server
+// uses a plan that is not in the cached catalogue, at most once per
+// planMissTTL.
+type planCatalogue struct {
+	client *gv.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	plans   map[int]gv.Plan
+	names   map[int]string
+	fetched time.Time
+}
+
+func newPlanCatalogue(client *gv.Client) *planCatalogue {
+	return &planCatalogue{client: client, now: time.Now}
+}
+
+// plan returns the plan with the numeric ID id.
+func (c *planCatalogue) plan(id int) (gv.Plan, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	plan, found := c.plans[id]
+	age := c.now().Sub(c.fetched)
+	if found && age < planCacheTTL {
+		return plan, nil
+	}
+	if !found && c.plans != nil && age < planMissTTL {
+		return gv.Plan{}, fmt.Errorf("unknown plan %d", id)
+	}
+
+	plans, err := c.client.GetPlans()
+	if err != nil {
+		if found {
+			// a stale plan is better than none
+			return plan, nil
+		}
+		return gv.Plan{}, err
+	}
+	c.plans = make(map[int]gv.Plan, len(plans))
+	for _, p := range plans {
+		c.plans[p.ID] = p
+	}
+	c.names = planNames(c.names, plans)
+	c.fetched = c.now()
+
+	plan, found = c.plans[id]
+	if !found {
+		return gv.Plan{}, fmt.Errorf("unknown plan %d", id)
+	}
+	return plan, nil
+}
+
+// name returns the name of the plan with the numeric ID id, see planNames.
+func (c *planCatalogue) name(id int) (string, error) {
+	if _, err := c.plan(id); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.names[id], nil
+}
+
+// planNames returns the names of plans by ID, see planName. A name always
+// identifies a single plan: when names collide, the plan that was named in
+// previous keeps its name, or else the plan with the lowest ID, and the
+// others are named by their numeric ID. Plans keep their previous name as
+// long as their own name does not change, so that the instance type of a
+// server does not change when a colliding plan is added to the catalogue.
+func planNames(previous map[int]string, plans []gv.Plan) map[int]string {
+	sorted := append([]gv.Plan{}, plans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	names := make(map[int]string, len(plans))
+	taken := map[string]bool{}
+	for _, plan := range sorted {
+		name, found := previous[plan.ID]
+		if found && (name == planName(plan) || name == strconv.Itoa(plan.ID)) {
+			names[plan.ID] = name
+			taken[name] = true
+		}
+	}
+	for _, plan := range sorted {
+		if _, found := names[plan.ID]; found {
+			continue
+		}
+		name := planName(plan)
+		if taken[name] {
+			name = strconv.Itoa(plan.ID)
+		}
+		names[plan.ID] = name
+		taken[name] = true
+	}
+	return names
+}
+
+// planName returns a stable name for plan in the form <class>-<vcpus>c-<ram>,
+// e.g. vc2-1c-1gb, like the plan IDs of the Vultr v2 API. The v1 API only
+// describes plans with names like "1024 MB RAM,25 GB SSD,1.00 TB BW", so the
+// class is told from the storage and core type in the name: vhf for high
+// frequency plans on NVMe, vdc for dedicated cores and vc2 otherwise. If the
+// memory is unknown, the numeric plan ID is returned.
+func planName(plan gv.Plan) string {
+	ram, err := strconv.Atoi(strings.TrimSpace(plan.RAM))
+	if err != nil || ram <= 0 || plan.VCpus <= 0 {
+		return strconv.Itoa(plan.ID)
+	}
+
+	class := "vc2"
+	switch name := strings.ToLower(plan.Name); {
+	case strings.Contains(name, "dedicated"):
+		class = "vdc"
+	case strings.Contains(name, "nvme"):
+		class = "vhf"
+	}
+
+	memory := fmt.Sprintf("%dmb", ram)
+	if ram%1024 == 0 {
+		memory = fmt.Sprintf("%dgb", ram/1024)
+	}
+	return fmt.Sprintf("%s-%dc-%s", class, plan.VCpus, memory)
+}
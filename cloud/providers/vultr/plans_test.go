@@ -0,0 +1,127 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+var testPlans = []gv.Plan{
+	{ID: 201, Name: "1024 MB RAM,25 GB SSD,1.00 TB BW", VCpus: 1, RAM: "1024", Disk: "25", Price: "5.00"},
+	{ID: 202, Name: "4096 MB RAM,128 GB NVMe,3.00 TB BW", VCpus: 2, RAM: "4096", Disk: "128", Price: "24.00"},
+}
+
+func TestPlanName(t *testing.T) {
+	for _, test := range []struct {
+		plan gv.Plan
+		name string
+	}{
+		{testPlans[0], "vc2-1c-1gb"},
+		{testPlans[1], "vhf-2c-4gb"},
+		{gv.Plan{ID: 200, Name: "512 MB RAM,10 GB SSD,0.50 TB BW", VCpus: 1, RAM: "512"}, "vc2-1c-512mb"},
+		{gv.Plan{ID: 115, Name: "8192 MB RAM,110 GB SSD,10.00 TB BW, 2 Dedicated Cores", VCpus: 2, RAM: "8192"}, "vdc-2c-8gb"},
+		{gv.Plan{ID: 87, VCpus: 1, RAM: "unknown"}, "87"},
+	} {
+		if name := planName(test.plan); name != test.name {
+			t.Errorf("plan %d: expected name %s, got %s", test.plan.ID, test.name, name)
+		}
+	}
+}
+
+func TestPlanNames(t *testing.T) {
+	plans := append([]gv.Plan{{ID: 203, Name: "1024 MB RAM,32 GB SSD,1.00 TB BW", VCpus: 1, RAM: "1024"}}, testPlans...)
+	expected := map[int]string{201: "vc2-1c-1gb", 202: "vhf-2c-4gb", 203: "203"}
+	names := planNames(nil, plans)
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, got %v", expected, names)
+	}
+
+	// plans keep their names when a colliding plan appears later, even one
+	// with a lower ID
+	plans = append(plans, gv.Plan{ID: 200, Name: "1024 MB RAM,20 GB SSD,1.00 TB BW", VCpus: 1, RAM: "1024"})
+	expected = map[int]string{200: "200", 201: "vc2-1c-1gb", 202: "vhf-2c-4gb", 203: "203"}
+	names = planNames(names, plans)
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, got %v", expected, names)
+	}
+
+	// plans named by their ID keep it, plans whose own name changes are
+	// renamed
+	plans = []gv.Plan{plans[3], {ID: 202, Name: "8192 MB RAM,256 GB NVMe,4.00 TB BW", VCpus: 3, RAM: "8192"}}
+	expected = map[int]string{200: "200", 202: "vhf-3c-8gb"}
+	if names = planNames(names, plans); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, got %v", expected, names)
+	}
+}
+
+func TestPlanCatalogue(t *testing.T) {
+	api := standin.NewVultr()
+	defer api.Close()
+	api.Plans = testPlans[:1]
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+
+	now := time.Now()
+	plans := newPlanCatalogue(client)
+	plans.now = func() time.Time { return now }
+
+	if name, err := plans.name(201); err != nil || name != "vc2-1c-1gb" {
+		t.Fatalf("expected plan vc2-1c-1gb, got %q (%v)", name, err)
+	}
+
+	// cached plans are not fetched again
+	api.Plans = []gv.Plan{{ID: 201, Name: "2048 MB RAM,55 GB SSD,2.00 TB BW", VCpus: 1, RAM: "2048"}}
+	if name, err := plans.name(201); err != nil || name != "vc2-1c-1gb" {
+		t.Errorf("expected cached plan vc2-1c-1gb, got %q (%v)", name, err)
+	}
+
+	// unknown plans refresh the catalogue, at most once per planMissTTL
+	api.Plans = append(api.Plans, testPlans[1])
+	if _, err := plans.name(202); err == nil {
+		t.Error("expected unknown plan to be cached")
+	}
+	now = now.Add(planMissTTL)
+	if name, err := plans.name(202); err != nil || name != "vhf-2c-4gb" {
+		t.Errorf("expected plan vhf-2c-4gb, got %q (%v)", name, err)
+	}
+	if name, err := plans.name(201); err != nil || name != "vc2-1c-2gb" {
+		t.Errorf("expected refreshed plan vc2-1c-2gb, got %q (%v)", name, err)
+	}
+	if _, err := plans.name(999); err == nil {
+		t.Error("expected error for unknown plan")
+	}
+
+	// expired plans are refreshed, or kept if the API fails
+	api.Plans = testPlans
+	now = now.Add(planCacheTTL)
+	if name, err := plans.name(201); err != nil || name != "vc2-1c-1gb" {
+		t.Errorf("expected refreshed plan vc2-1c-1gb, got %q (%v)", name, err)
+	}
+	now = now.Add(planCacheTTL)
+	api.Close()
+	if name, err := plans.name(202); err != nil || name != "vhf-2c-4gb" {
+		t.Errorf("expected stale plan vhf-2c-4gb, got %q (%v)", name, err)
+	}
+}
+
+func TestNumericInstanceType(t *testing.T) {
+	api := standin.NewVultr(testServers...)
+	defer api.Close()
+	config := fmt.Sprintf("token: secret\nendpoint: %s\nnumericInstanceType: true\n", api.Endpoint())
+	c, err := newCloud(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances, _ := c.Instances()
+
+	// the plan catalogue is not needed
+	instanceType, err := instances.InstanceType(context.Background(), "node-1")
+	if err != nil || instanceType != "202" {
+		t.Errorf("expected instance type 202, got %q (%v)", instanceType, err)
+	}
+}
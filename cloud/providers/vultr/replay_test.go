@@ -0,0 +1,23 @@
+package vultr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"pharmer.dev/cloud-controller-manager/cloud/cassette"
+	"pharmer.dev/cloud-controller-manager/cloud/conformance"
+)
+
+func TestReplay(t *testing.T) {
+	if cassette.Recording() {
+		// respect the rate limit of the real API
+		defer func(d time.Duration) { rateLimit = d }(rateLimit)
+		rateLimit = 0
+	}
+	conformance.Replay(t, ProviderName, "testdata/replay.yaml", func(config []byte) ([]string, error) {
+		tokenSource := &tokenSource{}
+		err := yaml.Unmarshal(config, tokenSource)
+		return []string{tokenSource.Token}, err
+	})
+}
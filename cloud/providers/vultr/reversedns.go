@@ -0,0 +1,285 @@
+package vultr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	"github.com/appscode/go/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// reverseDNSInterval is the time between two syncs of the reverse DNS entries
+const reverseDNSInterval = time.Minute
+
+// reverseDNSAnnotation is the annotation of LoadBalancer Services naming the
+// reverse DNS entry of their ingress IPs, e.g. mail.example.com.
+var reverseDNSAnnotation = cloud.LabelKey(ProviderName, "reverse-dns")
+
+// reverseDNSConfigMap is the ConfigMap in the kube-system namespace recording
+// the reverse DNS entries set by the controller, keyed by IP with the colons
+// of IPv6 addresses replaced by dashes. Only these entries are reset.
+const reverseDNSConfigMap = ProviderName + "-reverse-dns"
+
+// reverseDNSOptions configure the reverse DNS entries of the IPs of the
+// servers of the cluster.
+type reverseDNSOptions struct {
+	// ManageReverseDNS sets the reverse DNS entries of the ingress IPs of
+	// LoadBalancer Services annotated with vultr.pharmer.dev/reverse-dns, and
+	// of the external IPs of Nodes if ReverseDNSDomain is set. The entries are
+	// reset to the default once the Service or Node is removed. Only IPs of
+	// servers of the cluster are managed.
+	ManageReverseDNS bool `json:"manageReverseDNS,omitempty" yaml:"manageReverseDNS,omitempty"`
+	// ReverseDNSDomain is the domain of the reverse DNS entries of Nodes, a
+	// Node gets <node name>.<domain>
+	ReverseDNSDomain string `json:"reverseDNSDomain,omitempty" yaml:"reverseDNSDomain,omitempty"`
+}
+
+// reverseDNSController keeps the reverse DNS entries of the IPs of the servers
+// of the cluster in sync with the Nodes and LoadBalancer Services.
+type reverseDNSController struct {
+	client  *gv.Client
+	mutator *cloud.Mutator
+	options reverseDNSOptions
+}
+
+func newReverseDNSController(client *gv.Client, mutator *cloud.Mutator, options reverseDNSOptions) *reverseDNSController {
+	return &reverseDNSController{client: client, mutator: mutator, options: options}
+}
+
+// serverIP is an IP of a server of the cluster and its reverse DNS entry.
+type serverIP struct {
+	server string
+	entry  string
+}
+
+// Start runs the controller until stop is closed, if it is enabled. It needs a
+// cluster ID to tell the servers of the cluster apart from others.
+func (c *reverseDNSController) Start(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	if !c.options.ManageReverseDNS {
+		return
+	}
+	if c.mutator.ClusterID() == "" {
+		log.Warningf("%s: not managing reverse DNS entries, %v", ProviderName, cloud.ErrNoClusterID)
+		return
+	}
+
+	client := clientBuilder.ClientOrDie(ProviderName + "-reverse-dns-controller")
+	go wait.Until(func() {
+		if err := c.Sync(context.Background(), client); err != nil {
+			log.Errorf("%s: failed to sync reverse DNS entries: %v", ProviderName, err)
+		}
+	}, reverseDNSInterval, stop)
+}
+
+// Sync sets the reverse DNS entries of the Nodes and Services and resets those
+// it set for Nodes and Services that were removed since.
+func (c *reverseDNSController) Sync(_ context.Context, kube kubernetes.Interface) error {
+	servers, err := clusterServers(c.client, c.mutator.ClusterID())
+	if err != nil {
+		return err
+	}
+	ips, err := c.serverIPs(servers)
+	if err != nil {
+		return err
+	}
+	nodes, err := kube.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	services, err := kube.CoreV1().Services(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	configMap, err := kube.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(reverseDNSConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: reverseDNSConfigMap}}
+	} else if err != nil {
+		return err
+	}
+
+	var errs []error
+	desired := map[string]string{}
+	if c.options.ReverseDNSDomain != "" {
+		for i := range nodes.Items {
+			node := &nodes.Items[i]
+			server := matchServer(servers, node)
+			if server == nil {
+				continue
+			}
+			entry := node.Name + "." + strings.TrimSuffix(c.options.ReverseDNSDomain, ".")
+			for _, address := range node.Status.Addresses {
+				if ip, found := ips[address.Address]; found && address.Type == v1.NodeExternalIP && ip.server == server.ID {
+					desired[address.Address] = entry
+				}
+			}
+		}
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		entry := strings.TrimSuffix(service.Annotations[reverseDNSAnnotation], ".")
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer || entry == "" {
+			continue
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if _, found := ips[ingress.IP]; !found {
+				errs = append(errs, fmt.Errorf("service %s/%s: ingress IP %s is not an IP of a server of the cluster", service.Namespace, service.Name, ingress.IP))
+				continue
+			}
+			// Services take precedence over Nodes
+			desired[ingress.IP] = entry
+		}
+	}
+
+	managed := map[string]string{}
+	for key, entry := range configMap.Data {
+		managed[strings.Replace(key, "-", ":", -1)] = entry
+	}
+	for _, address := range sortedKeys(managed) {
+		if _, found := desired[address]; found {
+			continue
+		}
+		ip, found := ips[address]
+		if !found {
+			// the IP was released with its server
+			delete(managed, address)
+			continue
+		}
+		if err := c.resetEntry(address, ip); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(managed, address)
+	}
+	for _, address := range sortedKeys(desired) {
+		entry := desired[address]
+		if ips[address].entry != entry {
+			if err := c.setEntry(address, ips[address], entry); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		managed[address] = entry
+	}
+
+	if err := c.record(kube, configMap, managed); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// serverIPs returns the public IPs of servers with their reverse DNS entries.
+func (c *reverseDNSController) serverIPs(servers []gv.Server) (map[string]serverIP, error) {
+	ips := map[string]serverIP{}
+	for _, server := range servers {
+		v4, err := c.client.ListIPv4(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range v4 {
+			if ip.Type != "private" {
+				ips[ip.IP] = serverIP{server: server.ID, entry: ip.ReverseDNS}
+			}
+		}
+		if len(server.V6Networks) == 0 {
+			continue
+		}
+		for _, network := range server.V6Networks {
+			ips[network.MainIP] = serverIP{server: server.ID}
+		}
+		v6, err := c.client.ListIPv6ReverseDNS(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range v6 {
+			ips[ip.IP] = serverIP{server: server.ID, entry: ip.ReverseDNS}
+		}
+	}
+	return ips, nil
+}
+
+// matchServer returns the server of node, by its provider ID if it is set and
+// by its name otherwise.
+func matchServer(servers []gv.Server, node *v1.Node) *gv.Server {
+	for i, server := range servers {
+		if node.Spec.ProviderID != "" {
+			if id, err := serverIDFromProviderID(node.Spec.ProviderID); err == nil && id == server.ID {
+				return &servers[i]
+			}
+		} else if server.Name == node.Name {
+			return &servers[i]
+		}
+	}
+	return nil
+}
+
+func (c *reverseDNSController) setEntry(address string, ip serverIP, entry string) error {
+	err := c.mutator.Do(nil, "set reverse DNS entry", cloud.Params{"ip": address, "server": ip.server, "entry": entry}, func() error {
+		if net.ParseIP(address).To4() != nil {
+			return c.client.SetIPv4ReverseDNS(ip.server, address, entry)
+		}
+		return c.client.SetIPv6ReverseDNS(ip.server, address, entry)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: set reverse DNS entry of %s to %s", ProviderName, address, entry)
+	return nil
+}
+
+// resetEntry resets the reverse DNS entry of an IPv4 address to the default
+// and removes that of an IPv6 address.
+func (c *reverseDNSController) resetEntry(address string, ip serverIP) error {
+	err := c.mutator.Destroy(nil, "reset reverse DNS entry", cloud.Params{"ip": address, "server": ip.server}, func() error {
+		if net.ParseIP(address).To4() != nil {
+			return c.client.DefaultIPv4ReverseDNS(ip.server, address)
+		}
+		return c.client.DeleteIPv6ReverseDNS(ip.server, address)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s: reset reverse DNS entry of %s", ProviderName, address)
+	return nil
+}
+
+// record saves the managed entries in configMap, unless they are unchanged.
+func (c *reverseDNSController) record(kube kubernetes.Interface, configMap *v1.ConfigMap, managed map[string]string) error {
+	data := map[string]string{}
+	for address, entry := range managed {
+		data[strings.Replace(address, ":", "-", -1)] = entry
+	}
+	if cloud.DryRun() || (len(data) == 0 && len(configMap.Data) == 0) || reflect.DeepEqual(data, configMap.Data) {
+		return nil
+	}
+
+	configMap.Data = data
+	var err error
+	if configMap.ResourceVersion == "" {
+		_, err = kube.CoreV1().ConfigMaps(metav1.NamespaceSystem).Create(configMap)
+	} else {
+		_, err = kube.CoreV1().ConfigMaps(metav1.NamespaceSystem).Update(configMap)
+	}
+	return err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package vultr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"pharmer.dev/cloud-controller-manager/cloud"
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
+)
+
+// reverseDNS returns the reverse DNS entries of api by IP.
+func reverseDNS(api *standin.Vultr) map[string]string {
+	entries := map[string]string{}
+	for _, ips := range api.IPv4 {
+		for _, ip := range ips {
+			entries[ip.IP] = ip.ReverseDNS
+		}
+	}
+	for _, ips := range api.IPv6ReverseDNS {
+		for _, ip := range ips {
+			entries[ip.IP] = ip.ReverseDNS
+		}
+	}
+	return entries
+}
+
+func TestReverseDNSController(t *testing.T) {
+	master := gv.Server{ID: "576965", Name: "master", Tag: cloud.ClusterTag("prod")}
+	master.V6Networks = []gv.V6Network{{Network: "2001:db8:1::", MainIP: "2001:db8:1::10", NetworkSize: "64"}}
+	api := standin.NewVultr(master, gv.Server{ID: "576966", Name: "other", Tag: cloud.ClusterTag("staging")})
+	defer api.Close()
+	api.IPv4 = map[string][]gv.IPv4{
+		"576965": {
+			{IP: "203.0.113.10", Type: "main_ip", ReverseDNS: "203.0.113.10.vultr.com"},
+			{IP: "203.0.113.20", Type: "secondary_ip", ReverseDNS: "203.0.113.20.vultr.com"},
+			{IP: "10.0.0.10", Type: "private"},
+		},
+		"576966": {{IP: "198.51.100.10", Type: "main_ip", ReverseDNS: "198.51.100.10.vultr.com"}},
+	}
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newReverseDNSController(client, cloud.NewMutator(ProviderName, "prod"), reverseDNSOptions{
+		ManageReverseDNS: true,
+		ReverseDNSDomain: "nodes.example.com.",
+	})
+
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "master"},
+		Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+			{Type: v1.NodeInternalIP, Address: "10.0.0.10"},
+			{Type: v1.NodeExternalIP, Address: "203.0.113.10"},
+			{Type: v1.NodeExternalIP, Address: "2001:db8:1::10"},
+		}},
+	}
+	kube := standin.NewKubernetes(node)
+	defer kube.Close()
+	mail := hostnameService("mail", "", "203.0.113.20")
+	mail.Annotations = map[string]string{reverseDNSAnnotation: "mail.example.com"}
+	foreign := hostnameService("foreign", "", "198.51.100.10")
+	foreign.Annotations = map[string]string{reverseDNSAnnotation: "foreign.example.com"}
+	kube.Services = []v1.Service{mail, foreign}
+
+	// an IP of another cluster is refused
+	if err := c.Sync(context.Background(), kube.Client()); err == nil {
+		t.Error("expected error for the ingress IP of another cluster")
+	}
+	kube.Services = []v1.Service{mail}
+	if err := c.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"203.0.113.10":   "master.nodes.example.com",
+		"203.0.113.20":   "mail.example.com",
+		"2001:db8:1::10": "master.nodes.example.com",
+		"10.0.0.10":      "",
+		"198.51.100.10":  "198.51.100.10.vultr.com",
+	}
+	if entries := reverseDNS(api); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected reverse DNS %v, got %v", expected, entries)
+	}
+	if len(kube.ConfigMaps) != 1 || len(kube.ConfigMaps[0].Data) != 3 {
+		t.Errorf("expected the entries to be recorded, got %v", kube.ConfigMaps)
+	}
+
+	// entries are reset once the Service and Node are removed
+	kube.Services, kube.Nodes = nil, nil
+	if err := c.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]string{
+		"203.0.113.10":  "203.0.113.10.vultr.com",
+		"203.0.113.20":  "203.0.113.20.vultr.com",
+		"10.0.0.10":     "",
+		"198.51.100.10": "198.51.100.10.vultr.com",
+	}
+	if entries := reverseDNS(api); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected reverse DNS %v, got %v", expected, entries)
+	}
+	if len(kube.ConfigMaps[0].Data) != 0 {
+		t.Errorf("expected no recorded entries, got %v", kube.ConfigMaps[0].Data)
+	}
+}
+
+func TestReverseDNSControllerKeepsForeignEntries(t *testing.T) {
+	api := standin.NewVultr(gv.Server{ID: "576965", Name: "master", Tag: cloud.ClusterTag("prod")})
+	defer api.Close()
+	api.IPv4 = map[string][]gv.IPv4{"576965": {{IP: "203.0.113.10", Type: "main_ip", ReverseDNS: "relay.example.com"}}}
+	client := gv.NewClient("secret", &gv.Options{Endpoint: api.Endpoint(), RateLimitation: rateLimit})
+	c := newReverseDNSController(client, cloud.NewMutator(ProviderName, "prod"), reverseDNSOptions{ManageReverseDNS: true})
+
+	kube := standin.NewKubernetes()
+	defer kube.Close()
+	if err := c.Sync(context.Background(), kube.Client()); err != nil {
+		t.Fatal(err)
+	}
+	// entries not set by the controller are not reset
+	if entry := api.IPv4["576965"][0].ReverseDNS; entry != "relay.example.com" {
+		t.Errorf("expected entry to be kept, got %s", entry)
+	}
+	if len(kube.ConfigMaps) != 0 {
+		t.Errorf("expected no ConfigMap, got %v", kube.ConfigMaps)
+	}
+}
@@ -0,0 +1,30 @@
+package vultr
+
+import (
+	"context"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+// GetLabelsForVolume returns the region label of the block storage of pv.
+// PersistentVolumes that are not backed by block storage of the account are
+// not labeled.
+func (c *Cloud) GetLabelsForVolume(_ context.Context, pv *v1.PersistentVolume) (map[string]string, error) {
+	id := cloud.VolumeID(pv)
+	if id == "" {
+		return nil, nil
+	}
+	storages, err := c.client.GetBlockStorages()
+	if err != nil {
+		return nil, err
+	}
+	for _, storage := range storages {
+		if storage.ID == id {
+			return cloud.VolumeLabels(cloudprovider.Zone{Region: strconv.Itoa(storage.RegionID)}), nil
+		}
+	}
+	return nil, nil
+}
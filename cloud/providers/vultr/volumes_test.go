@@ -0,0 +1,43 @@
+package vultr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gv "github.com/JamesClonk/vultr/lib"
+	v1 "k8s.io/api/core/v1"
+)
+
+func csiVolume(handle string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{
+		PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: handle}},
+	}}
+}
+
+func TestGetLabelsForVolume(t *testing.T) {
+	c, api := newTestCloud(t, "")
+	defer api.Close()
+	api.BlockStorages = []gv.BlockStorage{{ID: "1313217", Name: "pvc-1", RegionID: 1, SizeGB: 10, Status: "active"}}
+
+	labels, err := c.GetLabelsForVolume(context.Background(), csiVolume("1313217"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the region of the volume must match the zone of the servers in it
+	zone, err := c.zones.GetZoneByProviderID(context.Background(), "vultr://576965")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"failure-domain.beta.kubernetes.io/region": zone.Region}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+
+	if labels, err := c.GetLabelsForVolume(context.Background(), csiVolume("999")); err != nil || labels != nil {
+		t.Errorf("expected unknown volume not to be labeled, got %v (%v)", labels, err)
+	}
+	if labels, err := c.GetLabelsForVolume(context.Background(), &v1.PersistentVolume{}); err != nil || labels != nil {
+		t.Errorf("expected volume without ID not to be labeled, got %v (%v)", labels, err)
+	}
+}
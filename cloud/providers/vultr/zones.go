@@ -2,30 +2,35 @@ package vultr
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	gv "github.com/JamesClonk/vultr/lib"
 	"k8s.io/apimachinery/pkg/types"
 	cloudprovider "k8s.io/cloud-provider"
+	"pharmer.dev/cloud-controller-manager/cloud"
 )
 
 const (
-	serverIDURL   = "http://169.254.169.254/v1/instanceid"
-	serverListURL = "https://api.vultr.com/v1/server/list"
+	metadataURL  = "http://169.254.169.254/"
+	serverIDPath = "v1/instanceid"
 )
 
 type zones struct {
-	client *gv.Client
+	client      *gv.Client
+	metadataURL string
+	clusterID   string
 }
 
-func newZones(client *gv.Client) cloudprovider.Zones {
-	return zones{client}
+func newZones(client *gv.Client, metadataURL, clusterID string) cloudprovider.Zones {
+	return zones{client, metadataURL, clusterID}
 }
 
 func (z zones) GetZone(_ context.Context) (cloudprovider.Zone, error) {
-	subid, err := fetchServerID()
+	subid, err := fetchServerID(z.metadataURL)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
@@ -51,20 +56,30 @@ func (z zones) GetZoneByProviderID(_ context.Context, providerID string) (cloudp
 }
 
 func (z zones) GetZoneByNodeName(_ context.Context, nodeName types.NodeName) (cloudprovider.Zone, error) {
-	server, err := serverByName(z.client, nodeName)
+	server, err := serverByName(z.client, z.clusterID, nodeName)
 	if err != nil {
 		return cloudprovider.Zone{}, err
 	}
 	return cloudprovider.Zone{Region: strconv.Itoa(server.RegionID)}, nil
 }
 
-func fetchServerID() (string, error) {
-	resp, err := http.Get(serverIDURL)
+func fetchServerID(metadataURL string) (string, error) {
+	client := cloud.HTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(strings.TrimSuffix(metadataURL, "/") + "/" + serverIDPath)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
-	return string(body), err
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch server id from metadata service: %s", resp.Status)
+	}
+	return string(body), nil
 }
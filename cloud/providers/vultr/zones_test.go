@@ -1,13 +1,34 @@
 package vultr
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
+
+	"pharmer.dev/cloud-controller-manager/cloud/standin"
 )
 
 func TestZone(t *testing.T) {
-	//.Println(fetchRegion(""))
+	metadata := standin.NewMetadata(map[string]string{"/v1/instanceid": "576966"})
+	defer metadata.Close()
+	c, api := newTestCloud(t, metadata.Endpoint())
+	defer api.Close()
+
+	zone, err := c.zones.GetZone(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zone.Region != "1" {
+		t.Errorf("expected region 1, got %s", zone.Region)
+	}
+
+	metadata.Lock()
+	metadata.Paths = nil
+	metadata.Unlock()
+	if _, err := c.zones.GetZone(context.Background()); err == nil {
+		t.Error("expected error when metadata service has no instance id")
+	}
 }
 
 func TestToken(t *testing.T) {
@@ -0,0 +1,54 @@
+package cloud
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var invalidKeyNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// SSHKey is a public key added to instances by AddSSHKeyToAllInstances.
+type SSHKey struct {
+	// Name identifies the key in the cloud account. It is derived from the user
+	// and the key fingerprint, so adding the same key twice yields the same name.
+	Name string
+	// PublicKey is the key in authorized_keys format, without options or comment.
+	PublicKey string
+	// Fingerprint is the MD5 fingerprint of the key, e.g. 9b:1c:...
+	Fingerprint string
+
+	key ssh.PublicKey
+}
+
+// ParseSSHKey parses keyData, a public key in authorized_keys format, of user.
+func ParseSSHKey(user string, keyData []byte) (*SSHKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh public key for user %q: %v", user, err)
+	}
+
+	fingerprint := ssh.FingerprintLegacyMD5(key)
+	prefix := strings.Trim(invalidKeyNameChars.ReplaceAllString(user, "-"), "-")
+	if prefix == "" {
+		prefix = "kubernetes"
+	}
+	return &SSHKey{
+		Name:        prefix + "-" + strings.Replace(fingerprint, ":", "", -1)[:16],
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Fingerprint: fingerprint,
+		key:         key,
+	}, nil
+}
+
+// Matches returns true if authorizedKey, in authorized_keys format, is the same
+// key. Options and comments are ignored.
+func (k *SSHKey) Matches(authorizedKey string) bool {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return false
+	}
+	return ssh.FingerprintSHA256(key) == ssh.FingerprintSHA256(k.key)
+}
@@ -0,0 +1,46 @@
+package cloud
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testKey  = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOyiAiEqGOnlV2yPeHsqMmGnUhpcNTROCwsmk0vxihM+ alice@example.com"
+	otherKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDcQy7wEfMQT8WOVxNKcscvxZ2vZJdhDeRuPzEr/g2eg bob"
+)
+
+func TestParseSSHKey(t *testing.T) {
+	key, err := ParseSSHKey("alice@example.com", []byte(testKey+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key.Name, "alice-example-com-") || len(key.Name) != len("alice-example-com-")+16 {
+		t.Errorf("unexpected key name %q", key.Name)
+	}
+	if want := strings.TrimSuffix(testKey, " alice@example.com"); key.PublicKey != want {
+		t.Errorf("expected public key %q, got %q", want, key.PublicKey)
+	}
+
+	again, err := ParseSSHKey("alice@example.com", []byte(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name != key.Name {
+		t.Errorf("expected stable name %q, got %q", key.Name, again.Name)
+	}
+
+	if !key.Matches(`no-pty ` + testKey) {
+		t.Error("expected key to match itself with options")
+	}
+	if key.Matches(otherKey) || key.Matches("garbage") {
+		t.Error("expected key not to match a different key")
+	}
+
+	if _, err := ParseSSHKey("", []byte(otherKey)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSSHKey("alice", []byte("not a key")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
@@ -0,0 +1,262 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pharmer.dev/cloud-controller-manager/cloud/providers/hetzner/hcloud"
+)
+
+// Hetzner is a stand-in for the Hetzner Cloud API. Lists are paginated by the
+// page and per_page parameters and filtered by name and by label_selector,
+// which supports the key=value and key forms. Actions complete immediately.
+type Hetzner struct {
+	server
+	Servers       []hcloud.Server
+	LoadBalancers []hcloud.LoadBalancer
+	// nextID numbers the created load balancers and actions
+	nextID int64
+}
+
+func NewHetzner(servers ...hcloud.Server) *Hetzner {
+	h := &Hetzner{Servers: servers, nextID: 1000}
+	h.server = newServer(http.HandlerFunc(h.serveHTTP))
+	return h
+}
+
+// Endpoint returns the base URL to configure the Hetzner client with.
+func (h *Hetzner) Endpoint() string {
+	return h.URL + "/v1/"
+}
+
+func hetznerError(w http.ResponseWriter, code int, errCode, msg string) {
+	writeJSON(w, code, map[string]hcloud.Error{"error": {Code: errCode, Message: msg}})
+}
+
+// matchesSelector returns true if labels match selector.
+func matchesSelector(labels map[string]string, selector string) bool {
+	for _, term := range strings.Split(selector, ",") {
+		if term == "" {
+			continue
+		}
+		parts := strings.SplitN(term, "=", 2)
+		value, found := labels[parts[0]]
+		if !found || (len(parts) == 2 && value != parts[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// writePage writes the page of items selected by the page and per_page
+// parameters of r as the list key.
+func writePage(w http.ResponseWriter, r *http.Request, key string, items []interface{}) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 25
+	}
+	pagination := hcloud.Pagination{Page: page, PerPage: perPage}
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	if end < len(items) {
+		next := page + 1
+		pagination.NextPage = &next
+	} else {
+		end = len(items)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		key:    append([]interface{}{}, items[start:end]...),
+		"meta": hcloud.Meta{Pagination: pagination},
+	})
+}
+
+func (h *Hetzner) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		hetznerError(w, http.StatusUnauthorized, "unauthorized", "unable to authenticate")
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+
+	parts := pathParts(r)
+	if len(parts) == 0 || parts[0] != "v1" {
+		hetznerError(w, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+	parts = parts[1:]
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "servers":
+		var servers []interface{}
+		for _, s := range h.Servers {
+			if (query.Get("name") == "" || s.Name == query.Get("name")) && matchesSelector(s.Labels, query.Get("label_selector")) {
+				servers = append(servers, s)
+			}
+		}
+		writePage(w, r, "servers", servers)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "servers":
+		for _, s := range h.Servers {
+			if strconv.FormatInt(s.ID, 10) == parts[1] {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"server": s})
+				return
+			}
+		}
+		hetznerError(w, http.StatusNotFound, "not_found", "server not found")
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "load_balancers":
+		var lbs []interface{}
+		for _, lb := range h.LoadBalancers {
+			if (query.Get("name") == "" || lb.Name == query.Get("name")) && matchesSelector(lb.Labels, query.Get("label_selector")) {
+				lbs = append(lbs, lb)
+			}
+		}
+		writePage(w, r, "load_balancers", lbs)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "load_balancers":
+		h.createLoadBalancer(w, r)
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[0] == "load_balancers":
+		var req struct {
+			Labels map[string]string `json:"labels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			hetznerError(w, http.StatusBadRequest, "invalid_input", err.Error())
+			return
+		}
+		for i := range h.LoadBalancers {
+			if strconv.FormatInt(h.LoadBalancers[i].ID, 10) == parts[1] {
+				h.LoadBalancers[i].Labels = req.Labels
+				writeJSON(w, http.StatusOK, map[string]interface{}{"load_balancer": h.LoadBalancers[i]})
+				return
+			}
+		}
+		hetznerError(w, http.StatusNotFound, "not_found", "load balancer not found")
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "load_balancers":
+		for i, lb := range h.LoadBalancers {
+			if strconv.FormatInt(lb.ID, 10) == parts[1] {
+				h.LoadBalancers = append(h.LoadBalancers[:i], h.LoadBalancers[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		hetznerError(w, http.StatusNotFound, "not_found", "load balancer not found")
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[0] == "load_balancers" && parts[2] == "actions":
+		for i := range h.LoadBalancers {
+			if strconv.FormatInt(h.LoadBalancers[i].ID, 10) == parts[1] {
+				h.loadBalancerAction(w, r, &h.LoadBalancers[i], parts[3])
+				return
+			}
+		}
+		hetznerError(w, http.StatusNotFound, "not_found", "load balancer not found")
+	default:
+		hetznerError(w, http.StatusNotFound, "not_found", "not found")
+	}
+}
+
+// action returns a completed action running command.
+func (h *Hetzner) action(command string) hcloud.Action {
+	h.nextID++
+	return hcloud.Action{ID: h.nextID, Command: command, Status: hcloud.ActionStatusSuccess}
+}
+
+func (h *Hetzner) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	var req hcloud.LoadBalancerCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hetznerError(w, http.StatusBadRequest, "invalid_input", err.Error())
+		return
+	}
+	if req.Name == "" || req.LoadBalancerType == "" || req.Location == "" {
+		hetznerError(w, http.StatusBadRequest, "invalid_input", "name, load_balancer_type and location are required")
+		return
+	}
+	for _, lb := range h.LoadBalancers {
+		if lb.Name == req.Name {
+			hetznerError(w, http.StatusConflict, "uniqueness_error", "name is already used")
+			return
+		}
+	}
+	h.nextID++
+	n := len(h.LoadBalancers) + 1
+	lb := hcloud.LoadBalancer{
+		ID:   h.nextID,
+		Name: req.Name,
+		PublicNet: hcloud.LoadBalancerPublicNet{
+			Enabled: true,
+			IPv4:    hcloud.LoadBalancerPublicIPv4{IP: fmt.Sprintf("198.51.100.%d", n)},
+			IPv6:    hcloud.LoadBalancerPublicIPv6{IP: fmt.Sprintf("2001:db8:100::%d", n)},
+		},
+		Location:         hcloud.Location{Name: req.Location},
+		LoadBalancerType: hcloud.LoadBalancerType{Name: req.LoadBalancerType},
+		Services:         req.Services,
+		Targets:          req.Targets,
+		Labels:           req.Labels,
+	}
+	if req.Network != nil {
+		lb.PrivateNet = []hcloud.LoadBalancerPrivateNet{{Network: *req.Network, IP: fmt.Sprintf("10.0.255.%d", n)}}
+	}
+	h.LoadBalancers = append(h.LoadBalancers, lb)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"load_balancer": lb, "action": h.action("create_load_balancer")})
+}
+
+func (h *Hetzner) loadBalancerAction(w http.ResponseWriter, r *http.Request, lb *hcloud.LoadBalancer, command string) {
+	var target hcloud.LoadBalancerTarget
+	var service hcloud.LoadBalancerService
+	switch command {
+	case "add_target", "remove_target":
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil || target.Server == nil {
+			hetznerError(w, http.StatusBadRequest, "invalid_input", "server target required")
+			return
+		}
+	case "add_service", "update_service", "delete_service":
+		if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+			hetznerError(w, http.StatusBadRequest, "invalid_input", err.Error())
+			return
+		}
+	default:
+		hetznerError(w, http.StatusNotFound, "not_found", "action not found")
+		return
+	}
+
+	targetIndex, serviceIndex := -1, -1
+	for i, t := range lb.Targets {
+		if target.Server != nil && t.Server != nil && t.Server.ID == target.Server.ID {
+			targetIndex = i
+		}
+	}
+	for i, s := range lb.Services {
+		if s.ListenPort == service.ListenPort {
+			serviceIndex = i
+		}
+	}
+	switch {
+	case command == "add_target" && targetIndex >= 0:
+		hetznerError(w, http.StatusConflict, "target_already_defined", "target already defined")
+		return
+	case command == "add_target":
+		lb.Targets = append(lb.Targets, target)
+	case command == "remove_target" && targetIndex < 0:
+		hetznerError(w, http.StatusNotFound, "not_found", "target not found")
+		return
+	case command == "remove_target":
+		lb.Targets = append(lb.Targets[:targetIndex], lb.Targets[targetIndex+1:]...)
+	case command == "add_service" && serviceIndex >= 0:
+		hetznerError(w, http.StatusConflict, "source_port_already_used", "listen port already used")
+		return
+	case command == "add_service":
+		lb.Services = append(lb.Services, service)
+	case serviceIndex < 0:
+		hetznerError(w, http.StatusNotFound, "not_found", "service not found")
+		return
+	case command == "update_service":
+		lb.Services[serviceIndex] = service
+	case command == "delete_service":
+		lb.Services = append(lb.Services[:serviceIndex], lb.Services[serviceIndex+1:]...)
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"action": h.action(command)})
+}
@@ -0,0 +1,246 @@
+package standin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Kubernetes is a stand-in for the parts of the Kubernetes API used by the
+// controllers of the cloud providers: Nodes, Pods, Services, ConfigMaps,
+// evictions and Events. Evicted Pods are removed and recorded in Evictions as
+// <namespace>/<name>.
+type Kubernetes struct {
+	server
+	Nodes      []v1.Node
+	Pods       []v1.Pod
+	Services   []v1.Service
+	ConfigMaps []v1.ConfigMap
+	Events     []v1.Event
+	Evictions  []string
+}
+
+func NewKubernetes(nodes ...v1.Node) *Kubernetes {
+	k := &Kubernetes{Nodes: nodes}
+	k.server = newServer(http.HandlerFunc(k.serveHTTP))
+	return k
+}
+
+// Client returns a client of the stand-in.
+func (k *Kubernetes) Client() kubernetes.Interface {
+	return kubernetes.NewForConfigOrDie(&rest.Config{Host: k.URL})
+}
+
+// Node returns the node called name, or nil if it does not exist.
+func (k *Kubernetes) Node(name string) *v1.Node {
+	k.Lock()
+	defer k.Unlock()
+	for i := range k.Nodes {
+		if k.Nodes[i].Name == name {
+			return k.Nodes[i].DeepCopy()
+		}
+	}
+	return nil
+}
+
+func kubernetesError(w http.ResponseWriter, code int, reason metav1.StatusReason, msg string) {
+	writeJSON(w, code, metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Code:     int32(code),
+		Reason:   reason,
+		Message:  msg,
+	})
+}
+
+func (k *Kubernetes) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+
+	parts := pathParts(r)
+	if len(parts) < 3 || parts[0] != "api" || parts[1] != "v1" {
+		kubernetesError(w, http.StatusNotFound, metav1.StatusReasonNotFound, "the server could not find the requested resource")
+		return
+	}
+	parts = parts[2:]
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "nodes":
+		writeJSON(w, http.StatusOK, v1.NodeList{TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"}, Items: k.Nodes})
+	case len(parts) >= 2 && len(parts) <= 3 && parts[0] == "nodes":
+		k.serveNode(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "pods":
+		k.listPods(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "services":
+		writeJSON(w, http.StatusOK, v1.ServiceList{TypeMeta: metav1.TypeMeta{Kind: "ServiceList", APIVersion: "v1"}, Items: k.Services})
+	case r.Method == http.MethodPost && len(parts) == 5 && parts[0] == "namespaces" && parts[2] == "pods" && parts[4] == "eviction":
+		k.evict(w, r, parts[1], parts[3])
+	case len(parts) >= 3 && len(parts) <= 4 && parts[0] == "namespaces" && parts[2] == "configmaps":
+		k.serveConfigMap(w, r, parts[1], parts[3:])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "namespaces" && parts[2] == "events":
+		var event v1.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+			return
+		}
+		for _, e := range k.Events {
+			if e.Namespace == event.Namespace && e.Name == event.Name {
+				kubernetesError(w, http.StatusConflict, metav1.StatusReasonAlreadyExists, `events "`+e.Name+`" already exists`)
+				return
+			}
+		}
+		k.Events = append(k.Events, event)
+		writeJSON(w, http.StatusCreated, event)
+	default:
+		kubernetesError(w, http.StatusNotFound, metav1.StatusReasonNotFound, "the server could not find the requested resource")
+	}
+}
+
+// serveNode gets, updates or merge patches a node or its status.
+func (k *Kubernetes) serveNode(w http.ResponseWriter, r *http.Request, name string) {
+	i := -1
+	for j := range k.Nodes {
+		if k.Nodes[j].Name == name {
+			i = j
+		}
+	}
+	if i < 0 {
+		kubernetesError(w, http.StatusNotFound, metav1.StatusReasonNotFound, `nodes "`+name+`" not found`)
+		return
+	}
+
+	node := k.Nodes[i]
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		node = v1.Node{}
+		if err := json.NewDecoder(r.Body).Decode(&node); err != nil {
+			kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+			return
+		}
+	case http.MethodPatch:
+		if r.Header.Get("Content-Type") != string(types.MergePatchType) {
+			kubernetesError(w, http.StatusUnsupportedMediaType, metav1.StatusReasonUnsupportedMediaType, "only merge patches are supported")
+			return
+		}
+		patch, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+			return
+		}
+		doc, err := json.Marshal(node)
+		if err != nil {
+			kubernetesError(w, http.StatusInternalServerError, metav1.StatusReasonInternalError, err.Error())
+			return
+		}
+		if doc, err = jsonpatch.MergePatch(doc, patch); err == nil {
+			node = v1.Node{}
+			err = json.Unmarshal(doc, &node)
+		}
+		if err != nil {
+			kubernetesError(w, http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, err.Error())
+			return
+		}
+	default:
+		kubernetesError(w, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed, r.Method+" is not supported")
+		return
+	}
+	node.Kind, node.APIVersion = "Node", "v1"
+	k.Nodes[i] = node
+	writeJSON(w, http.StatusOK, node)
+}
+
+// serveConfigMap creates a ConfigMap in namespace, or gets or updates the
+// ConfigMap whose name is the remaining path part. The resource version of a
+// ConfigMap is increased on every write.
+func (k *Kubernetes) serveConfigMap(w http.ResponseWriter, r *http.Request, namespace string, rest []string) {
+	i := -1
+	for j := range k.ConfigMaps {
+		if len(rest) == 1 && k.ConfigMaps[j].Namespace == namespace && k.ConfigMaps[j].Name == rest[0] {
+			i = j
+		}
+	}
+
+	var configMap v1.ConfigMap
+	switch {
+	case r.Method == http.MethodGet && i >= 0:
+		writeJSON(w, http.StatusOK, k.ConfigMaps[i])
+		return
+	case (r.Method == http.MethodPost && len(rest) == 0) || (r.Method == http.MethodPut && i >= 0):
+		if err := json.NewDecoder(r.Body).Decode(&configMap); err != nil {
+			kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+			return
+		}
+	case len(rest) == 1:
+		kubernetesError(w, http.StatusNotFound, metav1.StatusReasonNotFound, `configmaps "`+rest[0]+`" not found`)
+		return
+	default:
+		kubernetesError(w, http.StatusMethodNotAllowed, metav1.StatusReasonMethodNotAllowed, r.Method+" is not supported")
+		return
+	}
+
+	configMap.Kind, configMap.APIVersion, configMap.Namespace = "ConfigMap", "v1", namespace
+	code := http.StatusOK
+	if i < 0 {
+		for _, c := range k.ConfigMaps {
+			if c.Namespace == namespace && c.Name == configMap.Name {
+				kubernetesError(w, http.StatusConflict, metav1.StatusReasonAlreadyExists, `configmaps "`+c.Name+`" already exists`)
+				return
+			}
+		}
+		configMap.ResourceVersion = "1"
+		k.ConfigMaps = append(k.ConfigMaps, configMap)
+		code = http.StatusCreated
+	} else {
+		version, _ := strconv.Atoi(k.ConfigMaps[i].ResourceVersion)
+		configMap.ResourceVersion = strconv.Itoa(version + 1)
+		k.ConfigMaps[i] = configMap
+	}
+	writeJSON(w, code, configMap)
+}
+
+// listPods lists the pods, filtered by the field selector spec.nodeName=<node>.
+func (k *Kubernetes) listPods(w http.ResponseWriter, r *http.Request) {
+	nodeName, filter := "", false
+	if selector := r.URL.Query().Get("fieldSelector"); selector != "" {
+		if !strings.HasPrefix(selector, "spec.nodeName=") {
+			kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, "unsupported field selector "+selector)
+			return
+		}
+		nodeName, filter = strings.TrimPrefix(selector, "spec.nodeName="), true
+	}
+
+	pods := []v1.Pod{}
+	for _, p := range k.Pods {
+		if !filter || p.Spec.NodeName == nodeName {
+			pods = append(pods, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, v1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}, Items: pods})
+}
+
+func (k *Kubernetes) evict(w http.ResponseWriter, r *http.Request, namespace, name string) {
+	var eviction policy.Eviction
+	if err := json.NewDecoder(r.Body).Decode(&eviction); err != nil {
+		kubernetesError(w, http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+		return
+	}
+	for i, p := range k.Pods {
+		if p.Namespace == namespace && p.Name == name {
+			k.Pods = append(k.Pods[:i], k.Pods[i+1:]...)
+			k.Evictions = append(k.Evictions, namespace+"/"+name)
+			writeJSON(w, http.StatusCreated, eviction)
+			return
+		}
+	}
+	kubernetesError(w, http.StatusNotFound, metav1.StatusReasonNotFound, `pods "`+name+`" not found`)
+}
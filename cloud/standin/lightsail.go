@@ -0,0 +1,150 @@
+package standin
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/lightsail"
+	"golang.org/x/crypto/ssh"
+)
+
+const lightsailTargetPrefix = "Lightsail_20161128."
+
+// Lightsail is a stand-in for the AWS Lightsail JSON API. PortStates are the
+// public ports of the instances, keyed by instance name.
+type Lightsail struct {
+	server
+	Instances  []*lightsail.Instance
+	KeyPairs   []*lightsail.KeyPair
+	PortStates map[string][]*lightsail.InstancePortState
+}
+
+func NewLightsail(instances ...*lightsail.Instance) *Lightsail {
+	l := &Lightsail{Instances: instances}
+	l.server = newServer(http.HandlerFunc(l.serveHTTP))
+	return l
+}
+
+// Endpoint returns the URL to configure the AWS session with.
+func (l *Lightsail) Endpoint() string {
+	return l.URL
+}
+
+func lightsailError(w http.ResponseWriter, code int, typ, msg string) {
+	writeJSON(w, code, map[string]string{"__type": typ, "message": msg})
+}
+
+// writeAWSJSON encodes v with the AWS JSON protocol, which uses the
+// locationName of struct fields instead of json tags.
+func writeAWSJSON(w http.ResponseWriter, v interface{}) {
+	data, err := jsonutil.BuildJSON(v)
+	if err != nil {
+		lightsailError(w, http.StatusInternalServerError, "ServiceException", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Write(data)
+}
+
+func (l *Lightsail) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+		lightsailError(w, http.StatusBadRequest, "MissingAuthenticationTokenException", "Missing Authentication Token")
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), lightsailTargetPrefix)
+	switch op {
+	case "GetInstance":
+		in := &lightsail.GetInstanceInput{}
+		if !decodeAWSJSON(w, r, in) {
+			return
+		}
+		for _, i := range l.Instances {
+			if in.InstanceName != nil && i.Name != nil && *i.Name == *in.InstanceName {
+				writeAWSJSON(w, &lightsail.GetInstanceOutput{Instance: i})
+				return
+			}
+		}
+		lightsailError(w, http.StatusBadRequest, "NotFoundException", "The Instance does not exist.")
+	case "GetInstances":
+		writeAWSJSON(w, &lightsail.GetInstancesOutput{Instances: l.Instances})
+	case "GetKeyPairs":
+		writeAWSJSON(w, &lightsail.GetKeyPairsOutput{KeyPairs: l.KeyPairs})
+	case "ImportKeyPair":
+		in := &lightsail.ImportKeyPairInput{}
+		if !decodeAWSJSON(w, r, in) {
+			return
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(aws.StringValue(in.PublicKeyBase64)))
+		if err != nil {
+			lightsailError(w, http.StatusBadRequest, "InvalidInputException", "Invalid public key")
+			return
+		}
+		for _, kp := range l.KeyPairs {
+			if aws.StringValue(kp.Name) == aws.StringValue(in.KeyPairName) {
+				lightsailError(w, http.StatusBadRequest, "InvalidInputException", "Some names are already in use")
+				return
+			}
+		}
+		l.KeyPairs = append(l.KeyPairs, &lightsail.KeyPair{
+			Name:        in.KeyPairName,
+			Fingerprint: aws.String(ssh.FingerprintLegacyMD5(key)),
+		})
+		writeAWSJSON(w, &lightsail.ImportKeyPairOutput{})
+	case "GetInstancePortStates":
+		in := &lightsail.GetInstancePortStatesInput{}
+		if !decodeAWSJSON(w, r, in) {
+			return
+		}
+		writeAWSJSON(w, &lightsail.GetInstancePortStatesOutput{PortStates: l.PortStates[aws.StringValue(in.InstanceName)]})
+	case "OpenInstancePublicPorts":
+		in := &lightsail.OpenInstancePublicPortsInput{}
+		if !decodeAWSJSON(w, r, in) {
+			return
+		}
+		if l.PortStates == nil {
+			l.PortStates = map[string][]*lightsail.InstancePortState{}
+		}
+		name := aws.StringValue(in.InstanceName)
+		l.PortStates[name] = append(l.PortStates[name], &lightsail.InstancePortState{
+			FromPort: in.PortInfo.FromPort,
+			ToPort:   in.PortInfo.ToPort,
+			Protocol: in.PortInfo.Protocol,
+			State:    aws.String(lightsail.PortStateOpen),
+		})
+		writeAWSJSON(w, &lightsail.OpenInstancePublicPortsOutput{})
+	case "CloseInstancePublicPorts":
+		in := &lightsail.CloseInstancePublicPortsInput{}
+		if !decodeAWSJSON(w, r, in) {
+			return
+		}
+		name := aws.StringValue(in.InstanceName)
+		var states []*lightsail.InstancePortState
+		for _, state := range l.PortStates[name] {
+			if aws.Int64Value(state.FromPort) != aws.Int64Value(in.PortInfo.FromPort) ||
+				aws.Int64Value(state.ToPort) != aws.Int64Value(in.PortInfo.ToPort) ||
+				aws.StringValue(state.Protocol) != aws.StringValue(in.PortInfo.Protocol) {
+				states = append(states, state)
+			}
+		}
+		l.PortStates[name] = states
+		writeAWSJSON(w, &lightsail.CloseInstancePublicPortsOutput{})
+	default:
+		lightsailError(w, http.StatusBadRequest, "InvalidAction", "Unknown operation "+op)
+	}
+}
+
+func decodeAWSJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(r.Body)
+	if err := jsonutil.UnmarshalJSON(v, buf); err != nil {
+		lightsailError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,302 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"pharmer.dev/cloud-controller-manager/cloud/providers/linode/linodeapi"
+)
+
+// Linode is a stand-in for the Linode API v4. Lists are paginated by the page
+// and page_size parameters. NodeBalancerConfigs are keyed by NodeBalancer ID
+// and NodeBalancerNodes by config ID.
+type Linode struct {
+	server
+	Instances           []linodeapi.Instance
+	NodeBalancers       []linodeapi.NodeBalancer
+	NodeBalancerConfigs map[int][]linodeapi.NodeBalancerConfig
+	NodeBalancerNodes   map[int][]linodeapi.NodeBalancerNode
+	// nextID numbers the created NodeBalancers, configs and nodes
+	nextID int
+}
+
+func NewLinode(instances ...linodeapi.Instance) *Linode {
+	l := &Linode{
+		Instances:           instances,
+		NodeBalancerConfigs: map[int][]linodeapi.NodeBalancerConfig{},
+		NodeBalancerNodes:   map[int][]linodeapi.NodeBalancerNode{},
+		nextID:              2000,
+	}
+	l.server = newServer(http.HandlerFunc(l.serveHTTP))
+	return l
+}
+
+// Endpoint returns the base URL to configure the Linode client with.
+func (l *Linode) Endpoint() string {
+	return l.URL + "/v4/"
+}
+
+func linodeError(w http.ResponseWriter, code int, reason string) {
+	writeJSON(w, code, linodeapi.Error{Errors: []linodeapi.ErrorReason{{Reason: reason}}})
+}
+
+// writeLinodePage writes the page of items selected by the page and page_size
+// parameters of r.
+func writeLinodePage(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 {
+		pageSize = 100
+	}
+	pages := (len(items) + pageSize - 1) / pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	start, end := (page-1)*pageSize, page*pageSize
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":    append([]interface{}{}, items[start:end]...),
+		"page":    page,
+		"pages":   pages,
+		"results": len(items),
+	})
+}
+
+func (l *Linode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		linodeError(w, http.StatusUnauthorized, "Invalid Token")
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	parts := pathParts(r)
+	if len(parts) == 0 || parts[0] != "v4" {
+		linodeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	parts = parts[1:]
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "linode" && parts[1] == "instances":
+		var instances []interface{}
+		for _, instance := range l.Instances {
+			instances = append(instances, instance)
+		}
+		writeLinodePage(w, r, instances)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "linode" && parts[1] == "instances":
+		for _, instance := range l.Instances {
+			if strconv.Itoa(instance.ID) == parts[2] {
+				writeJSON(w, http.StatusOK, instance)
+				return
+			}
+		}
+		linodeError(w, http.StatusNotFound, "Not found")
+	case len(parts) >= 1 && parts[0] == "nodebalancers":
+		l.serveNodeBalancers(w, r, parts[1:])
+	default:
+		linodeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (l *Linode) serveNodeBalancers(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			var nbs []interface{}
+			for _, nb := range l.NodeBalancers {
+				nbs = append(nbs, nb)
+			}
+			writeLinodePage(w, r, nbs)
+		case http.MethodPost:
+			l.createNodeBalancer(w, r)
+		default:
+			linodeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+		return
+	}
+
+	index := -1
+	for i, nb := range l.NodeBalancers {
+		if strconv.Itoa(nb.ID) == parts[0] {
+			index = i
+		}
+	}
+	if index < 0 {
+		linodeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	nb := l.NodeBalancers[index]
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, nb)
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var opts struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			linodeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		l.NodeBalancers[index].Tags = opts.Tags
+		writeJSON(w, http.StatusOK, l.NodeBalancers[index])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		for _, config := range l.NodeBalancerConfigs[nb.ID] {
+			delete(l.NodeBalancerNodes, config.ID)
+		}
+		delete(l.NodeBalancerConfigs, nb.ID)
+		l.NodeBalancers = append(l.NodeBalancers[:index], l.NodeBalancers[index+1:]...)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	case len(parts) == 2 && parts[1] == "configs" && r.Method == http.MethodGet:
+		var configs []interface{}
+		for _, config := range l.NodeBalancerConfigs[nb.ID] {
+			configs = append(configs, config)
+		}
+		writeLinodePage(w, r, configs)
+	case len(parts) == 2 && parts[1] == "configs" && r.Method == http.MethodPost:
+		var opts linodeapi.NodeBalancerConfigCreateOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			linodeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		config, ok := l.addConfig(w, nb.ID, opts)
+		if ok {
+			writeJSON(w, http.StatusOK, config)
+		}
+	case len(parts) >= 3 && parts[1] == "configs":
+		l.serveConfig(w, r, nb.ID, parts[2:])
+	default:
+		linodeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (l *Linode) serveConfig(w http.ResponseWriter, r *http.Request, nbID int, parts []string) {
+	configs := l.NodeBalancerConfigs[nbID]
+	index := -1
+	for i, config := range configs {
+		if strconv.Itoa(config.ID) == parts[0] {
+			index = i
+		}
+	}
+	if index < 0 {
+		linodeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	config := configs[index]
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var opts struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			linodeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		l.NodeBalancers[index].Tags = opts.Tags
+		writeJSON(w, http.StatusOK, l.NodeBalancers[index])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(l.NodeBalancerNodes, config.ID)
+		l.NodeBalancerConfigs[nbID] = append(configs[:index], configs[index+1:]...)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	case len(parts) == 2 && parts[1] == "nodes" && r.Method == http.MethodGet:
+		var nodes []interface{}
+		for _, node := range l.NodeBalancerNodes[config.ID] {
+			nodes = append(nodes, node)
+		}
+		writeLinodePage(w, r, nodes)
+	case len(parts) == 2 && parts[1] == "rebuild" && r.Method == http.MethodPost:
+		var opts linodeapi.NodeBalancerConfigCreateOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			linodeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		config.Port, config.Protocol = opts.Port, opts.Protocol
+		configs[index] = config
+		l.NodeBalancerNodes[config.ID] = l.nodes(nbID, config.ID, opts.Nodes)
+		writeJSON(w, http.StatusOK, config)
+	default:
+		linodeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (l *Linode) createNodeBalancer(w http.ResponseWriter, r *http.Request) {
+	var opts linodeapi.NodeBalancerCreateOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		linodeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Region == "" {
+		writeJSON(w, http.StatusBadRequest, linodeapi.Error{Errors: []linodeapi.ErrorReason{{Field: "region", Reason: "region is required"}}})
+		return
+	}
+	for _, nb := range l.NodeBalancers {
+		if nb.Label == opts.Label {
+			writeJSON(w, http.StatusBadRequest, linodeapi.Error{Errors: []linodeapi.ErrorReason{{Field: "label", Reason: "Label must be unique"}}})
+			return
+		}
+	}
+	l.nextID++
+	n := len(l.NodeBalancers) + 1
+	nb := linodeapi.NodeBalancer{
+		ID:       l.nextID,
+		Label:    opts.Label,
+		Region:   opts.Region,
+		Hostname: fmt.Sprintf("nb-198-51-100-%d.newark.nodebalancer.linode.com", n),
+		IPv4:     fmt.Sprintf("198.51.100.%d", n),
+		IPv6:     fmt.Sprintf("2001:db8:100::%d", n),
+		Tags:     opts.Tags,
+	}
+	if nb.Label == "" {
+		nb.Label = fmt.Sprintf("nodebalancer%d", nb.ID)
+	}
+	l.NodeBalancers = append(l.NodeBalancers, nb)
+	for _, config := range opts.Configs {
+		if _, ok := l.addConfig(w, nb.ID, config); !ok {
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, nb)
+}
+
+// addConfig adds a config with the nodes of opts to the NodeBalancer nbID. It
+// writes an error and returns false if the port is used already.
+func (l *Linode) addConfig(w http.ResponseWriter, nbID int, opts linodeapi.NodeBalancerConfigCreateOptions) (linodeapi.NodeBalancerConfig, bool) {
+	for _, config := range l.NodeBalancerConfigs[nbID] {
+		if config.Port == opts.Port {
+			writeJSON(w, http.StatusBadRequest, linodeapi.Error{Errors: []linodeapi.ErrorReason{{Field: "port", Reason: "Port is already in use"}}})
+			return linodeapi.NodeBalancerConfig{}, false
+		}
+	}
+	l.nextID++
+	config := linodeapi.NodeBalancerConfig{ID: l.nextID, Port: opts.Port, Protocol: opts.Protocol, Algorithm: "roundrobin", NodeBalancerID: nbID}
+	l.NodeBalancerConfigs[nbID] = append(l.NodeBalancerConfigs[nbID], config)
+	l.NodeBalancerNodes[config.ID] = l.nodes(nbID, config.ID, opts.Nodes)
+	return config, true
+}
+
+func (l *Linode) nodes(nbID, configID int, opts []linodeapi.NodeBalancerNodeCreateOptions) []linodeapi.NodeBalancerNode {
+	var nodes []linodeapi.NodeBalancerNode
+	for _, o := range opts {
+		l.nextID++
+		nodes = append(nodes, linodeapi.NodeBalancerNode{
+			ID:             l.nextID,
+			Address:        o.Address,
+			Label:          o.Label,
+			Status:         "UP",
+			Weight:         100,
+			Mode:           "accept",
+			ConfigID:       configID,
+			NodeBalancerID: nbID,
+		})
+	}
+	return nodes
+}
@@ -0,0 +1,118 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/packethost/packngo"
+)
+
+// Packet is a stand-in for the Packet API. Devices are listed under the
+// project they reference, SSHKeys are keyed by project ID. Events are listed
+// in their order, which should be newest first like the Packet API does, and
+// paginated by the page and per_page parameters.
+type Packet struct {
+	server
+	Devices []packngo.Device
+	SSHKeys map[string][]packngo.SSHKey
+	Events  []packngo.Event
+	Volumes []packngo.Volume
+}
+
+func NewPacket(devices ...packngo.Device) *Packet {
+	p := &Packet{Devices: devices, SSHKeys: map[string][]packngo.SSHKey{}}
+	p.server = newServer(http.HandlerFunc(p.serveHTTP))
+	return p
+}
+
+// Endpoint returns the base URL to configure the Packet client with.
+func (p *Packet) Endpoint() string {
+	return p.URL + "/"
+}
+
+func packetError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string][]string{"errors": {msg}})
+}
+
+func (p *Packet) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") == "" {
+		packetError(w, http.StatusUnauthorized, "Invalid authentication token")
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+
+	parts := pathParts(r)
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "projects" && parts[2] == "devices":
+		devices := []packngo.Device{}
+		for _, d := range p.Devices {
+			if d.Project != nil && d.Project.ID == parts[1] {
+				devices = append(devices, d)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"devices": devices,
+			"meta":    map[string]int{"total": len(devices)},
+		})
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "devices":
+		for _, d := range p.Devices {
+			if d.ID == parts[1] {
+				writeJSON(w, http.StatusOK, d)
+				return
+			}
+		}
+		packetError(w, http.StatusNotFound, "Not found")
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "storage":
+		for _, v := range p.Volumes {
+			if v.ID == parts[1] {
+				writeJSON(w, http.StatusOK, v)
+				return
+			}
+		}
+		packetError(w, http.StatusNotFound, "Not found")
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "events":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if perPage < 1 {
+			perPage = 10
+		}
+		start, end := (page-1)*perPage, page*perPage
+		if start > len(p.Events) {
+			start = len(p.Events)
+		}
+		if end > len(p.Events) {
+			end = len(p.Events)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"events": append([]packngo.Event{}, p.Events[start:end]...),
+			"meta":   map[string]int{"total": len(p.Events), "current_page": page, "last_page": (len(p.Events) + perPage - 1) / perPage},
+		})
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "projects" && parts[2] == "ssh-keys":
+		keys := p.SSHKeys[parts[1]]
+		if keys == nil {
+			keys = []packngo.SSHKey{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ssh_keys": keys})
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "projects" && parts[2] == "ssh-keys":
+		var req packngo.SSHKeyCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			packetError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		n := 0
+		for _, keys := range p.SSHKeys {
+			n += len(keys)
+		}
+		key := packngo.SSHKey{ID: fmt.Sprintf("key-%d", n+1), Label: req.Label, Key: req.Key}
+		p.SSHKeys[parts[1]] = append(p.SSHKeys[parts[1]], key)
+		writeJSON(w, http.StatusCreated, key)
+	default:
+		packetError(w, http.StatusNotFound, "Not found")
+	}
+}
@@ -0,0 +1,192 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	scw "github.com/scaleway/scaleway-cli/pkg/api"
+)
+
+// Scaleway is a stand-in for the Scaleway compute and account APIs. Servers
+// are served under the compute URL of their zone, see ComputeEndpoint, and so
+// are Volumes and SecurityGroups, which are keyed by zone. SecurityGroupRules
+// are keyed by group ID. Every token belongs to User.
+type Scaleway struct {
+	server
+	Servers            []scw.ScalewayServer
+	Volumes            map[string][]scw.ScalewayVolume
+	SecurityGroups     map[string][]scw.ScalewaySecurityGroups
+	SecurityGroupRules map[string][]scw.ScalewaySecurityGroupRule
+	User               scw.ScalewayUserDefinition
+	// nextID numbers the created security groups and rules
+	nextID int
+}
+
+func NewScaleway(servers ...scw.ScalewayServer) *Scaleway {
+	s := &Scaleway{Servers: servers}
+	s.server = newServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// AccountEndpoint returns the base URL of the account API.
+func (s *Scaleway) AccountEndpoint() string {
+	return s.URL + "/account/"
+}
+
+// ComputeEndpoint returns the base URL of the compute API of zone, e.g. par1.
+func (s *Scaleway) ComputeEndpoint(zone string) string {
+	return s.URL + "/" + zone + "/"
+}
+
+func scalewayError(w http.ResponseWriter, code int, typ, msg string) {
+	writeJSON(w, code, scw.ScalewayAPIError{Type: typ, APIMessage: msg})
+}
+
+func (s *Scaleway) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") == "" {
+		scalewayError(w, http.StatusUnauthorized, "invalid_auth", "Authentication error")
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	parts := pathParts(r)
+	switch {
+	case len(parts) == 3 && parts[0] == "account" && parts[1] == "tokens":
+		writeJSON(w, http.StatusOK, scw.ScalewayTokensDefinition{
+			Token: scw.ScalewayTokenDefinition{ID: parts[2], UserID: s.User.ID},
+		})
+	case len(parts) == 3 && parts[0] == "account" && parts[1] == "users" && parts[2] == s.User.ID:
+		if r.Method == http.MethodPatch {
+			var patch scw.ScalewayUserPatchSSHKeyDefinition
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				scalewayError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+				return
+			}
+			s.User.SSHPublicKeys = patch.SSHPublicKeys
+		}
+		writeJSON(w, http.StatusOK, scw.ScalewayUsersDefinition{User: s.User})
+	case len(parts) == 2 && parts[1] == "servers":
+		servers := []scw.ScalewayServer{}
+		for _, server := range s.Servers {
+			if server.Location.ZoneID == parts[0] {
+				servers = append(servers, server)
+			}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(servers)))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"servers": servers})
+	case len(parts) == 3 && parts[1] == "servers":
+		for i, server := range s.Servers {
+			if server.Identifier == parts[2] && server.Location.ZoneID == parts[0] {
+				if r.Method == http.MethodPatch {
+					var patch scw.ScalewayServerPatchDefinition
+					if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+						scalewayError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+						return
+					}
+					if patch.SecurityGroup != nil {
+						s.Servers[i].SecurityGroup = *patch.SecurityGroup
+					}
+				}
+				writeJSON(w, http.StatusOK, map[string]interface{}{"server": s.Servers[i]})
+				return
+			}
+		}
+		scalewayError(w, http.StatusNotFound, "unknown_resource", "Unable to find server "+parts[2])
+	case len(parts) == 3 && parts[1] == "volumes":
+		for _, volume := range s.Volumes[parts[0]] {
+			if volume.Identifier == parts[2] {
+				writeJSON(w, http.StatusOK, scw.ScalewayOneVolume{Volume: volume})
+				return
+			}
+		}
+		scalewayError(w, http.StatusNotFound, "unknown_resource", "Unable to find volume "+parts[2])
+	case len(parts) == 2 && parts[1] == "security_groups":
+		s.serveSecurityGroups(w, r, parts[0])
+	case len(parts) >= 4 && len(parts) <= 5 && parts[1] == "security_groups" && parts[3] == "rules":
+		s.serveSecurityGroupRules(w, r, parts[2], parts[4:])
+	default:
+		scalewayError(w, http.StatusNotFound, "unknown_resource", "Not found")
+	}
+}
+
+// serveSecurityGroups lists or creates the security groups of zone.
+func (s *Scaleway) serveSecurityGroups(w http.ResponseWriter, r *http.Request, zone string) {
+	if r.Method == http.MethodPost {
+		var group scw.ScalewayNewSecurityGroup
+		if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+			scalewayError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+		if s.SecurityGroups == nil {
+			s.SecurityGroups = map[string][]scw.ScalewaySecurityGroups{}
+		}
+		s.nextID++
+		created := scw.ScalewaySecurityGroups{
+			ID:           fmt.Sprintf("sg-%d", s.nextID),
+			Organization: group.Organization,
+			Name:         group.Name,
+			Description:  group.Description,
+		}
+		s.SecurityGroups[zone] = append(s.SecurityGroups[zone], created)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"security_group": created})
+		return
+	}
+	groups := append([]scw.ScalewaySecurityGroups{}, s.SecurityGroups[zone]...)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(groups)))
+	writeJSON(w, http.StatusOK, scw.ScalewayGetSecurityGroups{SecurityGroups: groups})
+}
+
+// serveSecurityGroupRules lists or creates the rules of a security group, or
+// deletes the rule whose ID is the remaining path part. Created rules are
+// appended at the next position.
+func (s *Scaleway) serveSecurityGroupRules(w http.ResponseWriter, r *http.Request, groupID string, rest []string) {
+	rules := s.SecurityGroupRules[groupID]
+	switch {
+	case r.Method == http.MethodDelete && len(rest) == 1:
+		for i, rule := range rules {
+			if rule.ID == rest[0] {
+				s.SecurityGroupRules[groupID] = append(rules[:i:i], rules[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		scalewayError(w, http.StatusNotFound, "unknown_resource", "Unable to find rule "+rest[0])
+	case r.Method == http.MethodPost && len(rest) == 0:
+		var rule scw.ScalewayNewSecurityGroupRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			scalewayError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+		position := 1
+		for _, existing := range rules {
+			if existing.Position >= position {
+				position = existing.Position + 1
+			}
+		}
+		if s.SecurityGroupRules == nil {
+			s.SecurityGroupRules = map[string][]scw.ScalewaySecurityGroupRule{}
+		}
+		s.nextID++
+		created := scw.ScalewaySecurityGroupRule{
+			ID:           fmt.Sprintf("rule-%d", s.nextID),
+			Action:       rule.Action,
+			Direction:    rule.Direction,
+			IPRange:      rule.IPRange,
+			Protocol:     rule.Protocol,
+			DestPortFrom: rule.DestPortFrom,
+			Position:     position,
+			Editable:     true,
+		}
+		s.SecurityGroupRules[groupID] = append(rules, created)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"rule": created})
+	case len(rest) == 0:
+		rules = append([]scw.ScalewaySecurityGroupRule{}, rules...)
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(rules)))
+		writeJSON(w, http.StatusOK, scw.ScalewayGetSecurityGroupRules{Rules: rules})
+	default:
+		scalewayError(w, http.StatusNotFound, "unknown_resource", "Not found")
+	}
+}
@@ -0,0 +1,99 @@
+package standin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/softlayer/softlayer-go/datatypes"
+)
+
+// SoftLayer is a stand-in for the SoftLayer REST API. Primary IP addresses,
+// the network components and the datacenter of a guest are served from
+// the guest's own fields. Object masks are ignored.
+type SoftLayer struct {
+	server
+	Guests []datatypes.Virtual_Guest
+}
+
+func NewSoftLayer(guests ...datatypes.Virtual_Guest) *SoftLayer {
+	s := &SoftLayer{Guests: guests}
+	s.server = newServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint returns the REST endpoint to configure the SoftLayer session with.
+func (s *SoftLayer) Endpoint() string {
+	return s.URL + "/rest/v3"
+}
+
+func softlayerError(w http.ResponseWriter, code int, slCode, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg, "code": slCode})
+}
+
+func (s *SoftLayer) guest(id string) (datatypes.Virtual_Guest, bool) {
+	guestID, err := strconv.Atoi(id)
+	if err != nil {
+		return datatypes.Virtual_Guest{}, false
+	}
+	for _, g := range s.Guests {
+		if g.Id != nil && *g.Id == guestID {
+			return g, true
+		}
+	}
+	return datatypes.Virtual_Guest{}, false
+}
+
+func (s *SoftLayer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); !ok {
+		softlayerError(w, http.StatusUnauthorized, "SoftLayer_Exception_Public", "Access Denied.")
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	parts := pathParts(r)
+	if len(parts) < 3 || parts[0] != "rest" || parts[1] != "v3" {
+		http.NotFound(w, r)
+		return
+	}
+	parts = parts[2:]
+	last := len(parts) - 1
+	parts[last] = strings.TrimSuffix(parts[last], ".json")
+
+	switch {
+	case len(parts) == 2 && parts[0] == "SoftLayer_Account" && parts[1] == "getVirtualGuests":
+		writeJSON(w, http.StatusOK, s.Guests)
+	case len(parts) >= 2 && parts[0] == "SoftLayer_Virtual_Guest":
+		g, found := s.guest(parts[1])
+		if !found {
+			softlayerError(w, http.StatusNotFound, "SoftLayer_Exception_ObjectNotFound", fmt.Sprintf("Unable to find object with id of '%s'.", parts[1]))
+			return
+		}
+		method := "getObject"
+		if len(parts) == 3 {
+			method = parts[2]
+		}
+		switch method {
+		case "getObject":
+			writeJSON(w, http.StatusOK, g)
+		case "getPrimaryIpAddress":
+			writeJSON(w, http.StatusOK, g.PrimaryIpAddress)
+		case "getPrimaryBackendIpAddress":
+			writeJSON(w, http.StatusOK, g.PrimaryBackendIpAddress)
+		case "getNetworkComponents":
+			components := g.NetworkComponents
+			if components == nil {
+				components = []datatypes.Virtual_Guest_Network_Component{}
+			}
+			writeJSON(w, http.StatusOK, components)
+		case "getDatacenter":
+			writeJSON(w, http.StatusOK, g.Datacenter)
+		default:
+			softlayerError(w, http.StatusNotFound, "SoftLayer_Exception_Public", "Function (\""+method+"\") is not a valid method for this service.")
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,73 @@
+// Package standin provides local HTTP stand-ins for the cloud provider APIs
+// and metadata services, so that provider code can be tested without network
+// access. Every stand-in embeds an *httptest.Server and keeps its inventory in
+// exported fields that tests may change between calls while holding Lock.
+package standin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+type server struct {
+	*httptest.Server
+	sync.Mutex
+}
+
+func newServer(handler http.Handler) server {
+	return server{Server: httptest.NewServer(handler)}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// pathParts splits the URL path of r into its non-empty segments.
+func pathParts(r *http.Request) []string {
+	var parts []string
+	for _, p := range strings.Split(r.URL.Path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// Metadata is a stand-in for an instance metadata service such as
+// http://169.254.169.254. It serves Paths, keyed by URL path, as plain text.
+type Metadata struct {
+	server
+	Paths map[string]string
+}
+
+func NewMetadata(paths map[string]string) *Metadata {
+	m := &Metadata{Paths: paths}
+	m.server = newServer(http.HandlerFunc(m.serveHTTP))
+	return m
+}
+
+// Endpoint returns the base URL of the metadata service.
+func (m *Metadata) Endpoint() string {
+	return m.URL + "/"
+}
+
+func (m *Metadata) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+	body, found := m.Paths[r.URL.Path]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write([]byte(body))
+}
@@ -0,0 +1,434 @@
+package standin
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	gv "github.com/JamesClonk/vultr/lib"
+)
+
+// Vultr is a stand-in for the Vultr v1 API. FirewallRules are keyed by
+// firewall group ID and DNSRecords by domain.
+type Vultr struct {
+	server
+	Servers []gv.Server
+	SSHKeys []gv.SSHKey
+	Plans   []gv.Plan
+	// IPv4 are the IPv4 addresses of the servers, keyed by server ID
+	IPv4 map[string][]gv.IPv4
+	// IPv6ReverseDNS are the reverse DNS entries of the IPv6 addresses of
+	// the servers, keyed by server ID
+	IPv6ReverseDNS map[string][]gv.ReverseDNSIPv6
+	BlockStorages  []gv.BlockStorage
+	FirewallGroups []gv.FirewallGroup
+	FirewallRules  map[string][]gv.FirewallRule
+	DNSDomains     []gv.DNSDomain
+	DNSRecords     map[string][]gv.DNSRecord
+}
+
+func NewVultr(servers ...gv.Server) *Vultr {
+	v := &Vultr{Servers: servers, FirewallRules: map[string][]gv.FirewallRule{}, DNSRecords: map[string][]gv.DNSRecord{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/server/list", v.listServers)
+	mux.HandleFunc("/v1/server/list_ipv4", v.listIPv4)
+	mux.HandleFunc("/v1/server/reverse_set_ipv4", v.setIPv4ReverseDNS)
+	mux.HandleFunc("/v1/server/reverse_default_ipv4", v.setIPv4ReverseDNS)
+	mux.HandleFunc("/v1/server/reverse_list_ipv6", v.listIPv6ReverseDNS)
+	mux.HandleFunc("/v1/server/reverse_set_ipv6", v.setIPv6ReverseDNS)
+	mux.HandleFunc("/v1/server/reverse_delete_ipv6", v.setIPv6ReverseDNS)
+	mux.HandleFunc("/v1/sshkey/list", v.listSSHKeys)
+	mux.HandleFunc("/v1/sshkey/create", v.createSSHKey)
+	mux.HandleFunc("/v1/plans/list", v.listPlans)
+	mux.HandleFunc("/v1/block/list", v.listBlockStorages)
+	mux.HandleFunc("/v1/server/firewall_group_set", v.setFirewallGroup)
+	mux.HandleFunc("/v1/firewall/group_list", v.listFirewallGroups)
+	mux.HandleFunc("/v1/firewall/group_create", v.createFirewallGroup)
+	mux.HandleFunc("/v1/firewall/rule_list", v.listFirewallRules)
+	mux.HandleFunc("/v1/firewall/rule_create", v.createFirewallRule)
+	mux.HandleFunc("/v1/firewall/rule_delete", v.deleteFirewallRule)
+	mux.HandleFunc("/v1/dns/list", v.listDNSDomains)
+	mux.HandleFunc("/v1/dns/records", v.listDNSRecords)
+	mux.HandleFunc("/v1/dns/create_record", v.createDNSRecord)
+	mux.HandleFunc("/v1/dns/update_record", v.updateDNSRecord)
+	mux.HandleFunc("/v1/dns/delete_record", v.deleteDNSRecord)
+	v.server = newServer(v.authenticate(mux))
+	return v
+}
+
+// Endpoint returns the base URL to configure the Vultr client with.
+func (v *Vultr) Endpoint() string {
+	return v.URL + "/"
+}
+
+func (v *Vultr) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("API-Key") == "" {
+			http.Error(w, "Invalid API key.", http.StatusForbidden)
+			return
+		}
+		v.Lock()
+		defer v.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Vultr) listServers(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("SUBID"); id != "" {
+		for _, s := range v.Servers {
+			if s.ID == id {
+				writeJSON(w, http.StatusOK, s)
+				return
+			}
+		}
+		http.Error(w, "Invalid server.  Check SUBID value and ensure your API key matches the server's account", http.StatusPreconditionFailed)
+		return
+	}
+
+	tag, filter := r.URL.Query()["tag"]
+	servers := map[string]gv.Server{}
+	for _, s := range v.Servers {
+		if !filter || s.Tag == tag[0] {
+			servers[s.ID] = s
+		}
+	}
+	writeJSON(w, http.StatusOK, servers)
+}
+
+func (v *Vultr) listIPv4(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("SUBID")
+	for _, s := range v.Servers {
+		if s.ID == id {
+			ips := v.IPv4[id]
+			if ips == nil {
+				ips = []gv.IPv4{}
+			}
+			writeJSON(w, http.StatusOK, map[string][]gv.IPv4{id: ips})
+			return
+		}
+	}
+	http.Error(w, "Invalid server.  Check SUBID value and ensure your API key matches the server's account", http.StatusPreconditionFailed)
+}
+
+// setIPv4ReverseDNS sets the posted reverse DNS entry of an IPv4 address, or
+// resets it to the default, <ip>.vultr.com, if no entry is posted.
+func (v *Vultr) setIPv4ReverseDNS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ip, entry := r.PostFormValue("SUBID"), r.PostFormValue("ip"), r.PostFormValue("entry")
+	if entry == "" {
+		entry = ip + ".vultr.com"
+	}
+	for i := range v.IPv4[id] {
+		if v.IPv4[id][i].IP == ip {
+			v.IPv4[id][i].ReverseDNS = entry
+			return
+		}
+	}
+	http.Error(w, "Invalid IP address", http.StatusPreconditionFailed)
+}
+
+func (v *Vultr) listIPv6ReverseDNS(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("SUBID")
+	entries := v.IPv6ReverseDNS[id]
+	if entries == nil {
+		entries = []gv.ReverseDNSIPv6{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]gv.ReverseDNSIPv6{id: entries})
+}
+
+// setIPv6ReverseDNS sets the posted reverse DNS entry of an IPv6 address, or
+// deletes it if no entry is posted.
+func (v *Vultr) setIPv6ReverseDNS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ip, entry := r.PostFormValue("SUBID"), r.PostFormValue("ip"), r.PostFormValue("entry")
+	if v.IPv6ReverseDNS == nil {
+		v.IPv6ReverseDNS = map[string][]gv.ReverseDNSIPv6{}
+	}
+	var entries []gv.ReverseDNSIPv6
+	for _, e := range v.IPv6ReverseDNS[id] {
+		if e.IP != ip {
+			entries = append(entries, e)
+		}
+	}
+	if entry != "" {
+		entries = append(entries, gv.ReverseDNSIPv6{IP: ip, ReverseDNS: entry})
+	}
+	v.IPv6ReverseDNS[id] = entries
+}
+
+func (v *Vultr) listSSHKeys(w http.ResponseWriter, r *http.Request) {
+	keys := map[string]gv.SSHKey{}
+	for _, k := range v.SSHKeys {
+		keys[k.ID] = k
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (v *Vultr) createSSHKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	key := gv.SSHKey{
+		ID:   fmt.Sprintf("%013x", len(v.SSHKeys)+1),
+		Name: r.PostFormValue("name"),
+		Key:  r.PostFormValue("ssh_key"),
+	}
+	v.SSHKeys = append(v.SSHKeys, key)
+	writeJSON(w, http.StatusOK, struct {
+		ID string `json:"SSHKEYID"`
+	}{key.ID})
+}
+
+func (v *Vultr) listPlans(w http.ResponseWriter, r *http.Request) {
+	plans := map[string]gv.Plan{}
+	for _, p := range v.Plans {
+		plans[strconv.Itoa(p.ID)] = p
+	}
+	writeJSON(w, http.StatusOK, plans)
+}
+
+// listBlockStorages writes the block storages the way the Vultr API does, the
+// encoding of gv.BlockStorage does not round-trip.
+func (v *Vultr) listBlockStorages(w http.ResponseWriter, r *http.Request) {
+	storages := []map[string]interface{}{}
+	for _, b := range v.BlockStorages {
+		storages = append(storages, map[string]interface{}{
+			"SUBID":             b.ID,
+			"label":             b.Name,
+			"DCID":              strconv.Itoa(b.RegionID),
+			"size_gb":           strconv.Itoa(b.SizeGB),
+			"date_created":      b.Created,
+			"cost_per_month":    b.Cost,
+			"status":            b.Status,
+			"attached_to_SUBID": b.AttachedTo,
+		})
+	}
+	writeJSON(w, http.StatusOK, storages)
+}
+
+func (v *Vultr) setFirewallGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	id, group := r.PostFormValue("SUBID"), r.PostFormValue("FIREWALLGROUPID")
+	if _, found := v.firewallGroup(group); !found && group != "0" {
+		http.Error(w, "Invalid firewall group", http.StatusPreconditionFailed)
+		return
+	}
+	for i := range v.Servers {
+		if v.Servers[i].ID == id {
+			v.Servers[i].FirewallGroupID = group
+			return
+		}
+	}
+	http.Error(w, "Invalid server.  Check SUBID value and ensure your API key matches the server's account", http.StatusPreconditionFailed)
+}
+
+func (v *Vultr) firewallGroup(id string) (int, bool) {
+	for i, g := range v.FirewallGroups {
+		if g.ID == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (v *Vultr) listFirewallGroups(w http.ResponseWriter, r *http.Request) {
+	groups := map[string]gv.FirewallGroup{}
+	for _, g := range v.FirewallGroups {
+		groups[g.ID] = g
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (v *Vultr) createFirewallGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	group := gv.FirewallGroup{
+		ID:          fmt.Sprintf("%08x", len(v.FirewallGroups)+1),
+		Description: r.PostFormValue("description"),
+	}
+	v.FirewallGroups = append(v.FirewallGroups, group)
+	writeJSON(w, http.StatusOK, struct {
+		ID string `json:"FIREWALLGROUPID"`
+	}{group.ID})
+}
+
+// listFirewallRules writes the inbound rules of a group of one IP type the
+// way the Vultr API does, the encoding of gv.FirewallRule does not round-trip.
+func (v *Vultr) listFirewallRules(w http.ResponseWriter, r *http.Request) {
+	group := r.URL.Query().Get("FIREWALLGROUPID")
+	if _, found := v.firewallGroup(group); !found {
+		http.Error(w, "Invalid firewall group", http.StatusPreconditionFailed)
+		return
+	}
+	ipv6 := r.URL.Query().Get("ip_type") == "v6"
+	rules := map[string]interface{}{}
+	for _, rule := range v.FirewallRules[group] {
+		if (rule.Network.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		size, _ := rule.Network.Mask.Size()
+		rules[strconv.Itoa(rule.RuleNumber)] = map[string]interface{}{
+			"rulenumber":  rule.RuleNumber,
+			"action":      rule.Action,
+			"protocol":    rule.Protocol,
+			"port":        rule.Port,
+			"subnet":      rule.Network.IP.String(),
+			"subnet_size": size,
+		}
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (v *Vultr) createFirewallRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	group := r.PostFormValue("FIREWALLGROUPID")
+	if _, found := v.firewallGroup(group); !found {
+		http.Error(w, "Invalid firewall group", http.StatusPreconditionFailed)
+		return
+	}
+	_, network, err := net.ParseCIDR(r.PostFormValue("subnet") + "/" + r.PostFormValue("subnet_size"))
+	if err != nil {
+		http.Error(w, "Invalid subnet", http.StatusPreconditionFailed)
+		return
+	}
+	number := 1
+	for _, rule := range v.FirewallRules[group] {
+		if rule.RuleNumber >= number {
+			number = rule.RuleNumber + 1
+		}
+	}
+	v.FirewallRules[group] = append(v.FirewallRules[group], gv.FirewallRule{
+		RuleNumber: number,
+		Action:     "accept",
+		Protocol:   r.PostFormValue("protocol"),
+		Port:       r.PostFormValue("port"),
+		Network:    network,
+	})
+	writeJSON(w, http.StatusOK, map[string]int{"rulenumber": number})
+}
+
+func (v *Vultr) deleteFirewallRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	group := r.PostFormValue("FIREWALLGROUPID")
+	rules := v.FirewallRules[group]
+	for i, rule := range rules {
+		if strconv.Itoa(rule.RuleNumber) == r.PostFormValue("rulenumber") {
+			v.FirewallRules[group] = append(rules[:i:i], rules[i+1:]...)
+			return
+		}
+	}
+	http.Error(w, "Invalid firewall rule", http.StatusPreconditionFailed)
+}
+
+func (v *Vultr) listDNSDomains(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, append([]gv.DNSDomain{}, v.DNSDomains...))
+}
+
+func (v *Vultr) dnsDomain(name string) bool {
+	for _, d := range v.DNSDomains {
+		if d.Domain == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Vultr) listDNSRecords(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if !v.dnsDomain(domain) {
+		http.Error(w, "Invalid domain.  Check and try again.", http.StatusPreconditionFailed)
+		return
+	}
+	writeJSON(w, http.StatusOK, append([]gv.DNSRecord{}, v.DNSRecords[domain]...))
+}
+
+func (v *Vultr) createDNSRecord(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return
+	}
+	domain := r.PostFormValue("domain")
+	if !v.dnsDomain(domain) {
+		http.Error(w, "Invalid domain.  Check and try again.", http.StatusPreconditionFailed)
+		return
+	}
+	id := 1
+	for _, records := range v.DNSRecords {
+		for _, record := range records {
+			if record.RecordID >= id {
+				id = record.RecordID + 1
+			}
+		}
+	}
+	priority, _ := strconv.Atoi(r.PostFormValue("priority"))
+	ttl, _ := strconv.Atoi(r.PostFormValue("ttl"))
+	v.DNSRecords[domain] = append(v.DNSRecords[domain], gv.DNSRecord{
+		RecordID: id,
+		Type:     r.PostFormValue("type"),
+		Name:     r.PostFormValue("name"),
+		Data:     r.PostFormValue("data"),
+		Priority: priority,
+		TTL:      ttl,
+	})
+}
+
+// dnsRecord returns the index of the record whose ID is posted in domain.
+func (v *Vultr) dnsRecord(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid API method.", http.StatusMethodNotAllowed)
+		return "", -1, false
+	}
+	domain := r.PostFormValue("domain")
+	for i, record := range v.DNSRecords[domain] {
+		if strconv.Itoa(record.RecordID) == r.PostFormValue("RECORDID") {
+			return domain, i, true
+		}
+	}
+	http.Error(w, "Invalid record.  Check and try again.", http.StatusPreconditionFailed)
+	return "", -1, false
+}
+
+func (v *Vultr) updateDNSRecord(w http.ResponseWriter, r *http.Request) {
+	domain, i, found := v.dnsRecord(w, r)
+	if !found {
+		return
+	}
+	record := &v.DNSRecords[domain][i]
+	if name, set := r.PostForm["name"]; set {
+		record.Name = name[0]
+	}
+	if data := r.PostFormValue("data"); data != "" {
+		record.Data = data
+	}
+	if ttl, err := strconv.Atoi(r.PostFormValue("ttl")); err == nil {
+		record.TTL = ttl
+	}
+}
+
+func (v *Vultr) deleteDNSRecord(w http.ResponseWriter, r *http.Request) {
+	domain, i, found := v.dnsRecord(w, r)
+	if !found {
+		return
+	}
+	records := v.DNSRecords[domain]
+	v.DNSRecords[domain] = append(records[:i:i], records[i+1:]...)
+}
@@ -0,0 +1,33 @@
+package cloud
+
+import (
+	"net/http"
+	"sync"
+)
+
+var (
+	transportMutex sync.Mutex
+	transport      http.RoundTripper
+)
+
+// SetTransport makes the API clients of the cloud providers send their
+// requests through rt. It is used to record and replay API interactions in
+// tests. Clients created before the call keep their transport; nil restores
+// the default transport of each client.
+func SetTransport(rt http.RoundTripper) {
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	transport = rt
+}
+
+// HTTPClient returns the HTTP client cloud providers pass to their API
+// clients. It is nil unless a transport is set, so that API clients keep
+// their own defaults.
+func HTTPClient() *http.Client {
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	if transport == nil {
+		return nil
+	}
+	return &http.Client{Transport: transport}
+}
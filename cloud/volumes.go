@@ -0,0 +1,39 @@
+package cloud
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+// VolumeID returns the ID of the provider volume of pv, taken from the CSI
+// volume handle or the volumeID option of a FlexVolume. Handles of CSI drivers
+// that prefix the ID with a zone, e.g. fr-par-1/<id>, are supported. It
+// returns an empty string if pv has neither source.
+func VolumeID(pv *v1.PersistentVolume) string {
+	var id string
+	switch {
+	case pv.Spec.CSI != nil:
+		id = pv.Spec.CSI.VolumeHandle
+	case pv.Spec.FlexVolume != nil:
+		id = pv.Spec.FlexVolume.Options["volumeID"]
+	}
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+// VolumeLabels returns the labels of a volume in zone. They are the labels the
+// Node lifecycle controller adds to the Nodes in zone, so that Pods using the
+// volume are scheduled to Nodes it can be attached to. The zone label is only
+// set if zone has a failure domain.
+func VolumeLabels(zone cloudprovider.Zone) map[string]string {
+	labels := map[string]string{}
+	if zone.Region != "" {
+		labels[kubeletapis.LabelZoneRegion] = zone.Region
+	}
+	if zone.FailureDomain != "" {
+		labels[kubeletapis.LabelZoneFailureDomain] = zone.FailureDomain
+	}
+	return labels
+}
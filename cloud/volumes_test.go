@@ -0,0 +1,38 @@
+package cloud
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+)
+
+func TestVolumeID(t *testing.T) {
+	for _, test := range []struct {
+		source v1.PersistentVolumeSource
+		id     string
+	}{
+		{v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "1313217"}}, "1313217"},
+		{v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "fr-par-1/7d4a8b5e"}}, "7d4a8b5e"},
+		{v1.PersistentVolumeSource{FlexVolume: &v1.FlexPersistentVolumeSource{Options: map[string]string{"volumeID": "f3d9b5a0"}}}, "f3d9b5a0"},
+		{v1.PersistentVolumeSource{NFS: &v1.NFSVolumeSource{Server: "nfs", Path: "/exports"}}, ""},
+	} {
+		pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: test.source}}
+		if id := VolumeID(pv); id != test.id {
+			t.Errorf("expected volume ID %q, got %q", test.id, id)
+		}
+	}
+}
+
+func TestVolumeLabels(t *testing.T) {
+	expected := map[string]string{"failure-domain.beta.kubernetes.io/region": "ewr1"}
+	if labels := VolumeLabels(cloudprovider.Zone{Region: "ewr1"}); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected %v, got %v", expected, labels)
+	}
+
+	expected["failure-domain.beta.kubernetes.io/zone"] = "us-west-2a"
+	if labels := VolumeLabels(cloudprovider.Zone{Region: "ewr1", FailureDomain: "us-west-2a"}); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected %v, got %v", expected, labels)
+	}
+}
@@ -0,0 +1,145 @@
+package cmds
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/appscode/go/log"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"pharmer.dev/cloud-controller-manager/cloud"
+)
+
+const (
+	ReadinessCheckNone     = "none"
+	ReadinessCheckDNS      = "dns"
+	ReadinessCheckProvider = "provider"
+)
+
+type readinessOptions struct {
+	Check    string
+	Host     string
+	Endpoint string
+	Timeout  time.Duration
+	Interval time.Duration
+	Address  string
+}
+
+func newReadinessOptions() *readinessOptions {
+	return &readinessOptions{
+		Check:    ReadinessCheckNone,
+		Timeout:  5 * time.Minute,
+		Interval: 3 * time.Second,
+	}
+}
+
+func (o *readinessOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Check, "readiness-check", o.Check, "Startup readiness check to run before starting controllers. One of none, dns or provider.")
+	fs.StringVar(&o.Host, "readiness-host", o.Host, "Host name resolved by the dns readiness check.")
+	fs.StringVar(&o.Endpoint, "readiness-endpoint", o.Endpoint, "URL reached by the provider readiness check. Defaults to the API endpoint of the cloud provider.")
+	fs.DurationVar(&o.Timeout, "readiness-timeout", o.Timeout, "Time to wait for the readiness check to pass before starting controllers anyway.")
+	fs.StringVar(&o.Address, "readiness-address", o.Address, "Address to serve the readiness healthz endpoint on, e.g. :10264. Disabled if empty.")
+}
+
+func (o *readinessOptions) Validate() error {
+	switch o.Check {
+	case ReadinessCheckNone:
+	case ReadinessCheckDNS:
+		if o.Host == "" {
+			return fmt.Errorf("--readiness-host is required for readiness check %q", o.Check)
+		}
+	case ReadinessCheckProvider:
+	default:
+		return fmt.Errorf("unknown readiness check %q, must be one of none, dns or provider", o.Check)
+	}
+	return nil
+}
+
+// readinessChecker runs the configured startup readiness check and reports its
+// last result as a healthz check.
+type readinessChecker struct {
+	opts  *readinessOptions
+	probe func() error
+
+	mu  sync.RWMutex
+	err error
+}
+
+func newReadinessChecker(opts *readinessOptions, provider string) (*readinessChecker, error) {
+	c := &readinessChecker{opts: opts}
+	switch opts.Check {
+	case ReadinessCheckNone:
+		c.probe = func() error { return nil }
+	case ReadinessCheckDNS:
+		c.probe = func() error {
+			addr, err := net.LookupIP(opts.Host)
+			if err == nil && len(addr) == 0 {
+				err = fmt.Errorf("no address found for %s", opts.Host)
+			}
+			return err
+		}
+	case ReadinessCheckProvider:
+		endpoint := func() (string, error) { return opts.Endpoint, nil }
+		if opts.Endpoint == "" {
+			var found bool
+			if endpoint, found = cloud.APIEndpoint(provider); !found {
+				return nil, fmt.Errorf("no API endpoint known for cloud provider %q, set --readiness-endpoint", provider)
+			}
+		}
+		client := &http.Client{Timeout: 10 * time.Second}
+		c.probe = func() error {
+			// resolved on every probe, as it may depend on the instance metadata
+			url, err := endpoint()
+			if err != nil {
+				return err
+			}
+			// any response means the endpoint is reachable, authentication is not checked here
+			resp, err := client.Get(url)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}
+	}
+	c.err = fmt.Errorf("readiness check %q has not run yet", opts.Check)
+	return c, nil
+}
+
+func (c *readinessChecker) Name() string {
+	return "startup-readiness"
+}
+
+func (c *readinessChecker) Check(_ *http.Request) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+func (c *readinessChecker) poll() (bool, error) {
+	err := c.probe()
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	if err != nil {
+		log.Infof("Readiness check %q failed. Reason: %v", c.opts.Check, err)
+	}
+	return err == nil, nil
+}
+
+// Wait blocks until the readiness check passes or the timeout expires. If the
+// timeout expires, the check keeps running in the background so that healthz
+// turns healthy once it passes.
+func (c *readinessChecker) Wait(stop <-chan struct{}) error {
+	if ok, _ := c.poll(); ok {
+		return nil
+	}
+	err := wait.Poll(c.opts.Interval, c.opts.Timeout, c.poll)
+	if err == nil {
+		return nil
+	}
+	go wait.PollUntil(c.opts.Interval, c.poll, stop)
+	return fmt.Errorf("readiness check %q did not pass within %v. Reason: %v", c.opts.Check, c.opts.Timeout, c.Check(nil))
+}
@@ -3,23 +3,27 @@ package cmds
 import (
 	"flag"
 	"log"
-	"strings"
+	"time"
 
 	v "github.com/appscode/go/version"
 	"github.com/appscode/kutil/tools/analytics"
-	ga "github.com/jpillora/go-ogle-analytics"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	_ "k8s.io/kubernetes/pkg/cloudprovider/providers"
+	"pharmer.dev/cloud-controller-manager/telemetry"
 )
 
-const (
-	gaTrackingCode = "UA-62096468-20"
-)
+// telemetryTimeout bounds how long a command waits for its telemetry event
+// to be sent once it is done.
+const telemetryTimeout = 5 * time.Second
 
 func NewRootCmd(version string) *cobra.Command {
 	var (
-		enableAnalytics = true
+		enableAnalytics = false
+		telemetrySink   = ""
+		telemetryConfig = ""
+		// sent is closed once the telemetry event is sent, if one is
+		sent chan struct{}
 	)
 	rootCmd := &cobra.Command{
 		Use:               "cloud-controller-manager [command]",
@@ -29,19 +33,61 @@ func NewRootCmd(version string) *cobra.Command {
 			c.Flags().VisitAll(func(flag *pflag.Flag) {
 				log.Printf("FLAG: --%s=%q", flag.Name, flag.Value)
 			})
-			if enableAnalytics && gaTrackingCode != "" {
-				if client, err := ga.NewClient(gaTrackingCode); err == nil {
-					client.ClientID(analytics.ClientID())
-					parts := strings.Split(c.CommandPath(), " ")
-					client.Send(ga.NewEvent(parts[0], strings.Join(parts[1:], "/")).Label(version))
+
+			cfg, err := telemetry.LoadConfig(telemetryConfig)
+			if err == nil {
+				err = cfg.ApplyEnv()
+			}
+			if err != nil {
+				log.Printf("Telemetry disabled. Reason: %v", err)
+				return
+			}
+			if c.Flags().Changed("analytics") {
+				cfg.Enabled = enableAnalytics
+			}
+			if c.Flags().Changed("telemetry-sink") {
+				cfg.Sink = telemetrySink
+			}
+			sink, err := telemetry.NewSink(cfg)
+			if err != nil {
+				log.Printf("Telemetry disabled. Reason: %v", err)
+				return
+			}
+			if sink != telemetry.Noop {
+				e := telemetry.Event{
+					Command:   c.CommandPath(),
+					Version:   version,
+					ClientID:  analytics.ClientID(),
+					Timestamp: time.Now().UTC(),
 				}
+				// don't delay the command on a slow sink, the event is waited
+				// for in PersistentPostRun
+				sent = make(chan struct{})
+				go func() {
+					defer close(sent)
+					if err := sink.Send(e); err != nil {
+						log.Printf("Failed to send telemetry. Reason: %v", err)
+					}
+				}()
+			}
+		},
+		PersistentPostRun: func(c *cobra.Command, args []string) {
+			if sent == nil {
+				return
+			}
+			select {
+			case <-sent:
+			case <-time.After(telemetryTimeout):
+				log.Printf("Failed to send telemetry. Reason: timed out after %v", telemetryTimeout)
 			}
 		},
 	}
 	rootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 	// ref: https://github.com/kubernetes/kubernetes/issues/17162#issuecomment-225596212
 	flag.CommandLine.Parse([]string{})
-	rootCmd.PersistentFlags().BoolVar(&enableAnalytics, "analytics", enableAnalytics, "Send analytical events to Google Analytics")
+	rootCmd.PersistentFlags().BoolVar(&enableAnalytics, "analytics", enableAnalytics, "Send anonymous usage telemetry, see docs/telemetry.md. Overrides "+telemetry.EnvEnabled)
+	rootCmd.PersistentFlags().StringVar(&telemetrySink, "telemetry-sink", telemetrySink, "Telemetry sink: google-analytics, file:///path or an OTLP/HTTP logs endpoint. Overrides "+telemetry.EnvSink)
+	rootCmd.PersistentFlags().StringVar(&telemetryConfig, "telemetry-config", telemetryConfig, "Path to the telemetry config file")
 
 	rootCmd.AddCommand(NewCmdUp())
 	rootCmd.AddCommand(NewCmdDebug())
@@ -2,9 +2,8 @@ package cmds
 
 import (
 	"fmt"
-	"net"
+	"net/http"
 	"os"
-	"time"
 
 	"github.com/appscode/go/log"
 	"github.com/spf13/cobra"
@@ -14,26 +13,72 @@ import (
 	"k8s.io/kubernetes/cmd/cloud-controller-manager/app/options"
 	_ "k8s.io/kubernetes/pkg/client/metrics/prometheus" // for client metric registration
 	_ "k8s.io/kubernetes/pkg/version/prometheus"        // for version metric registration
+	"pharmer.dev/cloud-controller-manager/cloud"
 	_ "pharmer.dev/cloud-controller-manager/cloud/providers"
 )
 
-func init() {
-	healthz.DefaultHealthz()
-}
-
 func NewCmdUp() *cobra.Command {
 	s, _ := options.NewCloudControllerManagerOptions()
+	readiness := newReadinessOptions()
+	dryRun := false
+	gc := cloud.DefaultGCOptions
+	gcMode := string(gc.Mode)
+	nodeLabeler := cloud.DefaultNodeLabelerOptions
+	addressFamilies := []string{}
+	for _, f := range cloud.DefaultAddressOptions.Families {
+		addressFamilies = append(addressFamilies, string(f))
+	}
+	excludeCIDRs := []string{}
 	cmd := &cobra.Command{
 		Use:               "up",
 		Short:             "Bootstrap as a Kubernetes master or node",
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
-			err := wait.Poll(3*time.Second, 5*time.Minute, func() (bool, error) {
-				addr, err := net.LookupIP("google.com")
-				return len(addr) > 0, err
-			})
+			cloud.SetDryRun(dryRun)
+			if dryRun {
+				log.Infoln("Running in dry-run mode, mutating cloud API calls will only be logged")
+			}
+			gc.Mode = cloud.GCMode(gcMode)
+			if err := cloud.SetGCOptions(gc); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			if err := cloud.SetNodeLabelerOptions(nodeLabeler); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			addresses := cloud.AddressOptions{}
+			for _, f := range addressFamilies {
+				addresses.Families = append(addresses.Families, cloud.AddressFamily(f))
+			}
+			cidrs, err := cloud.ParseCIDRs(excludeCIDRs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			addresses.ExcludeCIDRs = cidrs
+			if err := cloud.SetAddressOptions(addresses); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+
+			if err := readiness.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			checker, err := newReadinessChecker(readiness, s.KubeCloudShared.CloudProvider.Name)
 			if err != nil {
-				log.Fatalln("Failed to resolve DNS. Reason: %v", err)
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			healthz.DefaultHealthz(healthz.PingHealthz, checker)
+			if readiness.Address != "" {
+				go func() {
+					log.Errorln(http.ListenAndServe(readiness.Address, nil))
+				}()
+			}
+			if err := checker.Wait(wait.NeverStop); err != nil {
+				log.Warningf("Starting controllers anyway. %v", err)
 			}
 
 			c, err := s.Config()
@@ -52,6 +97,15 @@ func NewCmdUp() *cobra.Command {
 	for _, f := range s.Flags().FlagSets {
 		cmd.Flags().AddFlagSet(f)
 	}
+	readiness.AddFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "Log and record Events for mutating cloud API calls instead of sending them.")
+	cmd.Flags().StringVar(&gcMode, "gc-mode", gcMode, "What to do with cloud resources of deleted load balancer Services: report or delete. Requires a cluster ID in the cloud config.")
+	cmd.Flags().DurationVar(&gc.GracePeriod, "gc-grace-period", gc.GracePeriod, "Time a cloud resource must be orphaned before it is reported or deleted.")
+	cmd.Flags().DurationVar(&gc.Interval, "gc-interval", gc.Interval, "Time between two searches for orphaned cloud resources.")
+	cmd.Flags().StringSliceVar(&addressFamilies, "node-address-families", addressFamilies, "Address families of the reported Node IPs in order of preference: ipv4, ipv6 or both.")
+	cmd.Flags().StringSliceVar(&excludeCIDRs, "node-address-exclude-cidrs", excludeCIDRs, "Networks whose addresses are not reported as Node IPs, e.g. the floating IPs of load balancers.")
+	cmd.Flags().BoolVar(&nodeLabeler.Enabled, "node-labels", nodeLabeler.Enabled, "Add provider specific labels like vultr.pharmer.dev/plan-name to Nodes.")
+	cmd.Flags().DurationVar(&nodeLabeler.Interval, "node-labels-interval", nodeLabeler.Interval, "Time between two updates of the provider specific Node labels.")
 
 	return cmd
 }
@@ -146,6 +146,12 @@ def test(type, *args):
         print '{test unit|e2e}'
 
 
+def record(provider, config, node):
+    # re-records the API cassettes of a provider against a real account
+    die(call('PHARMER_RECORD=1 PHARMER_RECORD_CONFIG={} PHARMER_RECORD_NODE={} {} test -count=1 -run TestReplay ./cloud/providers/{}/'.format(
+        os.path.abspath(config), node, libbuild.GOC, provider)))
+
+
 def revendor():
     libbuild.revendor()
 
@@ -0,0 +1,120 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ga "github.com/jpillora/go-ogle-analytics"
+)
+
+const (
+	gaTrackingCode = "UA-62096468-20"
+
+	serviceName = "cloud-controller-manager"
+)
+
+type fileSink struct {
+	path string
+}
+
+func newFileSink(path string) Sink {
+	return &fileSink{path: path}
+}
+
+func (s *fileSink) Send(e Event) error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+// otlpSink posts events to an OpenTelemetry collector using the OTLP/HTTP
+// JSON encoding of log records.
+type otlpSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPSink(endpoint string) Sink {
+	return &otlpSink{endpoint: endpoint, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func attribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+func (s *otlpSink) Send(e Event) error {
+	record := map[string]interface{}{
+		"timeUnixNano": strconv.FormatInt(e.Timestamp.UnixNano(), 10),
+		"body":         otlpValue{StringValue: e.Command},
+		"attributes": []otlpAttribute{
+			attribute("command", e.Command),
+			attribute("version", e.Version),
+			attribute("client.id", e.ClientID),
+		},
+	}
+	payload := map[string]interface{}{
+		"resourceLogs": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttribute{attribute("service.name", serviceName)},
+				},
+				"scopeLogs": []interface{}{
+					map[string]interface{}{
+						"scope":      map[string]string{"name": "pharmer.dev/cloud-controller-manager/telemetry"},
+						"logRecords": []interface{}{record},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry collector %s returned %s", s.endpoint, resp.Status)
+	}
+	return nil
+}
+
+type googleAnalyticsSink struct {
+	trackingCode string
+}
+
+func newGoogleAnalyticsSink(trackingCode string) Sink {
+	return &googleAnalyticsSink{trackingCode: trackingCode}
+}
+
+func (s *googleAnalyticsSink) Send(e Event) error {
+	client, err := ga.NewClient(s.trackingCode)
+	if err != nil {
+		return err
+	}
+	client.ClientID(e.ClientID)
+	parts := strings.Split(e.Command, " ")
+	return client.Send(ga.NewEvent(parts[0], strings.Join(parts[1:], "/")).Label(e.Version))
+}
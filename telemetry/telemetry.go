@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// EnvEnabled enables or disables telemetry, e.g. PHARMER_TELEMETRY=false
+	EnvEnabled = "PHARMER_TELEMETRY"
+	// EnvSink selects the telemetry sink, see NewSink for the supported values
+	EnvSink = "PHARMER_TELEMETRY_SINK"
+
+	SinkGoogleAnalytics = "google-analytics"
+)
+
+// Event is the complete set of fields sent by telemetry. Nothing else about
+// the cluster, its nodes or the cloud account is sent. Keep docs/telemetry.md
+// in sync when changing it.
+type Event struct {
+	// Command is the command path that was run, e.g. "cloud-controller-manager up"
+	Command string `json:"command"`
+	// Version is the version of the binary
+	Version string `json:"version"`
+	// ClientID is an anonymous hash identifying the installation
+	ClientID string `json:"clientID"`
+	// Timestamp is the time the command was run
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Sink delivers telemetry events.
+type Sink interface {
+	Send(e Event) error
+}
+
+type noop struct{}
+
+func (noop) Send(_ Event) error { return nil }
+
+// Noop is the default sink, it discards all events.
+var Noop Sink = noop{}
+
+// Config configures telemetry. It can be loaded from a file and overridden
+// by environment variables and command line flags, in that order.
+type Config struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Sink    string `json:"sink,omitempty" yaml:"sink,omitempty"`
+}
+
+// LoadConfig reads a telemetry config file. An empty path returns the
+// default config, which disables telemetry.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(contents, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry config %s. Reason: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides the config from environment variables.
+func (c *Config) ApplyEnv() error {
+	if v, found := os.LookupEnv(EnvEnabled); found {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s. Reason: %v", v, EnvEnabled, err)
+		}
+		c.Enabled = enabled
+	}
+	if v, found := os.LookupEnv(EnvSink); found {
+		c.Sink = v
+	}
+	return nil
+}
+
+// NewSink returns the sink selected by the config. Supported sinks are
+//
+//	file:///path/to/events.jsonl  appends events as JSON lines to a local file
+//	http(s)://host:port/v1/logs   posts events as OTLP/HTTP JSON log records
+//	google-analytics              sends events to AppsCode's Google Analytics
+//
+// Enabling telemetry without a sink is an error, events are only sent where
+// they are explicitly directed to.
+func NewSink(c *Config) (Sink, error) {
+	if !c.Enabled {
+		return Noop, nil
+	}
+	switch {
+	case c.Sink == "":
+		return nil, fmt.Errorf("telemetry is enabled but no sink is configured, set %s or --telemetry-sink", EnvSink)
+	case c.Sink == SinkGoogleAnalytics:
+		return newGoogleAnalyticsSink(gaTrackingCode), nil
+	case strings.HasPrefix(c.Sink, "file://"):
+		return newFileSink(strings.TrimPrefix(c.Sink, "file://")), nil
+	case strings.HasPrefix(c.Sink, "http://"), strings.HasPrefix(c.Sink, "https://"):
+		return newOTLPSink(c.Sink), nil
+	}
+	return nil, fmt.Errorf("unknown telemetry sink %q", c.Sink)
+}
@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSinkDefaultsToNoop(t *testing.T) {
+	os.Unsetenv(EnvEnabled)
+	os.Unsetenv(EnvSink)
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink != Noop {
+		t.Errorf("expected noop sink, got %T", sink)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "telemetry.yaml")
+	if err := ioutil.WriteFile(path, []byte("enabled: true\nsink: file:///tmp/a.jsonl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Enabled || cfg.Sink != "file:///tmp/a.jsonl" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	os.Setenv(EnvEnabled, "false")
+	defer os.Unsetenv(EnvEnabled)
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Enabled {
+		t.Error("expected environment to disable telemetry")
+	}
+
+	os.Setenv(EnvEnabled, "maybe")
+	if err := cfg.ApplyEnv(); err == nil {
+		t.Error("expected an error for an invalid boolean")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+	sink, err := NewSink(&Config{Enabled: true, Sink: "file://" + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Event{Command: "cloud-controller-manager up", Version: "v1", ClientID: "abc", Timestamp: time.Unix(0, 0).UTC()}
+	if err := sink.Send(e); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Event
+	if err := json.Unmarshal(contents, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != e {
+		t.Errorf("expected %+v, got %+v", e, got)
+	}
+}
+
+func TestOTLPSink(t *testing.T) {
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(&Config{Enabled: true, Sink: srv.URL + "/v1/logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(Event{Command: "cloud-controller-manager up", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := payload["resourceLogs"]; !found {
+		t.Errorf("expected OTLP resourceLogs payload, got %v", payload)
+	}
+}
+
+func TestNewSinkRequiresSink(t *testing.T) {
+	if _, err := NewSink(&Config{Enabled: true}); err == nil {
+		t.Error("expected an error for enabled telemetry without a sink")
+	}
+}